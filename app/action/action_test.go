@@ -0,0 +1,362 @@
+package action
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"go/parser"
+	"go/token"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	jc "github.com/juju/testing/checkers"
+	gc "gopkg.in/check.v1"
+)
+
+func Test(t *testing.T) {
+	gc.TestingT(t)
+}
+
+type actionSuite struct{}
+
+var _ = gc.Suite(&actionSuite{})
+
+const testReleases = `
+versions:
+  0.9.0:
+    linux/amd64:
+      sha256: aa
+  1.2.0:
+    linux/amd64:
+      url: https://example.com/cmd.tar.gz
+      sha256: bb
+  "1.10":
+    darwin/amd64:
+      sha256: cc
+`
+
+func (s *actionSuite) TestSelect(c *gc.C) {
+	releases, err := ParseReleases([]byte(testReleases))
+	c.Assert(err, jc.ErrorIsNil)
+
+	for constraint, expected := range map[string]string{
+		"":               "1.10",
+		"latest":         "1.10",
+		"1.2.0":          "1.2.0",
+		"v0.9.0":         "0.9.0",
+		">=1.0.0 <1.5.0": "1.2.0",
+	} {
+		version, err := releases.Select(constraint)
+		c.Assert(err, jc.ErrorIsNil)
+		c.Check(version, gc.Equals, expected, gc.Commentf("constraint %q", constraint))
+	}
+
+	_, err = releases.Select("2.0.0")
+	c.Assert(err, gc.ErrorMatches, `no released version matches "2.0.0". Available versions: 1.10, 1.2.0, 0.9.0`)
+	_, err = releases.Select("not a version")
+	c.Assert(err, gc.ErrorMatches, `"not a version" is not a valid version or version range`)
+}
+
+func (s *actionSuite) TestArchive(c *gc.C) {
+	releases, err := ParseReleases([]byte(testReleases))
+	c.Assert(err, jc.ErrorIsNil)
+
+	archive, err := releases.Archive("0.9.0", "linux", "amd64")
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(archive.ArchiveURL("https://base", "0.9.0", "linux", "amd64"), gc.Equals, "https://base/bin/linux/amd64/0.9.0/cmd.tar.gz")
+
+	archive, err = releases.Archive("1.2.0", "linux", "amd64")
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(archive.ArchiveURL("https://base", "1.2.0", "linux", "amd64"), gc.Equals, "https://example.com/cmd.tar.gz")
+
+	_, err = releases.Archive("1.10", "linux", "amd64")
+	c.Assert(err, gc.ErrorMatches, "version 1.10 has not been released for linux/amd64")
+}
+
+func (s *actionSuite) TestParseRef(c *gc.C) {
+	owner, name, version, err := ParseRef("codelingo/review@1.2.0")
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert([]string{owner, name, version}, jc.DeepEquals, []string{"codelingo", "review", "1.2.0"})
+
+	owner, name, version, err = ParseRef("review")
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert([]string{owner, name, version}, jc.DeepEquals, []string{"", "review", ""})
+
+	for _, ref := range []string{"a/b/c", "/review", "review@", ""} {
+		_, _, _, err = ParseRef(ref)
+		c.Check(err, gc.NotNil, gc.Commentf("ref %q", ref))
+	}
+}
+
+func (s *actionSuite) TestVerify(c *gc.C) {
+	data := []byte("archive")
+	sum := sha256.Sum256(data)
+	pub, priv, err := ed25519.GenerateKey(nil)
+	c.Assert(err, jc.ErrorIsNil)
+	publicKey := base64.StdEncoding.EncodeToString(pub)
+
+	archive := &Archive{SHA256: hex.EncodeToString(sum[:])}
+	c.Assert(archive.Verify(data, ""), jc.ErrorIsNil)
+	c.Assert(archive.Verify([]byte("tampered"), ""), gc.ErrorMatches, "checksum mismatch: .*")
+	c.Assert(archive.Verify(data, publicKey), gc.ErrorMatches, "a public key is configured but the archive is not signed")
+
+	archive.Signature = base64.StdEncoding.EncodeToString(ed25519.Sign(priv, data))
+	c.Assert(archive.Verify(data, publicKey), jc.ErrorIsNil)
+
+	otherPub, _, err := ed25519.GenerateKey(nil)
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(archive.Verify(data, base64.StdEncoding.EncodeToString(otherPub)), gc.ErrorMatches, "signature verification failed")
+
+	c.Assert((&Archive{}).Verify(data, ""), gc.ErrorMatches, "the release manifest has no checksum for this archive")
+}
+
+func (s *actionSuite) TestFetch(c *gc.C) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/ok" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte("data"))
+	}))
+	defer srv.Close()
+
+	data, err := Fetch(srv.URL + "/ok")
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(string(data), gc.Equals, "data")
+
+	_, err = Fetch(srv.URL + "/missing")
+	c.Assert(err, gc.ErrorMatches, "failed to download .*/missing: 404 Not Found")
+}
+
+func (s *actionSuite) TestManifest(c *gc.C) {
+	dir := c.MkDir()
+	path := filepath.Join(dir, LockFile)
+
+	m, err := LoadManifest(path)
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(m.Actions, gc.HasLen, 0)
+
+	m.Set(&Installed{Owner: "codelingo", Name: "search", Version: "1.0.0", SHA256: "aa"})
+	m.Set(&Installed{Owner: "codelingo", Name: "review", Version: "1.0.0", SHA256: "bb"})
+	m.Set(&Installed{Owner: "codelingo", Name: "review", Version: "1.2.0", SHA256: "cc"})
+	c.Assert(m.Save(), jc.ErrorIsNil)
+
+	found, err := FindLockFile(filepath.Join(dir, "sub", "dir"))
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(found, gc.Equals, path)
+
+	m, err = LoadManifest(path)
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(m.Actions, jc.DeepEquals, []*Installed{
+		{Owner: "codelingo", Name: "review", Version: "1.2.0", SHA256: "cc"},
+		{Owner: "codelingo", Name: "search", Version: "1.0.0", SHA256: "aa"},
+	})
+
+	c.Assert(m.Remove("codelingo", "review"), jc.IsTrue)
+	c.Assert(m.Remove("codelingo", "review"), jc.IsFalse)
+	c.Assert(m.Get("codelingo", "search").Version, gc.Equals, "1.0.0")
+}
+
+func (s *actionSuite) TestSwapAndRollback(c *gc.C) {
+	dir := filepath.Join(c.MkDir(), "codelingo", "review")
+	read := func(path string) string {
+		data, err := ioutil.ReadFile(path)
+		c.Assert(err, jc.ErrorIsNil)
+		return string(data)
+	}
+	stage := func(content string) string {
+		staging, err := StagingDir(dir)
+		c.Assert(err, jc.ErrorIsNil)
+		c.Assert(ioutil.WriteFile(filepath.Join(staging, "cmd"), []byte(content), 0755), jc.ErrorIsNil)
+		return staging
+	}
+
+	c.Assert(Rollback(dir), gc.ErrorMatches, "there is no previous version of review to roll back to")
+
+	c.Assert(Swap(dir, stage("v1")), jc.ErrorIsNil)
+	c.Assert(read(filepath.Join(dir, "cmd")), gc.Equals, "v1")
+	info, err := os.Stat(dir)
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(info.Mode().Perm(), gc.Equals, os.FileMode(0755))
+
+	c.Assert(Swap(dir, stage("v2")), jc.ErrorIsNil)
+	c.Assert(read(filepath.Join(dir, "cmd")), gc.Equals, "v2")
+	c.Assert(read(filepath.Join(PreviousDir(dir), "cmd")), gc.Equals, "v1")
+
+	c.Assert(Rollback(dir), jc.ErrorIsNil)
+	c.Assert(read(filepath.Join(dir, "cmd")), gc.Equals, "v1")
+	c.Assert(read(filepath.Join(PreviousDir(dir), "cmd")), gc.Equals, "v2")
+
+	c.Assert(Swap(dir, filepath.Join(dir, "missing")), gc.NotNil)
+	c.Assert(read(filepath.Join(dir, "cmd")), gc.Equals, "v1")
+}
+
+func (s *actionSuite) TestLocalSource(c *gc.C) {
+	dir := c.MkDir()
+
+	archive := filepath.Join(dir, "myaction.tar.gz")
+	c.Assert(ioutil.WriteFile(archive, []byte("archive"), 0644), jc.ErrorIsNil)
+	fetched, err := (&Local{Path: archive}).Fetch("acme", "myaction", "")
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(fetched.Version, gc.Equals, LocalVersion)
+	c.Assert(fetched.ArchiveName, gc.Equals, "myaction.tar.gz")
+	c.Assert(fetched.Origin, gc.Equals, archive)
+
+	built := filepath.Join(dir, "built")
+	c.Assert(os.Mkdir(built, 0755), jc.ErrorIsNil)
+	c.Assert(ioutil.WriteFile(filepath.Join(built, "cmd"), []byte("binary"), 0755), jc.ErrorIsNil)
+	fetched, err = (&Local{Path: built}).Fetch("acme", "built", "")
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(fetched.ArchiveName, gc.Equals, "")
+	c.Assert(string(fetched.Data), gc.Equals, "binary")
+	c.Assert(fetched.Spec, gc.IsNil)
+
+	c.Assert(ioutil.WriteFile(filepath.Join(built, SpecFile), []byte("name: built\n"), 0644), jc.ErrorIsNil)
+	fetched, err = (&Local{Path: built}).Fetch("acme", "built", "")
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(string(fetched.Spec), gc.Equals, "name: built\n")
+
+	// A directory laid out as a registry serves released versions.
+	data := []byte("released")
+	sum := sha256.Sum256(data)
+	actionDir := filepath.Join(dir, "registry", "acme", "lint")
+	archivePath := filepath.Join(actionDir, "bin", runtime.GOOS, runtime.GOARCH, "1.0.0", ArchiveName(runtime.GOOS))
+	c.Assert(os.MkdirAll(filepath.Dir(archivePath), 0755), jc.ErrorIsNil)
+	c.Assert(ioutil.WriteFile(archivePath, data, 0644), jc.ErrorIsNil)
+	releases := "versions:\n  1.0.0:\n    " + runtime.GOOS + "/" + runtime.GOARCH + ":\n      sha256: " + hex.EncodeToString(sum[:]) + "\n"
+	c.Assert(ioutil.WriteFile(filepath.Join(actionDir, ReleasesFile), []byte(releases), 0644), jc.ErrorIsNil)
+	fetched, err = (&Local{Path: filepath.Join(dir, "registry")}).Fetch("acme", "lint", "latest")
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(fetched.Version, gc.Equals, "1.0.0")
+	c.Assert(string(fetched.Data), gc.Equals, "released")
+
+	_, err = (&Local{Path: filepath.Join(dir, "registry")}).Fetch("acme", "missing", "")
+	c.Assert(err, gc.ErrorMatches, ".* holds neither a cmd nor a registry with acme/missing")
+}
+
+func (s *actionSuite) TestIsLocalPath(c *gc.C) {
+	for arg, expected := range map[string]bool{
+		"review":                 false,
+		"codelingo/review@1.0.0": false,
+		"./dist/myaction.tar.gz": true,
+		"dist/myaction.zip":      true,
+		"/opt/actions/lint":      true,
+		"dist/acme/lint":         true,
+		"../lint":                true,
+	} {
+		c.Check(IsLocalPath(arg), gc.Equals, expected, gc.Commentf("arg %q", arg))
+	}
+	c.Assert(LocalName("./dist/myaction.tar.gz"), gc.Equals, "myaction")
+}
+
+func (s *actionSuite) TestResolveOwner(c *gc.C) {
+	flowsDir := c.MkDir()
+	for _, dir := range []string{"codelingo/review", "acme/review", "acme/lint", "acme/.search.previous"} {
+		c.Assert(os.MkdirAll(filepath.Join(flowsDir, dir), 0755), jc.ErrorIsNil)
+	}
+
+	owner, err := ResolveOwner(flowsDir, "lint", nil)
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(owner, gc.Equals, "acme")
+
+	owner, err = ResolveOwner(flowsDir, "review", []string{"other", "acme", "codelingo"})
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(owner, gc.Equals, "acme")
+
+	_, err = ResolveOwner(flowsDir, "review", []string{"other"})
+	c.Assert(err, gc.ErrorMatches, `(?s)Action "review" is provided by more than one owner:\n  - acme/review\n  - codelingo/review\n.*`)
+
+	_, err = ResolveOwner(flowsDir, "search", nil)
+	c.Assert(err, gc.ErrorMatches, `Action "search" not found. .*`)
+}
+
+func (s *actionSuite) TestContract(c *gc.C) {
+	dir := c.MkDir()
+	path, err := WriteContext(dir, &Context{Version: ContractVersion, WorkingDir: dir, Auth: AuthContext{Token: "secret"}})
+	c.Assert(err, jc.ErrorIsNil)
+	info, err := os.Stat(path)
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(info.Mode().Perm(), gc.Equals, os.FileMode(0600))
+
+	resultsPath := filepath.Join(dir, "results.json")
+	results, err := ReadResults(resultsPath)
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(results, gc.IsNil)
+
+	c.Assert(ioutil.WriteFile(resultsPath, []byte(`{"message": "1 issue found", "issues": [
+		{"tenet": "no-panics", "file": "main.go", "line": 12, "comment": "Don't panic."},
+		{"file": "README.md", "comment": "Missing licence."}]}`), 0644), jc.ErrorIsNil)
+	results, err = ReadResults(resultsPath)
+	c.Assert(err, jc.ErrorIsNil)
+	var out bytes.Buffer
+	results.Render(&out)
+	c.Assert(out.String(), gc.Equals, "main.go:12 [no-panics]: Don't panic.\nREADME.md: Missing licence.\n1 issue found\n")
+
+	c.Assert(ioutil.WriteFile(resultsPath, []byte("not json"), 0644), jc.ErrorIsNil)
+	_, err = ReadResults(resultsPath)
+	c.Assert(err, gc.ErrorMatches, "the Action wrote invalid results: .*")
+
+	c.Assert(ExitError("review", ExitOK), jc.ErrorIsNil)
+	c.Assert(ExitError("review", ExitAuth), gc.ErrorMatches, "Action review failed to authenticate. .*")
+	c.Assert(ExitError("review", 42), gc.ErrorMatches, "Action review failed with exit code 42.")
+	// A panicking Go Action exits with 2, which isn't a usage error.
+	c.Assert(ExitError("review", 2), gc.ErrorMatches, "Action review failed with exit code 2.")
+	c.Assert(ExitError("review", ExitUsage), gc.ErrorMatches, "Action review was given invalid arguments. .*")
+}
+
+func (s *actionSuite) TestLoadSpec(c *gc.C) {
+	dir := c.MkDir()
+	spec, err := LoadSpec(dir)
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(spec.Permissions.Network, jc.IsFalse)
+	c.Assert(spec.CPULimit(), gc.Equals, DefaultCPULimit)
+	c.Assert(spec.MemoryLimit(), gc.Equals, DefaultMemoryLimit)
+
+	c.Assert(ioutil.WriteFile(filepath.Join(dir, SpecFile), []byte(`owner: acme
+name: lint
+version: 1.0.0
+permissions:
+  network: true
+  env: [GOPATH]
+limits:
+  cpu: 30
+`), 0644), jc.ErrorIsNil)
+	spec, err = LoadSpec(dir)
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(spec.Permissions, jc.DeepEquals, Permissions{Network: true, Env: []string{"GOPATH"}})
+	c.Assert(spec.CPULimit(), gc.Equals, 30)
+	c.Assert(spec.MemoryLimit(), gc.Equals, DefaultMemoryLimit)
+
+	c.Assert(ioutil.WriteFile(filepath.Join(dir, SpecFile), []byte("permissions:\n  root: true\n"), 0644), jc.ErrorIsNil)
+	_, err = LoadSpec(dir)
+	c.Assert(err, gc.ErrorMatches, "(?s)invalid .*lingo_action.yaml: .*field root not found.*")
+}
+
+func (s *actionSuite) TestScaffold(c *gc.C) {
+	dir := filepath.Join(c.MkDir(), "hello")
+	scaffold := &Scaffold{Owner: "acme", Name: "hello", Version: "0.1.0", Module: "example.com/acme/hello"}
+	files, err := scaffold.Write(dir)
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(files, jc.DeepEquals, []string{".gitignore", "Makefile", "go.mod", SpecFile, "main.go", "main_test.go"})
+
+	for _, file := range []string{"main.go", "main_test.go"} {
+		_, err := parser.ParseFile(token.NewFileSet(), filepath.Join(dir, file), nil, 0)
+		c.Check(err, jc.ErrorIsNil, gc.Commentf("file %s", file))
+	}
+	spec, err := LoadSpec(dir)
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(spec.Owner+"/"+spec.Name+"@"+spec.Version, gc.Equals, "acme/hello@0.1.0")
+	makefile, err := ioutil.ReadFile(filepath.Join(dir, "Makefile"))
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(string(makefile), jc.Contains, "lingo install --owner $(OWNER) --name $(NAME) .\n")
+
+	_, err = scaffold.Write(dir)
+	c.Assert(err, gc.ErrorMatches, ".*/hello already exists and is not empty")
+}
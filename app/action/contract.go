@@ -0,0 +1,166 @@
+package action
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+
+	"github.com/juju/errors"
+)
+
+// ContractVersion is the version of the contract between lingo and the
+// Actions it runs. It is bumped whenever the context or results change
+// incompatibly. See doc/ACTIONS.md.
+const ContractVersion = 1
+
+const (
+	// ContextEnv names the environment variable holding the path of the
+	// JSON encoded Context of a run.
+	ContextEnv = "LINGO_CONTEXT"
+	// ResultsEnv names the environment variable holding the path an Action
+	// may write its JSON encoded Results to.
+	ResultsEnv = "LINGO_RESULTS"
+	// ContractVersionEnv names the environment variable holding the
+	// ContractVersion.
+	ContractVersionEnv = "LINGO_CONTRACT_VERSION"
+)
+
+// The exit codes an Action uses to tell lingo why it failed. ExitUsage is
+// sysexits' EX_USAGE, as 2 is the status of a Go program which panics.
+const (
+	ExitOK      = 0
+	ExitFailure = 1
+	ExitIssues  = 3
+	ExitConfig  = 4
+	ExitAuth    = 5
+	ExitVCS     = 6
+	ExitUsage   = 64
+)
+
+// Context is what lingo knows about the environment an Action is run in, so
+// the Action doesn't have to rediscover it.
+type Context struct {
+	Version int `json:"version"`
+	// RepoRoot is the absolute path of the repository's root, if the Action
+	// is run in a repository.
+	RepoRoot string `json:"repo_root,omitempty"`
+	// VCS is "git" or "perforce".
+	VCS string `json:"vcs,omitempty"`
+	// WorkingDir is the absolute path the Action is run in.
+	WorkingDir string `json:"working_dir"`
+	Commit     string `json:"commit,omitempty"`
+	// Dotlingos are the absolute paths of the codelingo.yaml files in the
+	// repository, or under the working directory outside of a repository.
+	Dotlingos []string        `json:"dotlingos"`
+	Platform  PlatformContext `json:"platform"`
+	Auth      AuthContext     `json:"auth"`
+}
+
+// PlatformContext holds the addresses of the CodeLingo services.
+type PlatformContext struct {
+	Website  string `json:"website,omitempty"`
+	Platform string `json:"platform,omitempty"`
+	Flow     string `json:"flow,omitempty"`
+}
+
+// AuthContext holds the credentials of the current user.
+type AuthContext struct {
+	Username string `json:"username,omitempty"`
+	Token    string `json:"token,omitempty"`
+}
+
+// Results are the structured results an Action writes to the file named by
+// ResultsEnv.
+type Results struct {
+	Message string   `json:"message,omitempty"`
+	Issues  []*Issue `json:"issues,omitempty"`
+}
+
+// Issue is a problem an Action found in the code.
+type Issue struct {
+	Tenet   string `json:"tenet,omitempty"`
+	File    string `json:"file"`
+	Line    int    `json:"line,omitempty"`
+	Comment string `json:"comment,omitempty"`
+}
+
+// WriteContext writes the context to a new private file in dir, returning
+// its path.
+func WriteContext(dir string, ctx *Context) (string, error) {
+	data, err := json.MarshalIndent(ctx, "", "  ")
+	if err != nil {
+		return "", errors.Trace(err)
+	}
+	// The context holds the user's token, so only they may read it.
+	f, err := ioutil.TempFile(dir, "context-*.json")
+	if err != nil {
+		return "", errors.Trace(err)
+	}
+	defer f.Close()
+	if err := f.Chmod(0600); err != nil {
+		return "", errors.Trace(err)
+	}
+	if _, err := f.Write(data); err != nil {
+		return "", errors.Trace(err)
+	}
+	return f.Name(), nil
+}
+
+// ReadResults reads the results an Action wrote to path. It returns nil if
+// the Action wrote none.
+func ReadResults(path string) (*Results, error) {
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	if len(data) == 0 {
+		return nil, nil
+	}
+	results := &Results{}
+	if err := json.Unmarshal(data, results); err != nil {
+		return nil, errors.Annotate(err, "the Action wrote invalid results")
+	}
+	return results, nil
+}
+
+// Render writes results in a human readable form.
+func (r *Results) Render(w io.Writer) {
+	for _, issue := range r.Issues {
+		location := issue.File
+		if issue.Line > 0 {
+			location = fmt.Sprintf("%s:%d", issue.File, issue.Line)
+		}
+		if issue.Tenet != "" {
+			location += " [" + issue.Tenet + "]"
+		}
+		fmt.Fprintf(w, "%s: %s\n", location, issue.Comment)
+	}
+	if r.Message != "" {
+		fmt.Fprintln(w, r.Message)
+	}
+}
+
+// ExitError returns the error an Action's exit code stands for, or nil if it
+// succeeded.
+func ExitError(name string, code int) error {
+	switch code {
+	case ExitOK:
+		return nil
+	case ExitUsage:
+		return errors.Errorf("Action %s was given invalid arguments. Run `lingo run %s --help` for its usage.", name, name)
+	case ExitIssues:
+		return errors.Errorf("Action %s found issues.", name)
+	case ExitConfig:
+		return errors.Errorf("Action %s is not configured correctly. Please run `lingo config setup`.", name)
+	case ExitAuth:
+		return errors.Errorf("Action %s failed to authenticate. Please run `lingo config setup` to authorise yourself.", name)
+	case ExitVCS:
+		return errors.Errorf("Action %s could not read the repository. Please check it is a git or perforce repository.", name)
+	}
+	return errors.Errorf("Action %s failed with exit code %d.", name, code)
+}
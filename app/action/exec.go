@@ -0,0 +1,74 @@
+package action
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/juju/errors"
+)
+
+// GracePeriod is how long an Action has to exit after being asked to stop,
+// before it is killed.
+const GracePeriod = 10 * time.Second
+
+// Run runs an Action's command in its own process group and waits for it to
+// exit. SIGINT and SIGTERM sent to lingo are forwarded to the group, which
+// is sent SIGTERM if ctx is done. If the command hasn't exited grace after
+// the first signal, the group is killed.
+//
+// Run returns the first signal sent to the command, or nil if none was. It
+// only returns an error if the command couldn't be run; its exit status is
+// in cmd.ProcessState.
+func Run(ctx context.Context, cmd *exec.Cmd, grace time.Duration) (os.Signal, error) {
+	sigc := make(chan os.Signal, 2)
+	signal.Notify(sigc, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(sigc)
+
+	if err := startGroup(cmd); err != nil {
+		return nil, errors.Trace(err)
+	}
+
+	exited := make(chan struct{})
+	go func() {
+		cmd.Wait()
+		close(exited)
+	}()
+
+	var sent os.Signal
+	var kill <-chan time.Time
+	stop := func(sig os.Signal) {
+		signalGroup(cmd, sig)
+		if sent == nil {
+			sent = sig
+			kill = time.After(grace)
+		}
+	}
+
+	done := ctx.Done()
+	for {
+		select {
+		case <-exited:
+			return sent, nil
+		case sig := <-sigc:
+			stop(sig)
+		case <-done:
+			done = nil
+			stop(syscall.SIGTERM)
+		case <-kill:
+			killGroup(cmd)
+		}
+	}
+}
+
+// ExitStatus returns a process's exit status as a shell reports it: 128
+// plus the signal's number for a process killed by a signal.
+func ExitStatus(state *os.ProcessState) int {
+	if status, ok := state.Sys().(syscall.WaitStatus); ok && status.Signaled() {
+		return 128 + int(status.Signal())
+	}
+	return state.ExitCode()
+}
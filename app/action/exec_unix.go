@@ -0,0 +1,33 @@
+//go:build !windows
+// +build !windows
+
+package action
+
+import (
+	"os"
+	"os/exec"
+	"syscall"
+
+	"github.com/juju/errors"
+)
+
+// startGroup starts cmd as the leader of a new process group. lingo stays
+// in the foreground of its terminal, if it has one, so that a Ctrl-C
+// reaches lingo, which forwards it to the group.
+func startGroup(cmd *exec.Cmd) error {
+	if cmd.SysProcAttr == nil {
+		cmd.SysProcAttr = &syscall.SysProcAttr{}
+	}
+	cmd.SysProcAttr.Setpgid = true
+	return errors.Trace(cmd.Start())
+}
+
+func signalGroup(cmd *exec.Cmd, sig os.Signal) {
+	if s, ok := sig.(syscall.Signal); ok {
+		syscall.Kill(-cmd.Process.Pid, s)
+	}
+}
+
+func killGroup(cmd *exec.Cmd) {
+	syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+}
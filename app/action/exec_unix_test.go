@@ -0,0 +1,88 @@
+//go:build !windows
+// +build !windows
+
+package action
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	jc "github.com/juju/testing/checkers"
+	gc "gopkg.in/check.v1"
+)
+
+func (s *actionSuite) TestRunExitStatus(c *gc.C) {
+	cmd := exec.Command("/bin/sh", "-c", "exit 3")
+	stopped, err := Run(context.Background(), cmd, time.Second)
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(stopped, gc.IsNil)
+	c.Assert(ExitStatus(cmd.ProcessState), gc.Equals, 3)
+}
+
+func (s *actionSuite) TestRunTimeout(c *gc.C) {
+	// The command exits gracefully when asked to.
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	cmd := exec.Command("/bin/sh", "-c", "trap 'exit 7' TERM; while :; do sleep 0.01; done")
+	stopped, err := Run(ctx, cmd, 5*time.Second)
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(stopped, gc.Equals, syscall.SIGTERM)
+	c.Assert(ExitStatus(cmd.ProcessState), gc.Equals, 7)
+}
+
+func (s *actionSuite) TestRunForwardsInterrupt(c *gc.C) {
+	// A Ctrl-C in the terminal is sent to lingo, not to the command's group,
+	// which is only interrupted through lingo.
+	ready := filepath.Join(c.MkDir(), "ready")
+	cmd := exec.Command("/bin/sh", "-c", "trap 'exit 5' INT; touch "+ready+"; while :; do sleep 0.01; done")
+	go func() {
+		for i := 0; i < 500; i++ {
+			if _, err := os.Stat(ready); err == nil {
+				syscall.Kill(os.Getpid(), syscall.SIGINT)
+				return
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+	}()
+	stopped, err := Run(context.Background(), cmd, 5*time.Second)
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(stopped, gc.Equals, os.Interrupt)
+	c.Assert(ExitStatus(cmd.ProcessState), gc.Equals, 5)
+	c.Assert(cmd.SysProcAttr.Foreground, jc.IsFalse)
+}
+
+func (s *actionSuite) TestRunKillsGroup(c *gc.C) {
+	// The command ignores SIGTERM, so it and its children are killed once
+	// the grace period is over.
+	pidFile := filepath.Join(c.MkDir(), "pid")
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	cmd := exec.Command("/bin/sh", "-c", "trap '' TERM; sleep 30 & echo $! > "+pidFile+"; wait")
+	start := time.Now()
+	stopped, err := Run(ctx, cmd, 200*time.Millisecond)
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(stopped, gc.Equals, syscall.SIGTERM)
+	c.Assert(ExitStatus(cmd.ProcessState), gc.Equals, 128+int(syscall.SIGKILL))
+	c.Assert(time.Since(start) < 5*time.Second, jc.IsTrue)
+
+	data, err := ioutil.ReadFile(pidFile)
+	c.Assert(err, jc.ErrorIsNil)
+	pid, err := strconv.Atoi(strings.TrimSpace(string(data)))
+	c.Assert(err, jc.ErrorIsNil)
+	// The child may linger as a zombie until it is reaped, but not run.
+	for i := 0; i < 100; i++ {
+		stat, err := ioutil.ReadFile("/proc/" + strconv.Itoa(pid) + "/stat")
+		if err != nil || strings.Contains(string(stat), ") Z ") {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	c.Fatalf("child %d of the command is still running", pid)
+}
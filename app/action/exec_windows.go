@@ -0,0 +1,22 @@
+package action
+
+import (
+	"os"
+	"os/exec"
+
+	"github.com/juju/errors"
+)
+
+// startGroup starts cmd. Windows has no process groups to signal, so it is
+// killed outright when asked to stop.
+func startGroup(cmd *exec.Cmd) error {
+	return errors.Trace(cmd.Start())
+}
+
+func signalGroup(cmd *exec.Cmd, sig os.Signal) {
+	cmd.Process.Kill()
+}
+
+func killGroup(cmd *exec.Cmd) {
+	cmd.Process.Kill()
+}
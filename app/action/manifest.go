@@ -0,0 +1,226 @@
+package action
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/juju/errors"
+	"gopkg.in/yaml.v2"
+)
+
+const (
+	// ManifestFile is the name of the manifest of installed Actions, kept in
+	// <LingoHome>/flows.
+	ManifestFile = "installed.yaml"
+	// LockFile is the name of a project's lockfile, which pins the Actions
+	// the project uses.
+	LockFile = "codelingo.lock"
+)
+
+const lockFileHeader = "# Generated by lingo. Run `lingo install` to install the pinned Actions.\n"
+
+// Installed is an installed, or pinned, version of an Action.
+type Installed struct {
+	Owner   string `yaml:"owner"`
+	Name    string `yaml:"name"`
+	Version string `yaml:"version"`
+	Source  string `yaml:"source,omitempty"`
+	SHA256  string `yaml:"sha256"`
+	// Previous is the version this one replaced, which can be rolled back
+	// to. It is not recorded in lockfiles.
+	Previous *Installed `yaml:"previous,omitempty"`
+}
+
+// FullName returns the Action's name qualified by its owner.
+func (i *Installed) FullName() string {
+	return i.Owner + "/" + i.Name
+}
+
+// Manifest is a list of Actions stored in a file. It records either the
+// installed Actions or the Actions pinned by a lockfile.
+type Manifest struct {
+	path    string
+	Actions []*Installed `yaml:"actions"`
+}
+
+// LoadManifest reads the manifest at path. A missing file is an empty
+// manifest.
+func LoadManifest(path string) (*Manifest, error) {
+	m := &Manifest{path: path}
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return m, nil
+	}
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	if err := yaml.Unmarshal(data, m); err != nil {
+		return nil, errors.Annotatef(err, "invalid Action manifest %s", path)
+	}
+	return m, nil
+}
+
+// Path returns the file the manifest is stored in.
+func (m *Manifest) Path() string {
+	return m.path
+}
+
+// Get returns the entry for an Action, or nil if it isn't listed.
+func (m *Manifest) Get(owner, name string) *Installed {
+	for _, a := range m.Actions {
+		if a.Owner == owner && a.Name == name {
+			return a
+		}
+	}
+	return nil
+}
+
+// Set adds an entry, replacing any existing entry for the same Action.
+func (m *Manifest) Set(installed *Installed) {
+	m.Remove(installed.Owner, installed.Name)
+	m.Actions = append(m.Actions, installed)
+	sort.Slice(m.Actions, func(i, j int) bool {
+		return m.Actions[i].FullName() < m.Actions[j].FullName()
+	})
+}
+
+// Remove removes the entry for an Action, reporting whether it was listed.
+func (m *Manifest) Remove(owner, name string) bool {
+	for i, a := range m.Actions {
+		if a.Owner == owner && a.Name == name {
+			m.Actions = append(m.Actions[:i], m.Actions[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// Save atomically writes the manifest back to its file.
+func (m *Manifest) Save() error {
+	data, err := yaml.Marshal(m)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	if filepath.Base(m.path) == LockFile {
+		data = append([]byte(lockFileHeader), data...)
+	}
+	return errors.Trace(WriteFileAtomic(m.path, data, 0644))
+}
+
+// WriteFileAtomic writes data to a temporary file which is then renamed to
+// path, so readers never see a partially written file.
+func WriteFileAtomic(path string, data []byte, perm os.FileMode) error {
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return errors.Trace(err)
+	}
+	tmp, err := ioutil.TempFile(dir, "."+filepath.Base(path)+".tmp")
+	if err != nil {
+		return errors.Trace(err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return errors.Trace(err)
+	}
+	if err := tmp.Close(); err != nil {
+		return errors.Trace(err)
+	}
+	if err := os.Chmod(tmp.Name(), perm); err != nil {
+		return errors.Trace(err)
+	}
+	return errors.Trace(os.Rename(tmp.Name(), path))
+}
+
+// FindLockFile returns the path of the lockfile in dir or its closest
+// parent, or an empty string if there is none.
+func FindLockFile(dir string) (string, error) {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return "", errors.Trace(err)
+	}
+	for {
+		path := filepath.Join(dir, LockFile)
+		if _, err := os.Stat(path); err == nil {
+			return path, nil
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", nil
+		}
+		dir = parent
+	}
+}
+
+// InstalledNames returns the full names, owner/name, of the Actions
+// installed in the directories under flowsDir.
+func InstalledNames(flowsDir string) ([]string, error) {
+	owners, err := ioutil.ReadDir(flowsDir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+
+	var names []string
+	for _, owner := range owners {
+		if !owner.IsDir() || strings.HasPrefix(owner.Name(), ".") {
+			continue
+		}
+		actions, err := ioutil.ReadDir(filepath.Join(flowsDir, owner.Name()))
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		for _, a := range actions {
+			// Hidden directories hold staged and previous versions.
+			if a.IsDir() && !strings.HasPrefix(a.Name(), ".") {
+				names = append(names, owner.Name()+"/"+a.Name())
+			}
+		}
+	}
+	return names, nil
+}
+
+// ResolveOwner returns the owner of the installed Action with the given
+// name. If several owners provide it, the first of them in order is chosen;
+// if none of them is in order, the name is ambiguous.
+func ResolveOwner(flowsDir, name string, order []string) (string, error) {
+	installed, err := InstalledNames(flowsDir)
+	if err != nil {
+		return "", errors.Trace(err)
+	}
+
+	var owners []string
+	for _, fullName := range installed {
+		parts := strings.SplitN(fullName, "/", 2)
+		if parts[1] == name {
+			owners = append(owners, parts[0])
+		}
+	}
+
+	switch len(owners) {
+	case 0:
+		return "", errors.Errorf("Action %[1]q not found. Try installing it with `lingo install %[1]s`", name)
+	case 1:
+		return owners[0], nil
+	}
+
+	for _, preferred := range order {
+		for _, owner := range owners {
+			if owner == preferred {
+				return owner, nil
+			}
+		}
+	}
+
+	choices := make([]string, len(owners))
+	for i, owner := range owners {
+		choices[i] = "  - " + owner + "/" + name
+	}
+	return "", errors.Errorf("Action %q is provided by more than one owner:\n%s\nRun it as <owner>/%[1]s, or set the preferred owners with actions.owners in platform.yaml.", name, strings.Join(choices, "\n"))
+}
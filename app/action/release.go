@@ -0,0 +1,167 @@
+// Package action installs and manages CodeLingo Actions.
+package action
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/blang/semver"
+	"github.com/juju/errors"
+	"gopkg.in/yaml.v2"
+)
+
+// ReleasesFile is the name of the manifest, published alongside an Action's
+// archives, which lists its released versions.
+const ReleasesFile = "releases.yaml"
+
+// Latest selects the highest released version of an Action.
+const Latest = "latest"
+
+// Releases is the release manifest of an Action.
+type Releases struct {
+	// Versions maps each released version to its archives, keyed by
+	// "<os>/<arch>".
+	Versions map[string]map[string]*Archive `yaml:"versions"`
+}
+
+// Archive is the released command of an Action for one platform.
+type Archive struct {
+	// URL is the location of the archive. A relative URL is resolved
+	// against the Action's base URL. It defaults to
+	// bin/<os>/<arch>/<version>/<archive name>.
+	URL string `yaml:"url,omitempty"`
+	// SHA256 is the hex encoded checksum of the archive.
+	SHA256 string `yaml:"sha256"`
+	// Signature is the base64 encoded ed25519 signature of the archive.
+	Signature string `yaml:"signature,omitempty"`
+}
+
+// ParseReleases parses a release manifest.
+func ParseReleases(data []byte) (*Releases, error) {
+	releases := &Releases{}
+	if err := yaml.Unmarshal(data, releases); err != nil {
+		return nil, errors.Annotate(err, "invalid release manifest")
+	}
+	if len(releases.Versions) == 0 {
+		return nil, errors.New("release manifest lists no versions")
+	}
+	return releases, nil
+}
+
+// Select returns the highest released version satisfying the constraint,
+// which is a version, a semver range such as ">=1.2.0 <2.0.0", or "latest".
+func (r *Releases) Select(constraint string) (string, error) {
+	var versions []semver.Version
+	for v := range r.Versions {
+		version, err := semver.ParseTolerant(v)
+		if err != nil {
+			return "", errors.Annotatef(err, "invalid version %q in release manifest", v)
+		}
+		versions = append(versions, version)
+	}
+	sort.Sort(sort.Reverse(semver.Versions(versions)))
+
+	if constraint == "" || constraint == Latest {
+		return versionKey(r, versions[0]), nil
+	}
+
+	match, err := versionMatcher(constraint)
+	if err != nil {
+		return "", errors.Trace(err)
+	}
+	for _, version := range versions {
+		if match(version) {
+			return versionKey(r, version), nil
+		}
+	}
+	return "", errors.Errorf("no released version matches %q. Available versions: %s", constraint, strings.Join(r.versionList(versions), ", "))
+}
+
+func versionMatcher(constraint string) (func(semver.Version) bool, error) {
+	if version, err := semver.ParseTolerant(constraint); err == nil {
+		return version.Equals, nil
+	}
+	rng, err := semver.ParseRange(constraint)
+	if err != nil {
+		return nil, errors.Errorf("%q is not a valid version or version range", constraint)
+	}
+	return rng, nil
+}
+
+// versionKey returns the manifest key of a parsed version, which may have
+// been written without its "v" prefix or patch number.
+func versionKey(r *Releases, version semver.Version) string {
+	for v := range r.Versions {
+		if parsed, err := semver.ParseTolerant(v); err == nil && parsed.Equals(version) {
+			return v
+		}
+	}
+	return version.String()
+}
+
+func (r *Releases) versionList(versions []semver.Version) []string {
+	list := make([]string, len(versions))
+	for i, version := range versions {
+		list[i] = versionKey(r, version)
+	}
+	return list
+}
+
+// Archive returns the archive of the given version for a platform.
+func (r *Releases) Archive(version, goos, goarch string) (*Archive, error) {
+	archives, ok := r.Versions[version]
+	if !ok {
+		return nil, errors.Errorf("version %s has not been released", version)
+	}
+	archive, ok := archives[goos+"/"+goarch]
+	if !ok || archive == nil {
+		return nil, errors.Errorf("version %s has not been released for %s/%s", version, goos, goarch)
+	}
+	return archive, nil
+}
+
+// ArchiveName returns the name of an Action's archive on the given OS.
+func ArchiveName(goos string) string {
+	if goos == "windows" {
+		return "cmd.exe.zip"
+	}
+	return "cmd.tar.gz"
+}
+
+// ArchiveURL returns the absolute URL of an archive, given the base URL of
+// its Action.
+func (a *Archive) ArchiveURL(baseURL, version, goos, goarch string) string {
+	switch {
+	case a.URL == "":
+		return fmt.Sprintf("%s/bin/%s/%s/%s/%s", baseURL, goos, goarch, version, ArchiveName(goos))
+	case strings.Contains(a.URL, "://"):
+		return a.URL
+	}
+	return baseURL + "/" + strings.TrimPrefix(a.URL, "/")
+}
+
+// ParseRef splits an Action reference of the form [owner/]name[@version].
+// The owner and version are empty if not given.
+func ParseRef(ref string) (owner, name, version string, err error) {
+	if i := strings.LastIndex(ref, "@"); i >= 0 {
+		ref, version = ref[:i], ref[i+1:]
+		if version == "" {
+			return "", "", "", errors.Errorf("%q is missing a version after @", ref+"@")
+		}
+	}
+
+	parts := strings.Split(ref, "/")
+	switch {
+	case len(parts) == 1:
+		name = parts[0]
+	case len(parts) == 2:
+		owner, name = parts[0], parts[1]
+	default:
+		return "", "", "", errors.Errorf("%q is not a valid Action name", ref)
+	}
+	if name == "" || (len(parts) == 2 && owner == "") {
+		return "", "", "", errors.Errorf("%q is not a valid Action name", ref)
+	}
+	return owner, name, version, nil
+}
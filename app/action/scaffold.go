@@ -0,0 +1,323 @@
+package action
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/template"
+
+	"github.com/juju/errors"
+)
+
+// Scaffold describes a new Action written in Go.
+type Scaffold struct {
+	Owner   string
+	Name    string
+	Version string
+	// Module is the Go module path of the Action.
+	Module string
+}
+
+// Write generates the Action's files in dir, which must not exist or be
+// empty, and returns their names.
+func (s *Scaffold) Write(dir string) ([]string, error) {
+	if entries, err := ioutil.ReadDir(dir); err == nil && len(entries) > 0 {
+		return nil, errors.Errorf("%s already exists and is not empty", dir)
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, errors.Trace(err)
+	}
+
+	var names []string
+	for name := range scaffoldFiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		tmpl, err := template.New(name).Funcs(template.FuncMap{
+			// Go struct tags are quoted with backticks, which the raw
+			// strings holding the templates can't contain.
+			"json": func(field string) string { return "`json:\"" + field + "\"`" },
+		}).Parse(strings.TrimPrefix(scaffoldFiles[name], "\n"))
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		var buf bytes.Buffer
+		if err := tmpl.Execute(&buf, s); err != nil {
+			return nil, errors.Trace(err)
+		}
+		if err := ioutil.WriteFile(filepath.Join(dir, name), buf.Bytes(), 0644); err != nil {
+			return nil, errors.Trace(err)
+		}
+	}
+	return names, nil
+}
+
+// scaffoldFiles are the templates of a new Action's files, less their
+// leading newline.
+var scaffoldFiles = map[string]string{
+	".gitignore": `
+/cmd
+/cmd.exe
+/dist/
+`,
+
+	"go.mod": `
+module {{.Module}}
+
+go 1.14
+`,
+
+	SpecFile: `
+owner: {{.Owner}}
+name: {{.Name}}
+version: {{.Version}}
+description: TODO describe what {{.Name}} does.
+
+# The permissions and limits of the Action when lingo runs it in a sandbox.
+# permissions:
+#   network: true
+#   env: [GOPATH]
+# limits:
+#   cpu: 600     # seconds
+#   memory: 4096 # MiB
+`,
+
+	"Makefile": `
+OWNER   := {{.Owner}}
+NAME    := {{.Name}}
+VERSION ?= {{.Version}}
+
+# The platforms released Actions are built for, as <os>/<arch>.
+PLATFORMS ?= linux/amd64 linux/arm64 darwin/amd64 darwin/arm64 windows/amd64
+
+DIST   := dist/$(OWNER)/$(NAME)
+SHA256 ?= shasum -a 256
+
+.PHONY: build install run test release clean
+
+# Build the Action for this machine.
+build:
+	go build -o cmd$(shell go env GOEXE) .
+
+# Install the Action from this directory, replacing any installed version.
+install: build
+	lingo install --owner $(OWNER) --name $(NAME) .
+
+# Install and run the Action in the current directory.
+run: install
+	lingo run $(OWNER)/$(NAME)
+
+test:
+	go test ./...
+
+# Build the Action for every platform, laid out as a registry in dist:
+#   dist/<owner>/<name>/bin/<os>/<arch>/<version>/cmd.tar.gz
+#   dist/<owner>/<name>/releases.yaml
+# Install it with ` + "`lingo install --owner $(OWNER) --name $(NAME) ./dist`" + `,
+# or publish dist to a registry.
+release:
+	@for platform in $(PLATFORMS); do \
+		os=$${platform%/*}; arch=$${platform#*/}; \
+		out=$(DIST)/bin/$$os/$$arch/$(VERSION); \
+		mkdir -p $$out; \
+		echo "Building $$out"; \
+		if [ $$os = windows ]; then \
+			GOOS=$$os GOARCH=$$arch go build -o $$out/cmd.exe . && \
+			cp lingo_action.yaml $$out/ && \
+			(cd $$out && zip -q cmd.exe.zip cmd.exe lingo_action.yaml && rm cmd.exe lingo_action.yaml); \
+		else \
+			GOOS=$$os GOARCH=$$arch go build -o $$out/cmd . && \
+			cp lingo_action.yaml $$out/ && \
+			tar -czf $$out/cmd.tar.gz -C $$out cmd lingo_action.yaml && \
+			rm $$out/cmd $$out/lingo_action.yaml; \
+		fi || exit 1; \
+	done
+	@{ echo "versions:"; \
+	for version in $$(ls $(DIST)/bin/*/* | grep -v : | sort -u); do \
+		echo "  \"$$version\":"; \
+		for archive in $(DIST)/bin/*/*/$$version/cmd*; do \
+			platform=$$(echo $$archive | sed 's|^$(DIST)/bin/||; s|/[^/]*/[^/]*$$||'); \
+			echo "    $$platform:"; \
+			echo "      sha256: $$($(SHA256) $$archive | cut -d' ' -f1)"; \
+		done; \
+	done; } > $(DIST)/releases.yaml
+	@echo "Wrote $(DIST)/releases.yaml"
+
+clean:
+	rm -rf cmd cmd.exe dist
+`,
+
+	"main.go": `
+// Command {{.Name}} is a CodeLingo Action. lingo runs it with
+// ` + "`lingo run {{.Owner}}/{{.Name}}`" + `, passing it the context of the run and reading
+// back its results, as described in
+// https://github.com/codelingo/lingo/blob/master/doc/ACTIONS.md.
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+)
+
+// The exit codes lingo understands.
+const (
+	exitOK      = 0
+	exitFailure = 1
+	exitIssues  = 3
+	exitConfig  = 4
+	exitAuth    = 5
+	exitVCS     = 6
+	exitUsage   = 64
+)
+
+// runContext is what lingo knows about the environment the Action is run
+// in.
+type runContext struct {
+	Version    int      {{json "version"}}
+	RepoRoot   string   {{json "repo_root"}}
+	VCS        string   {{json "vcs"}}
+	WorkingDir string   {{json "working_dir"}}
+	Commit     string   {{json "commit"}}
+	Dotlingos  []string {{json "dotlingos"}}
+	Platform   struct {
+		Website  string {{json "website"}}
+		Platform string {{json "platform"}}
+		Flow     string {{json "flow"}}
+	} {{json "platform"}}
+	Auth struct {
+		Username string {{json "username"}}
+		Token    string {{json "token"}}
+	} {{json "auth"}}
+}
+
+// results are rendered by lingo once the Action exits.
+type results struct {
+	Message string   {{json "message,omitempty"}}
+	Issues  []*issue {{json "issues,omitempty"}}
+}
+
+type issue struct {
+	Tenet   string {{json "tenet,omitempty"}}
+	File    string {{json "file"}}
+	Line    int    {{json "line,omitempty"}}
+	Comment string {{json "comment,omitempty"}}
+}
+
+func main() {
+	os.Exit(run(os.Args[1:]))
+}
+
+func run(args []string) int {
+	flags := flag.NewFlagSet("{{.Name}}", flag.ContinueOnError)
+	if err := flags.Parse(args); err != nil {
+		return exitUsage
+	}
+
+	ctx, err := readContext()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return exitFailure
+	}
+	if ctx.RepoRoot == "" {
+		fmt.Fprintln(os.Stderr, "{{.Name}} must be run in a repository")
+		return exitVCS
+	}
+
+	// TODO implement the Action. This example counts the codelingo.yaml files.
+	res := &results{
+		Message: fmt.Sprintf("Found %d codelingo.yaml file(s) in %s.", len(ctx.Dotlingos), ctx.RepoRoot),
+	}
+
+	if err := writeResults(res); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return exitFailure
+	}
+	if len(res.Issues) > 0 {
+		return exitIssues
+	}
+	return exitOK
+}
+
+func readContext() (*runContext, error) {
+	path := os.Getenv("LINGO_CONTEXT")
+	if path == "" {
+		return nil, fmt.Errorf("LINGO_CONTEXT is not set. Run {{.Name}} with ` + "`lingo run {{.Owner}}/{{.Name}}`" + `")
+	}
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	ctx := &runContext{}
+	return ctx, json.Unmarshal(data, ctx)
+}
+
+func writeResults(res *results) error {
+	path := os.Getenv("LINGO_RESULTS")
+	if path == "" {
+		return nil
+	}
+	data, err := json.Marshal(res)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, data, 0644)
+}
+`,
+
+	"main_test.go": `
+package main
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestRun(t *testing.T) {
+	dir, err := ioutil.TempDir("", "{{.Name}}")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	contextPath := filepath.Join(dir, "context.json")
+	resultsPath := filepath.Join(dir, "results.json")
+	ctx := &runContext{Version: 1, RepoRoot: dir, WorkingDir: dir, Dotlingos: []string{filepath.Join(dir, "codelingo.yaml")}}
+	data, err := json.Marshal(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(contextPath, data, 0600); err != nil {
+		t.Fatal(err)
+	}
+	os.Setenv("LINGO_CONTEXT", contextPath)
+	os.Setenv("LINGO_RESULTS", resultsPath)
+
+	if code := run(nil); code != exitOK {
+		t.Fatalf("run exited with %d", code)
+	}
+
+	data, err = ioutil.ReadFile(resultsPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res := &results{}
+	if err := json.Unmarshal(data, res); err != nil {
+		t.Fatal(err)
+	}
+	if res.Message == "" {
+		t.Error("run wrote no message")
+	}
+}
+`,
+}
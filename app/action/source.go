@@ -0,0 +1,189 @@
+package action
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"github.com/juju/errors"
+)
+
+// DefaultRegistry is the registry Actions are installed from unless another
+// is configured.
+const DefaultRegistry = "https://github.com/codelingo/actions/raw/master/actions"
+
+// LocalVersion is the version recorded for Actions installed from a local
+// directory or archive, which have no releases to upgrade to.
+const LocalVersion = "local"
+
+// Source provides the commands of Actions.
+type Source interface {
+	// Fetch returns the command of the version of an Action matching the
+	// constraint.
+	Fetch(owner, name, constraint string) (*Fetched, error)
+}
+
+// Fetched is an Action's command, ready to be installed.
+type Fetched struct {
+	Version string
+	// Origin is the URL or path the command was fetched from.
+	Origin string
+	// ArchiveName is the file name of the archive in Data, or empty if Data
+	// is the command itself.
+	ArchiveName string
+	Data        []byte
+	// SHA256 is the hex encoded checksum of Data.
+	SHA256 string
+	// Spec is the Action's lingo_action.yaml, if it isn't in the archive.
+	Spec []byte
+}
+
+// Registry is a Source which serves released, versioned Actions under
+// <URL>/<owner>/<name>. The URL may be a file:// URL.
+type Registry struct {
+	URL string
+	// PublicKey, if set, is the base64 encoded ed25519 key each archive
+	// must be signed with.
+	PublicKey string
+}
+
+// Releases returns the release manifest of an Action and the URL it was
+// published at.
+func (r *Registry) Releases(owner, name string) (string, *Releases, error) {
+	baseURL := fmt.Sprintf("%s/%s/%s", strings.TrimSuffix(r.URL, "/"), owner, name)
+	data, err := Fetch(baseURL + "/" + ReleasesFile)
+	if err != nil {
+		return "", nil, errors.Annotatef(err, "could not find releases of Action %s/%s", owner, name)
+	}
+	releases, err := ParseReleases(data)
+	if err != nil {
+		return "", nil, errors.Trace(err)
+	}
+	return baseURL, releases, nil
+}
+
+func (r *Registry) Fetch(owner, name, constraint string) (*Fetched, error) {
+	baseURL, releases, err := r.Releases(owner, name)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	version, err := releases.Select(constraint)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	archive, err := releases.Archive(version, runtime.GOOS, runtime.GOARCH)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+
+	url := archive.ArchiveURL(baseURL, version, runtime.GOOS, runtime.GOARCH)
+	data, err := Fetch(url)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	if err := archive.Verify(data, r.PublicKey); err != nil {
+		return nil, errors.Annotatef(err, "refusing to install %s/%s@%s", owner, name, version)
+	}
+	return &Fetched{
+		Version:     version,
+		Origin:      url,
+		ArchiveName: ArchiveName(runtime.GOOS),
+		Data:        data,
+		SHA256:      strings.ToLower(archive.SHA256),
+	}, nil
+}
+
+// Local is a Source which installs an Action from a path on disk: a .tar.gz
+// or .zip archive, a directory holding a built cmd, or a directory laid out
+// as a registry.
+type Local struct {
+	Path string
+}
+
+func (l *Local) Fetch(owner, name, constraint string) (*Fetched, error) {
+	path, err := filepath.Abs(l.Path)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+
+	if !info.IsDir() {
+		if !IsArchive(path) {
+			return nil, errors.Errorf("%s is not a .tar.gz or .zip archive", l.Path)
+		}
+		return readLocal(path, filepath.Base(path))
+	}
+
+	if _, err := os.Stat(filepath.Join(path, owner, name, ReleasesFile)); err == nil {
+		registry := &Registry{URL: "file://" + filepath.ToSlash(path)}
+		return registry.Fetch(owner, name, constraint)
+	}
+	for _, cmd := range []string{"cmd", "cmd.exe"} {
+		if _, err := os.Stat(filepath.Join(path, cmd)); err != nil {
+			continue
+		}
+		fetched, err := readLocal(filepath.Join(path, cmd), "")
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		fetched.Spec, err = ioutil.ReadFile(filepath.Join(path, SpecFile))
+		if err != nil && !os.IsNotExist(err) {
+			return nil, errors.Trace(err)
+		}
+		return fetched, nil
+	}
+	return nil, errors.Errorf("%s holds neither a cmd nor a registry with %s/%s", l.Path, owner, name)
+}
+
+func readLocal(path, archiveName string) (*Fetched, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	sum := sha256.Sum256(data)
+	return &Fetched{
+		Version:     LocalVersion,
+		Origin:      path,
+		ArchiveName: archiveName,
+		Data:        data,
+		SHA256:      hex.EncodeToString(sum[:]),
+	}, nil
+}
+
+// IsArchive reports whether path names an archive an Action can be
+// installed from.
+func IsArchive(path string) bool {
+	for _, ext := range []string{".tar.gz", ".tgz", ".zip"} {
+		if strings.HasSuffix(path, ext) {
+			return true
+		}
+	}
+	return false
+}
+
+// IsLocalPath reports whether an install argument refers to a path on disk
+// rather than an Action in a registry.
+func IsLocalPath(arg string) bool {
+	if strings.HasPrefix(arg, ".") || filepath.IsAbs(arg) || IsArchive(arg) {
+		return true
+	}
+	// Action names have at most one slash, between the owner and name.
+	return strings.Count(filepath.ToSlash(arg), "/") > 1
+}
+
+// LocalName derives an Action's name from the path it is installed from.
+func LocalName(path string) string {
+	base := filepath.Base(filepath.Clean(path))
+	for _, ext := range []string{".tar.gz", ".tgz", ".zip"} {
+		base = strings.TrimSuffix(base, ext)
+	}
+	return base
+}
@@ -0,0 +1,83 @@
+package action
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/juju/errors"
+	"gopkg.in/yaml.v2"
+)
+
+// SpecFile is the name of the file describing an Action, shipped alongside
+// its cmd.
+const SpecFile = "lingo_action.yaml"
+
+const (
+	// DefaultCPULimit is the CPU time, in seconds, a sandboxed Action may
+	// use unless its spec declares otherwise.
+	DefaultCPULimit = 10 * 60
+	// DefaultMemoryLimit is the memory, in MiB, a sandboxed Action may use
+	// unless its spec declares otherwise.
+	DefaultMemoryLimit = 4096
+)
+
+// Spec describes an Action and the permissions it needs when sandboxed.
+type Spec struct {
+	Owner       string      `yaml:"owner"`
+	Name        string      `yaml:"name"`
+	Version     string      `yaml:"version"`
+	Description string      `yaml:"description,omitempty"`
+	Permissions Permissions `yaml:"permissions,omitempty"`
+	Limits      Limits      `yaml:"limits,omitempty"`
+}
+
+// Permissions are the privileges a sandboxed Action asks for.
+type Permissions struct {
+	// Network allows the Action to use the network.
+	Network bool `yaml:"network,omitempty"`
+	// Env lists environment variables passed through to the Action, beyond
+	// the few every Action is given.
+	Env []string `yaml:"env,omitempty"`
+}
+
+// Limits are the resources a sandboxed Action may use.
+type Limits struct {
+	// CPU is the CPU time in seconds.
+	CPU int `yaml:"cpu,omitempty"`
+	// Memory is the address space in MiB.
+	Memory int `yaml:"memory,omitempty"`
+}
+
+// LoadSpec reads the spec of the Action installed in dir. Actions without a
+// spec get no permissions and the default limits.
+func LoadSpec(dir string) (*Spec, error) {
+	spec := &Spec{}
+	data, err := ioutil.ReadFile(filepath.Join(dir, SpecFile))
+	if os.IsNotExist(err) {
+		return spec, nil
+	}
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	if err := yaml.UnmarshalStrict(data, spec); err != nil {
+		return nil, errors.Annotatef(err, "invalid %s", filepath.Join(dir, SpecFile))
+	}
+	return spec, nil
+}
+
+// CPULimit returns the CPU time, in seconds, the Action may use.
+func (s *Spec) CPULimit() int {
+	if s.Limits.CPU > 0 {
+		return s.Limits.CPU
+	}
+	return DefaultCPULimit
+}
+
+// MemoryLimit returns the memory, in MiB, the Action may use.
+func (s *Spec) MemoryLimit() int {
+	if s.Limits.Memory > 0 {
+		return s.Limits.Memory
+	}
+	return DefaultMemoryLimit
+}
@@ -0,0 +1,85 @@
+package action
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/juju/errors"
+)
+
+// PreviousDir returns the directory which keeps the version of the Action
+// installed in dir that was replaced by the last install or upgrade.
+func PreviousDir(dir string) string {
+	return filepath.Join(filepath.Dir(dir), "."+filepath.Base(dir)+".previous")
+}
+
+// StagingDir creates a directory, next to the Action installed in dir, to
+// prepare a new version in before it is swapped in. It has the same mode as
+// the other directories of installed Actions.
+func StagingDir(dir string) (string, error) {
+	parent := filepath.Dir(dir)
+	if err := os.MkdirAll(parent, 0755); err != nil {
+		return "", errors.Trace(err)
+	}
+	staging, err := ioutil.TempDir(parent, "."+filepath.Base(dir)+".new")
+	if err != nil {
+		return "", errors.Trace(err)
+	}
+	// TempDir creates directories only their owner can read.
+	if err := os.Chmod(staging, 0755); err != nil {
+		os.RemoveAll(staging)
+		return "", errors.Trace(err)
+	}
+	return staging, nil
+}
+
+// Swap replaces the Action installed in dir with the one in newDir. The
+// replaced version is kept in PreviousDir(dir). If the swap fails, the
+// installed version is left in place.
+func Swap(dir, newDir string) error {
+	prev := PreviousDir(dir)
+	if err := os.RemoveAll(prev); err != nil {
+		return errors.Trace(err)
+	}
+
+	_, err := os.Stat(dir)
+	hadInstalled := err == nil
+	if hadInstalled {
+		if err := os.Rename(dir, prev); err != nil {
+			return errors.Trace(err)
+		}
+	}
+	if err := os.Rename(newDir, dir); err != nil {
+		if hadInstalled {
+			if restoreErr := os.Rename(prev, dir); restoreErr != nil {
+				return errors.Annotatef(err, "could not restore %s from %s: %v", dir, prev, restoreErr)
+			}
+		}
+		return errors.Trace(err)
+	}
+	return nil
+}
+
+// Rollback swaps the Action installed in dir with its previous version.
+func Rollback(dir string) error {
+	prev := PreviousDir(dir)
+	if _, err := os.Stat(prev); err != nil {
+		return errors.Errorf("there is no previous version of %s to roll back to", filepath.Base(dir))
+	}
+
+	tmp := filepath.Join(filepath.Dir(dir), "."+filepath.Base(dir)+".rollback")
+	if err := os.RemoveAll(tmp); err != nil {
+		return errors.Trace(err)
+	}
+	if err := os.Rename(dir, tmp); err != nil {
+		return errors.Trace(err)
+	}
+	if err := os.Rename(prev, dir); err != nil {
+		if restoreErr := os.Rename(tmp, dir); restoreErr != nil {
+			return errors.Annotatef(err, "could not restore %s: %v", dir, restoreErr)
+		}
+		return errors.Trace(err)
+	}
+	return errors.Trace(os.Rename(tmp, prev))
+}
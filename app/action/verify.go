@@ -0,0 +1,70 @@
+package action
+
+import (
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"io/ioutil"
+	"net/http"
+	"path/filepath"
+	"strings"
+
+	"github.com/juju/errors"
+)
+
+// Fetch downloads the resource at url, failing on any non-200 response.
+// file:// URLs are read from disk.
+func Fetch(url string) ([]byte, error) {
+	if strings.HasPrefix(url, "file://") {
+		data, err := ioutil.ReadFile(filepath.FromSlash(strings.TrimPrefix(url, "file://")))
+		return data, errors.Trace(err)
+	}
+
+	resp, err := http.Get(url)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, errors.Errorf("failed to download %s: %s", url, resp.Status)
+	}
+
+	data, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, errors.Annotatef(err, "failed to download %s", url)
+	}
+	return data, nil
+}
+
+// Verify checks an archive against the checksum in its release manifest
+// and, if a public key is given, against its signature.
+func (a *Archive) Verify(data []byte, publicKey string) error {
+	if a.SHA256 == "" {
+		return errors.New("the release manifest has no checksum for this archive")
+	}
+	sum := sha256.Sum256(data)
+	if got := hex.EncodeToString(sum[:]); !strings.EqualFold(got, a.SHA256) {
+		return errors.Errorf("checksum mismatch: expected sha256 %s, got %s", a.SHA256, got)
+	}
+
+	if publicKey == "" {
+		return nil
+	}
+	if a.Signature == "" {
+		return errors.New("a public key is configured but the archive is not signed")
+	}
+	key, err := base64.StdEncoding.DecodeString(publicKey)
+	if err != nil || len(key) != ed25519.PublicKeySize {
+		return errors.New("the configured public key is not a base64 encoded ed25519 key")
+	}
+	sig, err := base64.StdEncoding.DecodeString(a.Signature)
+	if err != nil {
+		return errors.Annotate(err, "invalid archive signature")
+	}
+	if !ed25519.Verify(ed25519.PublicKey(key), data, sig) {
+		return errors.New("signature verification failed")
+	}
+	return nil
+}
@@ -24,7 +24,7 @@ func New() *cli.App {
 	app.Commands = commands.All()
 	app.Version = common.ClientVersion
 	// TODO(waigani) once messaging is implemented, add -q flag to suppress them here.
-	// app.Flags = common.GlobalOptions
+	app.Flags = util.GlobalOptions
 	app.CommandNotFound = func(c *cli.Context, command string) {
 		util.FatalOSErr(errors.Errorf("'%s' is not a lingo command. See 'lingo --help'.", command))
 	}
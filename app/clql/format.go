@@ -0,0 +1,378 @@
+package clql
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/juju/errors"
+)
+
+// indentWidth is the number of spaces used for each level of a formatted
+// query.
+const indentWidth = 2
+
+// SyntaxError is returned when a query cannot be formatted. Line is 1-based
+// and relative to the start of the query.
+type SyntaxError struct {
+	Line int
+	Msg  string
+}
+
+func (e *SyntaxError) Error() string {
+	return fmt.Sprintf("line %d: %s", e.Line, e.Msg)
+}
+
+type lineKind int
+
+const (
+	blankLine lineKind = iota
+	commentLine
+	importLine
+	decoratorLine
+	codeLine
+)
+
+// line is a single parsed line of a query.
+type line struct {
+	num     int
+	kind    lineKind
+	indent  int
+	level   int
+	text    string
+	comment string
+}
+
+// Format returns the canonical form of the given CLQL query. Indentation is
+// normalised to two spaces per level, decorators are placed on their own line
+// directly above the fact they decorate, arguments and operators are evenly
+// spaced and all imports are grouped at the top of the query. Comments are
+// preserved.
+func Format(src string) (string, error) {
+	lines, err := parseLines(src)
+	if err != nil {
+		return "", errors.Trace(err)
+	}
+
+	imports, body, err := splitImports(lines)
+	if err != nil {
+		return "", errors.Trace(err)
+	}
+
+	if err := assignLevels(body); err != nil {
+		return "", errors.Trace(err)
+	}
+
+	var out []string
+	out = append(out, formatImports(imports)...)
+	if len(out) > 0 && hasCode(body) {
+		out = append(out, "")
+	}
+
+	lastBlank := true
+	for i, l := range body {
+		switch l.kind {
+		case blankLine:
+			// Collapse runs of blank lines and never separate a
+			// decorator from the fact it decorates.
+			if lastBlank || (i > 0 && body[i-1].kind == decoratorLine) {
+				continue
+			}
+			out = append(out, "")
+			lastBlank = true
+			continue
+		case commentLine:
+			out = append(out, indent(l.level)+l.comment)
+		default:
+			out = append(out, indent(l.level)+joinComment(l.text, l.comment))
+		}
+		lastBlank = false
+	}
+
+	for len(out) > 0 && out[len(out)-1] == "" {
+		out = out[:len(out)-1]
+	}
+	if len(out) == 0 {
+		return "", nil
+	}
+	return strings.Join(out, "\n") + "\n", nil
+}
+
+func parseLines(src string) ([]*line, error) {
+	var lines []*line
+	for i, raw := range strings.Split(strings.TrimRight(src, "\n"), "\n") {
+		l := &line{num: i + 1}
+		trimmed := strings.TrimSpace(raw)
+		l.indent = indentOf(raw)
+
+		switch {
+		case trimmed == "":
+			l.kind = blankLine
+		case strings.HasPrefix(trimmed, "#"):
+			l.kind = commentLine
+			l.comment = trimmed
+		case isGroupDelim(trimmed):
+			// The parentheses of an import group span several lines.
+			l.kind = codeLine
+			l.text, l.comment = splitGroupDelim(trimmed)
+			if l.text != ")" {
+				l.kind = importLine
+			}
+		default:
+			toks, comment, err := scanLine(trimmed)
+			if err != nil {
+				return nil, &SyntaxError{Line: l.num, Msg: err.Error()}
+			}
+			text := renderTokens(toks)
+			l.text = text
+			l.comment = comment
+			switch {
+			case text == "import" || strings.HasPrefix(text, "import ") || strings.HasPrefix(text, "import("):
+				l.kind = importLine
+			case strings.HasPrefix(text, "@"):
+				l.kind = decoratorLine
+			default:
+				l.kind = codeLine
+			}
+		}
+		lines = append(lines, l)
+	}
+	return lines, nil
+}
+
+// isGroupDelim reports whether the trimmed line opens or closes an import
+// group.
+func isGroupDelim(trimmed string) bool {
+	var rest string
+	switch {
+	case strings.HasPrefix(trimmed, ")"):
+		rest = trimmed[1:]
+	case strings.HasPrefix(trimmed, "import"):
+		rest = strings.TrimSpace(strings.TrimPrefix(trimmed, "import"))
+		if !strings.HasPrefix(rest, "(") {
+			return false
+		}
+		rest = rest[1:]
+	default:
+		return false
+	}
+	rest = strings.TrimSpace(rest)
+	return rest == "" || strings.HasPrefix(rest, "#")
+}
+
+func splitGroupDelim(trimmed string) (string, string) {
+	text := "import ("
+	if strings.HasPrefix(trimmed, ")") {
+		text = ")"
+	}
+	rest := strings.TrimSpace(trimmed[strings.Index(trimmed, text[len(text)-1:])+1:])
+	if strings.HasPrefix(rest, "#") {
+		return text, rest
+	}
+	return text, ""
+}
+
+// indentOf returns the width of the leading whitespace of s. Tabs are
+// counted as four columns.
+func indentOf(s string) int {
+	width := 0
+	for _, r := range s {
+		switch r {
+		case ' ':
+			width++
+		case '\t':
+			width += 4
+		default:
+			return width
+		}
+	}
+	return width
+}
+
+type importSpec struct {
+	path     string
+	comments []string
+	trailing string
+}
+
+// splitImports removes the import statements from the top of the query and
+// returns them separately from the remaining lines.
+func splitImports(lines []*line) ([]*importSpec, []*line, error) {
+	var imports []*importSpec
+	var pending []string
+	i := 0
+	for ; i < len(lines); i++ {
+		l := lines[i]
+		switch l.kind {
+		case blankLine:
+			continue
+		case commentLine:
+			pending = append(pending, l.comment)
+			continue
+		case importLine:
+		default:
+			// Comments directly above the first fact belong to it.
+			return imports, append(commentLines(pending), lines[i:]...), nil
+		}
+
+		rest := strings.TrimSpace(strings.TrimPrefix(l.text, "import"))
+		if rest != "(" {
+			// A group written on a single line.
+			if strings.HasPrefix(rest, "(") && strings.HasSuffix(rest, ")") {
+				rest = strings.TrimSpace(rest[1 : len(rest)-1])
+			}
+			if rest == "" {
+				return nil, nil, &SyntaxError{Line: l.num, Msg: "import is missing a lexicon path"}
+			}
+			for _, path := range strings.Fields(rest) {
+				imports = append(imports, &importSpec{path: path, comments: pending, trailing: l.comment})
+				pending = nil
+			}
+			continue
+		}
+
+		// Parenthesised import group.
+		closed := false
+		for i++; i < len(lines); i++ {
+			g := lines[i]
+			switch {
+			case g.kind == blankLine:
+			case g.kind == commentLine:
+				pending = append(pending, g.comment)
+			case g.text == ")":
+				closed = true
+			default:
+				imports = append(imports, &importSpec{path: g.text, comments: pending, trailing: g.comment})
+				pending = nil
+			}
+			if closed {
+				break
+			}
+		}
+		if !closed {
+			return nil, nil, &SyntaxError{Line: l.num, Msg: "import group is missing a closing parenthesis"}
+		}
+	}
+
+	// The query is only imports and comments.
+	return imports, commentLines(pending), nil
+}
+
+func commentLines(comments []string) []*line {
+	var ls []*line
+	for _, c := range comments {
+		ls = append(ls, &line{kind: commentLine, comment: c, level: -1})
+	}
+	return ls
+}
+
+func formatImports(imports []*importSpec) []string {
+	if len(imports) == 0 {
+		return nil
+	}
+
+	seen := make(map[string]bool)
+	var specs []*importSpec
+	for _, imp := range imports {
+		if seen[imp.path] {
+			continue
+		}
+		seen[imp.path] = true
+		specs = append(specs, imp)
+	}
+	sort.SliceStable(specs, func(i, j int) bool {
+		return specs[i].path < specs[j].path
+	})
+
+	var out []string
+	if len(specs) == 1 {
+		out = append(out, specs[0].comments...)
+		return append(out, joinComment("import "+specs[0].path, specs[0].trailing))
+	}
+
+	out = append(out, "import (")
+	for _, imp := range specs {
+		for _, c := range imp.comments {
+			out = append(out, indent(1)+c)
+		}
+		out = append(out, indent(1)+joinComment(imp.path, imp.trailing))
+	}
+	return append(out, ")")
+}
+
+// assignLevels sets the logical nesting level of each line from the relative
+// indentation of the source.
+func assignLevels(lines []*line) error {
+	var stack []int
+	for _, l := range lines {
+		if l.kind != codeLine {
+			continue
+		}
+		for len(stack) > 0 && stack[len(stack)-1] > l.indent {
+			stack = stack[:len(stack)-1]
+		}
+		if len(stack) == 0 || stack[len(stack)-1] < l.indent {
+			stack = append(stack, l.indent)
+		}
+		l.level = len(stack) - 1
+	}
+
+	// Decorators and comments take the level of the next fact. Trailing
+	// comments fall back to the level of the previous fact.
+	next := -1
+	for i := len(lines) - 1; i >= 0; i-- {
+		l := lines[i]
+		switch l.kind {
+		case codeLine:
+			next = l.level
+		case decoratorLine:
+			if next < 0 || nextCodeKind(lines[i+1:]) != codeLine {
+				return &SyntaxError{Line: l.num, Msg: "decorator " + strings.Fields(l.text)[0] + " must be followed by a fact"}
+			}
+			l.level = next
+		case commentLine:
+			l.level = next
+		}
+	}
+	prev := 0
+	for _, l := range lines {
+		switch {
+		case l.kind == codeLine:
+			prev = l.level
+		case l.level < 0:
+			l.level = prev
+		}
+	}
+	return nil
+}
+
+// nextCodeKind returns the kind of the first line in ls which is not blank,
+// a comment or another decorator.
+func nextCodeKind(ls []*line) lineKind {
+	for _, l := range ls {
+		if l.kind != blankLine && l.kind != commentLine && l.kind != decoratorLine {
+			return l.kind
+		}
+	}
+	return blankLine
+}
+
+func hasCode(lines []*line) bool {
+	for _, l := range lines {
+		if l.kind != blankLine {
+			return true
+		}
+	}
+	return false
+}
+
+func indent(level int) string {
+	return strings.Repeat(" ", level*indentWidth)
+}
+
+func joinComment(text, comment string) string {
+	if comment == "" {
+		return text
+	}
+	return text + " " + comment
+}
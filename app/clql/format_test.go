@@ -0,0 +1,198 @@
+package clql_test
+
+import (
+	"testing"
+
+	"github.com/codelingo/lingo/app/clql"
+	jc "github.com/juju/testing/checkers"
+	gc "gopkg.in/check.v1"
+)
+
+func Test(t *testing.T) {
+	gc.TestingT(t)
+}
+
+type clqlSuite struct{}
+
+var _ = gc.Suite(&clqlSuite{})
+
+func (s *clqlSuite) TestFormat(c *gc.C) {
+	cases := []struct {
+		src, expected string
+	}{
+		{
+			// Indentation is normalised to two spaces per level.
+			src: `
+import codelingo/ast/go
+
+go.file(depth = any):
+    go.func_decl:
+        go.ident:
+            name == "main"
+`[1:],
+			expected: `
+import codelingo/ast/go
+
+go.file(depth = any):
+  go.func_decl:
+    go.ident:
+      name == "main"
+`[1:],
+		}, {
+			// Arguments and operators are evenly spaced.
+			src: `
+import codelingo/ast/go
+
+go.file( depth=any ) :
+  go.basic_lit:
+    value   as  v
+    regex(/^a,b$/,v)
+    value!="x"
+`[1:],
+			expected: `
+import codelingo/ast/go
+
+go.file(depth = any):
+  go.basic_lit:
+    value as v
+    regex(/^a,b$/, v)
+    value != "x"
+`[1:],
+		}, {
+			// Decorators sit directly above the fact they decorate.
+			src: `
+import codelingo/ast/go
+
+go.file(depth = any):
+      @review comment
+
+  go.func_decl:
+    name == "x"
+`[1:],
+			expected: `
+import codelingo/ast/go
+
+go.file(depth = any):
+  @review comment
+  go.func_decl:
+    name == "x"
+`[1:],
+		}, {
+			// Imports are grouped, sorted and deduplicated.
+			src: `
+import codelingo/ast/php
+import (
+  codelingo/ast/go
+  codelingo/ast/php
+)
+go.file:
+  php.file
+`[1:],
+			expected: `
+import (
+  codelingo/ast/go
+  codelingo/ast/php
+)
+
+go.file:
+  php.file
+`[1:],
+		}, {
+			// Comments are preserved and blank lines are collapsed.
+			src: `
+# Find main.
+import codelingo/ast/go
+
+
+
+go.file(depth = any):   # the file
+    # the function
+    go.func_decl:
+        name == "a#b"
+`[1:],
+			expected: `
+# Find main.
+import codelingo/ast/go
+
+go.file(depth = any): # the file
+  # the function
+  go.func_decl:
+    name == "a#b"
+`[1:],
+		},
+	}
+
+	for _, t := range cases {
+		formatted, err := clql.Format(t.src)
+		c.Assert(err, jc.ErrorIsNil)
+		c.Assert(formatted, gc.Equals, t.expected)
+
+		// Formatting is idempotent.
+		again, err := clql.Format(formatted)
+		c.Assert(err, jc.ErrorIsNil)
+		c.Assert(again, gc.Equals, formatted)
+	}
+}
+
+func (s *clqlSuite) TestFormatErrors(c *gc.C) {
+	cases := []struct {
+		src, err string
+	}{
+		{"go.file(depth = any:\n", "line 1: unbalanced parentheses"},
+		{"go.file:\n  name == \"x\n", "line 2: unterminated string literal"},
+		{"go.file:\n  @review comment\n", "line 2: decorator @review must be followed by a fact"},
+		{"import (\n  codelingo/ast/go\n", "line 1: import group is missing a closing parenthesis"},
+	}
+
+	for _, t := range cases {
+		_, err := clql.Format(t.src)
+		c.Assert(err, gc.ErrorMatches, t.err)
+	}
+}
+
+func (s *clqlSuite) TestFormatFile(c *gc.C) {
+	src := `
+tenets:
+  # A comment outside of the query.
+  - name: find-main
+    actions:
+      codelingo/review:
+        comment: Found main.
+    query: |
+      import codelingo/ast/go
+
+      go.func_decl(depth=any):
+          @review comment
+          go.ident:
+              name=="main"
+
+  - name: empty
+    query: |
+`[1:]
+
+	expected := `
+tenets:
+  # A comment outside of the query.
+  - name: find-main
+    actions:
+      codelingo/review:
+        comment: Found main.
+    query: |
+      import codelingo/ast/go
+
+      go.func_decl(depth = any):
+        @review comment
+        go.ident:
+          name == "main"
+
+  - name: empty
+    query: |
+`[1:]
+
+	formatted, err := clql.FormatFile([]byte(src))
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(string(formatted), gc.Equals, expected)
+
+	_, err = clql.FormatFile([]byte("tenets:\n  - query: |\n      go.file(\n"))
+	c.Assert(err, gc.ErrorMatches, "line 3: unbalanced parentheses")
+}
@@ -0,0 +1,61 @@
+package clql
+
+import (
+	"path/filepath"
+	"strings"
+
+	rpc "github.com/codelingo/rpc/service"
+	"github.com/juju/errors"
+)
+
+// lexiconsByExt maps file extensions to the lexicon used to query them. Files
+// with other extensions use a lexicon named after their extension.
+var lexiconsByExt = map[string]string{
+	".go":   "go",
+	".php":  "php",
+	".java": "java",
+	".cs":   "csharp",
+	".c":    "cpp",
+	".h":    "cpp",
+	".cc":   "cpp",
+	".cpp":  "cpp",
+	".cxx":  "cpp",
+	".hpp":  "cpp",
+	".py":   "python",
+	".js":   "javascript",
+	".jsx":  "javascript",
+	".ts":   "typescript",
+	".tsx":  "typescript",
+}
+
+// LexiconForFile returns the name of the lexicon used to query the given
+// file. The override is used if it is set.
+func LexiconForFile(file, override string) (string, error) {
+	if override != "" {
+		return override, nil
+	}
+
+	ext := strings.ToLower(filepath.Ext(file))
+	if lexicon, ok := lexiconsByExt[ext]; ok {
+		return lexicon, nil
+	}
+	if len(ext) > 1 {
+		return ext[1:], nil
+	}
+	return "", errors.Errorf("cannot detect the language of %q. Please set it with --lang", filepath.Base(file))
+}
+
+// PropertyValue returns the value held by a generated fact's property.
+func PropertyValue(property *rpc.GenProperty) (interface{}, error) {
+	switch val := property.Value.(type) {
+	case *rpc.GenProperty_Int:
+		return val.Int, nil
+	case *rpc.GenProperty_Float:
+		return val.Float, nil
+	case *rpc.GenProperty_Bool:
+		return val.Bool, nil
+	case *rpc.GenProperty_String_:
+		return val.String_, nil
+	}
+	return nil, errors.Errorf("unknown property type %T", property.Value)
+}
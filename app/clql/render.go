@@ -0,0 +1,124 @@
+package clql
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+)
+
+// Fact is a node of a fact tree, such as one generated from a selection of
+// source code.
+type Fact struct {
+	Name       string
+	Properties map[string]interface{}
+	Children   []*Fact
+}
+
+// Render returns a query which matches the given fact trees. The root facts
+// match at any depth and the innermost fact is decorated with
+// "@review comment".
+func Render(lexicon string, facts []*Fact) string {
+	innermost := innermostFact(facts)
+
+	var buf strings.Builder
+	fmt.Fprintf(&buf, "import codelingo/ast/%s\n\n", lexicon)
+	for _, fact := range facts {
+		renderFact(&buf, lexicon, fact, innermost, 0)
+	}
+	return buf.String()
+}
+
+func renderFact(buf *strings.Builder, lexicon string, fact, decorated *Fact, level int) {
+	if fact == decorated {
+		buf.WriteString(indent(level) + "@review comment\n")
+	}
+
+	name := fact.Name
+	if !strings.Contains(name, ".") {
+		name = lexicon + "." + name
+	}
+	buf.WriteString(indent(level) + name)
+	if level == 0 {
+		buf.WriteString("(depth = any)")
+	}
+	if len(fact.Properties) > 0 || len(fact.Children) > 0 {
+		buf.WriteString(":")
+	}
+	buf.WriteString("\n")
+
+	var props []string
+	for prop := range fact.Properties {
+		props = append(props, prop)
+	}
+	sort.Strings(props)
+	for _, prop := range props {
+		fmt.Fprintf(buf, "%s%s == %s\n", indent(level+1), prop, renderValue(fact.Properties[prop]))
+	}
+
+	for _, child := range fact.Children {
+		renderFact(buf, lexicon, child, decorated, level+1)
+	}
+}
+
+func renderValue(val interface{}) string {
+	switch v := val.(type) {
+	case string:
+		return strconv.Quote(v)
+	case float32:
+		return strconv.FormatFloat(float64(v), 'g', -1, 32)
+	case float64:
+		return strconv.FormatFloat(v, 'g', -1, 64)
+	}
+	return fmt.Sprint(val)
+}
+
+// innermostFact returns the first of the deepest facts in the given trees.
+func innermostFact(facts []*Fact) *Fact {
+	var deepest *Fact
+	max := -1
+	var walk func(f *Fact, depth int)
+	walk = func(f *Fact, depth int) {
+		if depth > max {
+			deepest, max = f, depth
+		}
+		for _, child := range f.Children {
+			walk(child, depth+1)
+		}
+	}
+	for _, f := range facts {
+		walk(f, 0)
+	}
+	return deepest
+}
+
+// RenderTenet wraps the given query in a Tenet which uses the
+// codelingo/review Action. The result is an item of the "tenets" list,
+// ready to be appended to a codelingo.yaml file.
+func RenderTenet(name, comment, query string) string {
+	var buf strings.Builder
+	fmt.Fprintf(&buf, "  - name: %s\n", yamlScalar(name))
+	buf.WriteString("    actions:\n")
+	buf.WriteString("      codelingo/review:\n")
+	fmt.Fprintf(&buf, "        comment: %s\n", yamlScalar(comment))
+	buf.WriteString("    query: |\n")
+	for _, l := range strings.Split(strings.TrimSuffix(query, "\n"), "\n") {
+		if l == "" {
+			buf.WriteString("\n")
+			continue
+		}
+		buf.WriteString("      " + l + "\n")
+	}
+	return buf.String()
+}
+
+// yamlScalar returns s as a single line YAML scalar, quoting it if needed.
+func yamlScalar(s string) string {
+	out, err := yaml.Marshal(s)
+	if err != nil || strings.Contains(strings.TrimSpace(string(out)), "\n") {
+		return strconv.Quote(s)
+	}
+	return strings.TrimSpace(string(out))
+}
@@ -0,0 +1,88 @@
+package clql_test
+
+import (
+	"github.com/codelingo/lingo/app/clql"
+	jc "github.com/juju/testing/checkers"
+	gc "gopkg.in/check.v1"
+)
+
+func (s *clqlSuite) TestRender(c *gc.C) {
+	facts := []*clql.Fact{{
+		Name: "file",
+		Children: []*clql.Fact{{
+			Name: "go.func_decl",
+			Children: []*clql.Fact{{
+				Name: "ident",
+				Properties: map[string]interface{}{
+					"name":     "main",
+					"exported": false,
+					"start":    int64(7),
+				},
+			}},
+		}},
+	}}
+
+	expected := `
+import codelingo/ast/go
+
+go.file(depth = any):
+  go.func_decl:
+    @review comment
+    go.ident:
+      exported == false
+      name == "main"
+      start == 7
+`[1:]
+
+	query := clql.Render("go", facts)
+	c.Assert(query, gc.Equals, expected)
+
+	// The rendered query is already formatted.
+	formatted, err := clql.Format(query)
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(formatted, gc.Equals, query)
+}
+
+func (s *clqlSuite) TestRenderTenet(c *gc.C) {
+	query := clql.Render("go", []*clql.Fact{{Name: "ident"}})
+	tenet := clql.RenderTenet("find-idents", "Found: an ident.", query)
+
+	c.Assert(tenet, gc.Equals, `
+  - name: find-idents
+    actions:
+      codelingo/review:
+        comment: 'Found: an ident.'
+    query: |
+      import codelingo/ast/go
+
+      @review comment
+      go.ident(depth = any)
+`[1:])
+
+	// The snippet is a valid item of a codelingo.yaml file.
+	formatted, err := clql.FormatFile([]byte("tenets:\n" + tenet))
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(string(formatted), gc.Equals, "tenets:\n"+tenet)
+}
+
+func (s *clqlSuite) TestLexiconForFile(c *gc.C) {
+	cases := []struct {
+		file, override, expected, err string
+	}{
+		{file: "main.go", expected: "go"},
+		{file: "Program.cs", expected: "csharp"},
+		{file: "script.rb", expected: "rb"},
+		{file: "Makefile", err: `cannot detect the language of "Makefile". Please set it with --lang`},
+		{file: "Makefile", override: "make", expected: "make"},
+	}
+
+	for _, t := range cases {
+		lexicon, err := clql.LexiconForFile(t.file, t.override)
+		if t.err != "" {
+			c.Assert(err, gc.ErrorMatches, t.err)
+			continue
+		}
+		c.Assert(err, jc.ErrorIsNil)
+		c.Assert(lexicon, gc.Equals, t.expected)
+	}
+}
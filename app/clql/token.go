@@ -0,0 +1,159 @@
+package clql
+
+import (
+	"strings"
+
+	"github.com/juju/errors"
+)
+
+type tokenKind int
+
+const (
+	wordToken tokenKind = iota
+	literalToken
+	punctToken
+	operatorToken
+)
+
+type token struct {
+	kind        tokenKind
+	text        string
+	spaceBefore bool
+}
+
+// scanLine splits a single trimmed line of CLQL into tokens and a trailing
+// comment, if any. String and regex literals are kept verbatim.
+func scanLine(src string) ([]*token, string, error) {
+	var toks []*token
+	space := false
+	depth := 0
+
+	for i := 0; i < len(src); {
+		c := src[i]
+		switch {
+		case c == ' ' || c == '\t':
+			space = true
+			i++
+			continue
+		case c == '#':
+			if depth != 0 {
+				return nil, "", errors.New("unbalanced parentheses")
+			}
+			return toks, strings.TrimSpace(src[i:]), nil
+		case c == '"' || c == '\'' || c == '`' || (c == '/' && regexAllowed(toks)):
+			end, err := literalEnd(src, i)
+			if err != nil {
+				return nil, "", errors.Trace(err)
+			}
+			toks = append(toks, &token{kind: literalToken, text: src[i:end], spaceBefore: space})
+			i = end
+		case c == '(' || c == ')' || c == ',' || c == ':':
+			switch c {
+			case '(':
+				depth++
+			case ')':
+				depth--
+				if depth < 0 {
+					return nil, "", errors.New("unbalanced parentheses")
+				}
+			}
+			toks = append(toks, &token{kind: punctToken, text: string(c), spaceBefore: space})
+			i++
+		case isOperatorStart(src, i):
+			n := 1
+			if i+1 < len(src) && src[i+1] == '=' {
+				n = 2
+			}
+			toks = append(toks, &token{kind: operatorToken, text: src[i : i+n], spaceBefore: space})
+			i += n
+		default:
+			start := i
+			for i < len(src) && !isWordEnd(src, i) {
+				i++
+			}
+			toks = append(toks, &token{kind: wordToken, text: src[start:i], spaceBefore: space})
+		}
+		space = false
+	}
+
+	if depth != 0 {
+		return nil, "", errors.New("unbalanced parentheses")
+	}
+	return toks, "", nil
+}
+
+// regexAllowed reports whether a '/' following toks starts a regex literal
+// rather than being part of a word, such as an import path.
+func regexAllowed(toks []*token) bool {
+	if len(toks) == 0 {
+		return true
+	}
+	last := toks[len(toks)-1]
+	return last.kind == operatorToken || (last.kind == punctToken && last.text != ")")
+}
+
+// literalEnd returns the index just past the literal starting at src[start].
+func literalEnd(src string, start int) (int, error) {
+	quote := src[start]
+	for i := start + 1; i < len(src); i++ {
+		switch src[i] {
+		case '\\':
+			i++
+		case quote:
+			return i + 1, nil
+		}
+	}
+	if quote == '/' {
+		return 0, errors.New("unterminated regex literal")
+	}
+	return 0, errors.New("unterminated string literal")
+}
+
+func isOperatorStart(src string, i int) bool {
+	switch src[i] {
+	case '=', '<', '>':
+		return true
+	case '!':
+		return i+1 < len(src) && src[i+1] == '='
+	}
+	return false
+}
+
+func isWordEnd(src string, i int) bool {
+	switch src[i] {
+	case ' ', '\t', '#', '"', '\'', '`', '(', ')', ',', ':':
+		return true
+	}
+	return isOperatorStart(src, i)
+}
+
+// renderTokens joins toks using canonical spacing: operators are surrounded
+// by single spaces, commas are followed by a single space, and there is no
+// space inside parentheses, before a call's opening parenthesis or before a
+// colon.
+func renderTokens(toks []*token) string {
+	var buf strings.Builder
+	for i, t := range toks {
+		if i > 0 && spaceBetween(toks[i-1], t) {
+			buf.WriteByte(' ')
+		}
+		buf.WriteString(t.text)
+	}
+	return buf.String()
+}
+
+func spaceBetween(prev, t *token) bool {
+	switch {
+	case t.kind == operatorToken || prev.kind == operatorToken:
+		return true
+	case t.text == "," || t.text == ")" || t.text == ":":
+		return false
+	case prev.text == "(":
+		return false
+	case prev.text == ",":
+		return true
+	case t.text == "(":
+		return prev.kind != wordToken && prev.text != ")"
+	}
+	return t.spaceBefore
+}
@@ -0,0 +1,132 @@
+package clql
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/juju/errors"
+	"gopkg.in/yaml.v2"
+)
+
+// queryKeyRegexp matches the key of a literal block scalar holding a query,
+// e.g. "    query: |" or "  - query: |-".
+var queryKeyRegexp = regexp.MustCompile(`^(\s*)(-\s+)?query:\s*\|([-+]?)([1-9]?)([-+]?)\s*(#.*)?$`)
+
+// QueryBlock is a CLQL query embedded in a codelingo.yaml file.
+type QueryBlock struct {
+	// KeyLine is the 0-based line of the "query: |" key.
+	KeyLine int
+	// Start and End are the 0-based lines of the query content. End is
+	// exclusive and trailing blank lines are not included.
+	Start, End int
+	// Indent is the number of spaces prefixing each line of the query.
+	Indent int
+	// Src is the query with its indentation removed.
+	Src string
+}
+
+// QueryBlocks returns the literal "query" blocks in the given codelingo.yaml
+// source.
+func QueryBlocks(src []byte) []*QueryBlock {
+	lines := strings.Split(string(src), "\n")
+
+	var blocks []*QueryBlock
+	for i := 0; i < len(lines); i++ {
+		m := queryKeyRegexp.FindStringSubmatch(lines[i])
+		if m == nil {
+			continue
+		}
+		keyIndent := len(m[1]) + len(m[2])
+
+		block := &QueryBlock{KeyLine: i, Start: i + 1, End: i + 1}
+		if m[4] != "" {
+			n, _ := strconv.Atoi(m[4])
+			block.Indent = keyIndent + n
+		}
+
+		j := i + 1
+		for ; j < len(lines); j++ {
+			l := lines[j]
+			if strings.TrimSpace(l) == "" {
+				continue
+			}
+			ind := len(l) - len(strings.TrimLeft(l, " "))
+			if ind <= keyIndent {
+				break
+			}
+			if block.Indent == 0 {
+				block.Indent = ind
+			}
+			block.End = j + 1
+		}
+
+		var content []string
+		for _, l := range lines[block.Start:block.End] {
+			if len(l) >= block.Indent {
+				l = l[block.Indent:]
+			} else {
+				l = strings.TrimLeft(l, " ")
+			}
+			content = append(content, l)
+		}
+		block.Src = strings.Join(content, "\n")
+		if len(content) > 0 {
+			block.Src += "\n"
+		}
+		blocks = append(blocks, block)
+		i = block.End - 1
+	}
+	return blocks
+}
+
+// FileError is returned when a query in a codelingo.yaml file cannot be
+// formatted. Line is 1-based and relative to the start of the file.
+type FileError struct {
+	Line int
+	Msg  string
+}
+
+func (e *FileError) Error() string {
+	return "line " + strconv.Itoa(e.Line) + ": " + e.Msg
+}
+
+// FormatFile returns the given codelingo.yaml source with every query block
+// formatted. Everything outside of the query blocks, including comments, is
+// left untouched.
+func FormatFile(src []byte) ([]byte, error) {
+	var check interface{}
+	if err := yaml.Unmarshal(src, &check); err != nil {
+		return nil, errors.Trace(err)
+	}
+
+	lines := strings.Split(string(src), "\n")
+	var out []string
+	last := 0
+	for _, block := range QueryBlocks(src) {
+		formatted, err := Format(block.Src)
+		if err != nil {
+			if sErr, ok := errors.Cause(err).(*SyntaxError); ok {
+				return nil, &FileError{Line: block.Start + sErr.Line, Msg: sErr.Msg}
+			}
+			return nil, errors.Trace(err)
+		}
+
+		out = append(out, lines[last:block.Start]...)
+		last = block.End
+		if formatted == "" {
+			continue
+		}
+		prefix := strings.Repeat(" ", block.Indent)
+		for _, l := range strings.Split(strings.TrimSuffix(formatted, "\n"), "\n") {
+			if l == "" {
+				out = append(out, "")
+				continue
+			}
+			out = append(out, prefix+l)
+		}
+	}
+	out = append(out, lines[last:]...)
+
+	return []byte(strings.Join(out, "\n")), nil
+}
@@ -0,0 +1,90 @@
+package commands
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"github.com/codelingo/lingo/app/action"
+	"github.com/codelingo/lingo/app/util"
+	"github.com/urfave/cli"
+
+	"github.com/juju/errors"
+)
+
+func init() {
+	register(&cli.Command{
+		Name:  "action",
+		Usage: "Write new Actions",
+		Subcommands: []cli.Command{
+			{
+				Name:      "new",
+				Usage:     "Create a Go module implementing a new Action",
+				ArgsUsage: "owner/name",
+				Description: `The module reads the context lingo runs Actions with and writes back
+   results, as described in doc/ACTIONS.md. Its Makefile installs the Action
+   from the module's directory with "make install", and builds releases laid
+   out as a registry with "make release".`,
+				Action: newActionAction,
+				Flags: []cli.Flag{
+					cli.StringFlag{
+						Name:  "dir",
+						Usage: "Directory to create the Action in. Defaults to ./<name>",
+					},
+					cli.StringFlag{
+						Name:  "module",
+						Usage: "Go module path of the Action. Defaults to github.com/<owner>/<name>",
+					},
+					cli.StringFlag{
+						Name:  "version",
+						Value: "0.1.0",
+						Usage: "Initial version of the Action",
+					},
+				},
+			},
+		},
+	}, false, false)
+}
+
+func newActionAction(ctx *cli.Context) {
+	if err := newAction(ctx); err != nil {
+		util.FatalOSErr(err)
+		return
+	}
+}
+
+func newAction(c *cli.Context) error {
+	if c.NArg() != 1 {
+		return errors.New("Failed to create Action - give its name as owner/name.")
+	}
+	owner, name, version, err := action.ParseRef(c.Args().First())
+	if err != nil {
+		return errors.Trace(err)
+	}
+	if owner == "" || version != "" {
+		return errors.Errorf("Failed to create Action - %q is not of the form owner/name.", c.Args().First())
+	}
+
+	scaffold := &action.Scaffold{
+		Owner:   owner,
+		Name:    name,
+		Version: c.String("version"),
+		Module:  c.String("module"),
+	}
+	if scaffold.Module == "" {
+		scaffold.Module = fmt.Sprintf("github.com/%s/%s", owner, name)
+	}
+	dir := c.String("dir")
+	if dir == "" {
+		dir = name
+	}
+
+	files, err := scaffold.Write(dir)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	for _, file := range files {
+		fmt.Println("  created", filepath.Join(dir, file))
+	}
+	fmt.Printf("Success! Created Action %s/%s in %s. Run `make install` there to install it, then `lingo run %s/%s`.\n", owner, name, dir, owner, name)
+	return nil
+}
@@ -0,0 +1,169 @@
+package commands
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+
+	"github.com/codelingo/lingo/app/commands/verify"
+	"github.com/codelingo/lingo/app/discovery"
+	"github.com/codelingo/lingo/app/dotlingo"
+	"github.com/codelingo/lingo/app/util"
+	"github.com/codelingo/lingo/vcs"
+	"github.com/juju/errors"
+	"github.com/urfave/cli"
+)
+
+func init() {
+	fileFlag := cli.StringFlag{
+		Name:  util.LingoFile.String(),
+		Usage: "The codelingo.yaml file to edit. Defaults to the nearest one in the current directory or its parents",
+	}
+	register(&cli.Command{
+		Name:      "add",
+		Usage:     "Import a published bundle of Tenets, or a single Tenet, into the project",
+		ArgsUsage: "<owner>/<bundle>[/<tenet>]",
+		Description: `Checks that the bundle or Tenet is in the discovery index, then adds an
+   import of it to the tenets of the nearest codelingo.yaml file, leaving the
+   rest of the file as it is.`,
+		Action: addImportAction,
+		Flags: []cli.Flag{
+			fileFlag,
+			cli.BoolFlag{
+				Name:  util.RefreshFlg.String(),
+				Usage: "Read the discovery index again instead of using the cached copy",
+			},
+		},
+	}, false, false, verify.HomeRq, verify.ConfigRq)
+	register(&cli.Command{
+		Name:      "remove",
+		Usage:     "Remove the import of a bundle or Tenet from the project",
+		ArgsUsage: "<owner>/<bundle>[/<tenet>]",
+		Action:    removeImportAction,
+		Flags:     []cli.Flag{fileFlag},
+	}, false, false)
+}
+
+func addImportAction(ctx *cli.Context) {
+	if err := addImport(ctx); err != nil {
+		util.FatalOSErr(err)
+		return
+	}
+}
+
+func addImport(ctx *cli.Context) error {
+	if ctx.NArg() != 1 {
+		return errors.New("Failed to add import - give the bundle or Tenet to import as owner/bundle[/tenet].")
+	}
+	ref := ctx.Args().First()
+
+	file, src, err := importingDotlingo(ctx)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	imports, err := dotlingo.Imports(src)
+	if err != nil {
+		return errors.Annotatef(err, "failed to read %s", file)
+	}
+	switch imp := dotlingo.Overlapping(imports, ref); {
+	case imp == ref:
+		return errors.Errorf("%s already imports %s.", file, ref)
+	case imp != "":
+		return errors.Errorf("%s already imports %s. Remove it first with `lingo remove %s`.", file, imp, imp)
+	}
+
+	index, err := discoverySource(ctx.Bool(util.RefreshFlg.Long))
+	if err != nil {
+		return errors.Trace(err)
+	}
+	item, err := discovery.LookupImport(index, ref)
+	if errors.IsNotFound(err) {
+		return errors.Errorf("%s is not in the discovery index. Find bundles and Tenets with `lingo search`.", ref)
+	}
+	if err != nil {
+		return errors.Trace(err)
+	}
+
+	out, err := dotlingo.AddImport(src, ref)
+	if err != nil {
+		return errors.Annotatef(err, "failed to edit %s", file)
+	}
+	if err := writeDotlingo(file, out); err != nil {
+		return errors.Trace(err)
+	}
+	fmt.Printf("Imported %s %s in %s.\n", item.Kind, ref, file)
+	return nil
+}
+
+func removeImportAction(ctx *cli.Context) {
+	if err := removeImport(ctx); err != nil {
+		util.FatalOSErr(err)
+		return
+	}
+}
+
+func removeImport(ctx *cli.Context) error {
+	if ctx.NArg() != 1 {
+		return errors.New("Failed to remove import - give the bundle or Tenet to remove as owner/bundle[/tenet].")
+	}
+	ref := ctx.Args().First()
+
+	file, src, err := importingDotlingo(ctx)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	out, err := dotlingo.RemoveImport(src, ref)
+	if err != nil {
+		return errors.Annotatef(err, "failed to edit %s", file)
+	}
+	if err := writeDotlingo(file, out); err != nil {
+		return errors.Trace(err)
+	}
+	fmt.Printf("Removed the import of %s from %s.\n", ref, file)
+	return nil
+}
+
+// importingDotlingo returns the path and contents of the codelingo.yaml
+// file to edit the imports of.
+func importingDotlingo(ctx *cli.Context) (string, []byte, error) {
+	file := ctx.String(util.LingoFile.Long)
+	if file == "" {
+		file = ctx.GlobalString(util.TenetCfgFlg.Long)
+	}
+	if file == "" {
+		var err error
+		if file, err = nearestDotlingo(); err != nil {
+			return "", nil, errors.Trace(err)
+		}
+	}
+	src, err := ioutil.ReadFile(file)
+	return file, src, errors.Trace(err)
+}
+
+// nearestDotlingo returns the codelingo.yaml file in the current directory
+// or the closest of its parents, looking no further than the repository's
+// root.
+func nearestDotlingo() (string, error) {
+	cwd, err := os.Getwd()
+	if err != nil {
+		return "", errors.Trace(err)
+	}
+	root := ""
+	if _, repo, err := vcs.New(); err == nil {
+		root, _ = repoRoot(repo, cwd)
+	}
+	path, err := dotlingo.Nearest(cwd, root)
+	if errors.IsNotFound(err) {
+		return "", errors.New("no codelingo.yaml file found. Create one with `lingo init`.")
+	}
+	return path, errors.Trace(err)
+}
+
+// writeDotlingo replaces a codelingo.yaml file, keeping its permissions.
+func writeDotlingo(file string, data []byte) error {
+	info, err := os.Stat(file)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	return errors.Trace(ioutil.WriteFile(file, data, info.Mode()))
+}
@@ -1,10 +1,7 @@
 package commands
 
 import (
-	"fmt"
-	"io/ioutil"
-	"net/http"
-
+	"github.com/codelingo/lingo/app/discovery"
 	"github.com/codelingo/lingo/app/util"
 	"github.com/juju/errors"
 	"github.com/urfave/cli"
@@ -15,7 +12,7 @@ func init() {
 		Name:   "bots",
 		Usage:  "List Bots",
 		Action: listBotsAction,
-		Flags: []cli.Flag{
+		Flags: append([]cli.Flag{
 			cli.BoolFlag{
 				Name:  util.InstalledFlg.String(),
 				Usage: "List Bots used in current project",
@@ -28,7 +25,7 @@ func init() {
 				Name:  util.NameFlg.String(),
 				Usage: "Describe the named Bot",
 			},
-		},
+		}, discoveryFlags()...),
 	}, false, true)
 }
 
@@ -41,36 +38,9 @@ func listBotsAction(ctx *cli.Context) {
 }
 
 func listBots(ctx *cli.Context) error {
-	owner := ctx.String("owner")
-	name := ctx.String("name")
-
-	baseBotURL := baseDiscoveryURL + "bots"
-	url := baseBotURL + "/lingo_bots.yaml"
-	switch {
-	case name != "":
-
-		if owner == "" {
-			return errors.New("owner flag must be set")
-		}
-
-		url = fmt.Sprintf("%s/%s/%s/lingo_bot.yaml",
-			baseBotURL, owner, name)
-
-	case owner != "":
-		url = fmt.Sprintf("%s/%s/lingo_owner.yaml",
-			baseBotURL, owner)
-	}
-	resp, err := http.Get(url)
-	if err != nil {
-		return errors.Trace(err)
-	}
-
-	defer resp.Body.Close()
-	data, err := ioutil.ReadAll(resp.Body)
-	if err != nil {
-		return errors.Trace(err)
-	}
-
-	fmt.Println(string(data))
-	return nil
+	return errors.Trace(listDiscovered(ctx, &discovery.Query{
+		Kinds: []discovery.Kind{discovery.KindBot},
+		Owner: ctx.String(util.OwnerFlg.Long),
+		Name:  ctx.String(util.NameFlg.Long),
+	}))
 }
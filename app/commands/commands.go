@@ -5,6 +5,7 @@ import (
 
 	"github.com/codelingo/lingo/app/commands/verify"
 	"github.com/codelingo/lingo/app/util"
+	"github.com/codelingo/lingo/app/util/common/config"
 	"github.com/urfave/cli"
 
 	"os"
@@ -60,6 +61,9 @@ func All() []cli.Command {
 }
 
 func Before(c *cli.Context) error {
+	if err := applyGlobalOptions(c); err != nil {
+		return errors.Trace(err)
+	}
 	cmdReq := cmdRequirements(cmds)
 
 	var currentCMDName string
@@ -92,8 +96,8 @@ func Before(c *cli.Context) error {
 			}
 		}
 	}
-	// No Requirements should be needed to show help
-	if currentCMDName == "help" || isHelpAlias(flags) {
+	// No Requirements should be needed to show help or complete arguments
+	if currentCMDName == "help" || isHelpAlias(flags) || isShellComplete() {
 		return nil
 	}
 
@@ -108,6 +112,35 @@ func Before(c *cli.Context) error {
 	return nil
 }
 
+// applyGlobalOptions applies the flags given before the command, which
+// commands read through the environment and config layers.
+func applyGlobalOptions(c *cli.Context) error {
+	if c.IsSet(util.LingoHomeFlg.Long) {
+		if err := os.Setenv("LINGO_HOME", c.String(util.LingoHomeFlg.Long)); err != nil {
+			return errors.Trace(err)
+		}
+	}
+	// Set the profile in the environment, so that git credential helpers
+	// run by this command use it too.
+	if c.IsSet(util.ProfileFlg.Long) {
+		if err := os.Setenv(util.ProfileEnvVar, c.String(util.ProfileFlg.Long)); err != nil {
+			return errors.Trace(err)
+		}
+	}
+	if dir := c.String(util.RepoPathFlg.Long); dir != "" && dir != "." {
+		if err := os.Chdir(dir); err != nil {
+			return errors.Annotatef(err, "failed to start in %s", dir)
+		}
+	}
+	return errors.Trace(config.SetFlagValues(c.StringSlice(util.ConfigFlg.Long)))
+}
+
+// isShellComplete returns true when lingo was invoked to complete a command
+// line, rather than to run it.
+func isShellComplete() bool {
+	return len(os.Args) > 1 && os.Args[len(os.Args)-1] == "--"+cli.BashCompletionFlag.GetName()
+}
+
 // isHelpAlias returns true when a command's arguments are equivalent to the
 // help command. For example, `lingo review --help` == `lingo help review`.
 func isHelpAlias(flags []string) bool {
@@ -1,36 +1,38 @@
 package commands
 
 import (
-	"crypto/md5"
-	"encoding/hex"
+	"bufio"
+	"context"
 	"fmt"
-	"io/ioutil"
+	"io"
+	"net"
 	"net/url"
-	"os/exec"
+	"os"
 	"path/filepath"
-	"runtime"
 	"strings"
-	"syscall"
+	"time"
 
 	"github.com/codelingo/lingo/app/commands/verify"
+	"github.com/codelingo/lingo/app/credentials"
+	"github.com/codelingo/lingo/app/login"
 	"github.com/codelingo/lingo/app/util"
 	commonConfig "github.com/codelingo/lingo/app/util/common/config"
 	serviceConfig "github.com/codelingo/lingo/service/config"
-	"github.com/howeyc/gopass"
 	"github.com/urfave/cli"
 
 	"github.com/juju/errors"
 	"golang.org/x/crypto/ssh/terminal"
 )
 
-const (
-	websiteAddr      = "website.addr"
-	platformAddr     = "platform.addr"
-	flowAddr         = "flow.address"
-	gitServerAddr    = "gitserver.remote.host"
-	p4ServerAddr     = "p4server.remote.host"
-	messagequeueAddr = "messagequeue.address.host"
-	baseDiscoveryURL = "https://raw.githubusercontent.com/codelingo/codelingo/master/"
+var (
+	showOriginFlag = cli.BoolFlag{
+		Name:  "show-origin",
+		Usage: "Show the layer, and the file, variable or flag, each value was set by.",
+	}
+	projectFlag = cli.BoolFlag{
+		Name:  "project",
+		Usage: "Write to the project's .codelingo/config.yaml instead of the user's config.",
+	}
 )
 
 func init() {
@@ -51,6 +53,48 @@ func init() {
 					},
 				},
 			},
+			{
+				Name:      "get",
+				Usage:     "Show the value of a config key.",
+				ArgsUsage: "<key>",
+				Action:    getConfigAction,
+				Flags:     []cli.Flag{showOriginFlag},
+			},
+			{
+				Name:      "set",
+				Usage:     "Set a config key for the current environment.",
+				ArgsUsage: "<key> <value>",
+				Action:    setConfigAction,
+				Flags:     []cli.Flag{projectFlag},
+			},
+			{
+				Name:      "unset",
+				Usage:     "Remove a config key set with `lingo config set`.",
+				ArgsUsage: "<key>",
+				Action:    unsetConfigAction,
+				Flags:     []cli.Flag{projectFlag},
+			},
+			{
+				Name:   "list",
+				Usage:  "List the config keys that are set, and their values.",
+				Action: listConfigAction,
+				Flags:  []cli.Flag{showOriginFlag},
+				Description: `Values are read from, in increasing order of precedence:
+
+     default  the built in defaults
+     user     platform.yaml in the lingo config directory, for the current environment
+     project  .codelingo/config.yaml at the root of the repository
+     env      LINGO_<KEY> environment variables, e.g. LINGO_DISCOVERY_ROOT for discovery.root
+     flag     lingo --config <key>=<value>`,
+			},
+			profileCommand,
+			onpremCommand,
+			{
+				Name:   "git-credential",
+				Usage:  "Give git the CodeLingo token from the credentials store.",
+				Hidden: true,
+				Action: gitCredentialAction,
+			},
 			{
 				Name:   "setup",
 				Usage:  "Configure the lingo tool for the current environment on this machine.",
@@ -68,13 +112,36 @@ func init() {
 						Name:  "keep-creds",
 						Usage: "Preserves existing credentials (if present).",
 					},
+					cli.BoolFlag{
+						Name:  "manual",
+						Usage: "Enter a token from the website instead of signing in from the browser.",
+					},
+					cli.BoolFlag{
+						Name:  "no-browser",
+						Usage: "Print the address to sign in at instead of opening it in a browser.",
+					},
+				},
+			},
+			{
+				Name:   "logout",
+				Usage:  "Remove the credentials saved by `lingo config setup`, from every environment.",
+				Action: logoutAction,
+				Flags: []cli.Flag{
+					cli.StringFlag{
+						Name:  "env",
+						Usage: "Only log out of the given environment.",
+					},
+					cli.BoolFlag{
+						Name:  "revoke",
+						Usage: "Also revoke the token on the website, so it can no longer be used anywhere.",
+					},
 				},
 			},
 		},
 		Flags: []cli.Flag{
 			cli.StringFlag{
 				Name:  "ip",
-				Usage: "Set the IP of the OnPrem Platform.",
+				Usage: "Run every service of the OnPrem Platform on this host. Use lingo config onprem to set each.",
 			},
 		},
 	}, false, false, verify.HomeRq, verify.ConfigRq)
@@ -98,13 +165,274 @@ func showConfig(ctx *cli.Context) error {
 		return errors.Trace(err)
 	}
 
-	fmt.Printf(`Username: %s
+	authCfg, err := commonConfig.Auth()
+	if err != nil {
+		return errors.Trace(err)
+	}
+	store, err := authCfg.Store()
+	if err != nil {
+		return errors.Trace(err)
+	}
+
+	profile, err := util.Profile()
+	if err != nil {
+		return errors.Trace(err)
+	}
+
+	fmt.Printf(`Profile: %s
+Username: %s
 Environment: %s
-`, username, env)
+Credentials: %s
+`, profile, username, env, store.Backend())
+
+	return nil
+}
+
+func getConfigAction(ctx *cli.Context) {
+	if err := getConfig(ctx); err != nil {
+		util.FatalOSErr(err)
+		return
+	}
+}
+
+func getConfig(ctx *cli.Context) error {
+	if ctx.NArg() != 1 {
+		return errors.New("Error: A config key must be specified: `lingo config get <key>`")
+	}
+	key := ctx.Args().First()
+	if err := commonConfig.ValidKey(key); err != nil {
+		return errors.Trace(err)
+	}
+	settings, err := configSettings()
+	if err != nil {
+		return errors.Trace(err)
+	}
+	setting := settings.Get(key)
+	if setting == nil {
+		return errors.Errorf("%s is not set", key)
+	}
+	if ctx.Bool("show-origin") {
+		fmt.Printf("%s\t%s\n", setting, setting.Value)
+		return nil
+	}
+	fmt.Println(setting.Value)
+	return nil
+}
+
+func listConfigAction(ctx *cli.Context) {
+	if err := listConfig(ctx); err != nil {
+		util.FatalOSErr(err)
+		return
+	}
+}
+
+func listConfig(ctx *cli.Context) error {
+	if err := util.MaxArgs(ctx, 0); err != nil {
+		return errors.Trace(err)
+	}
+	settings, err := configSettings()
+	if err != nil {
+		return errors.Trace(err)
+	}
+	for _, setting := range settings.List() {
+		if ctx.Bool("show-origin") {
+			fmt.Printf("%s\t", setting)
+		}
+		fmt.Printf("%s=%s\n", setting.Key, setting.Value)
+	}
+	return nil
+}
+
+func setConfigAction(ctx *cli.Context) {
+	if err := setConfig(ctx); err != nil {
+		util.FatalOSErr(err)
+		return
+	}
+}
+
+func setConfig(ctx *cli.Context) error {
+	if ctx.NArg() != 2 {
+		return errors.New("Error: A config key and value must be specified: `lingo config set <key> <value>`")
+	}
+	key, value := ctx.Args()[0], ctx.Args()[1]
+	if err := commonConfig.ValidKey(key); err != nil {
+		return errors.Trace(err)
+	}
+
+	file, origin := "", commonConfig.OriginUser
+	if ctx.Bool("project") {
+		origin = commonConfig.OriginProject
+		var err error
+		if file, err = commonConfig.SetProject(key, value); err != nil {
+			return errors.Trace(err)
+		}
+	} else {
+		cfg, err := commonConfig.Platform()
+		if err != nil {
+			return errors.Trace(err)
+		}
+		if err := cfg.Set(key, value); err != nil {
+			return errors.Trace(err)
+		}
+		file = cfg.Path()
+	}
+	fmt.Printf("Set %s in %s.\n", key, file)
+	return warnOverridden(key, origin)
+}
+
+func unsetConfigAction(ctx *cli.Context) {
+	if err := unsetConfig(ctx); err != nil {
+		util.FatalOSErr(err)
+		return
+	}
+}
+
+func unsetConfig(ctx *cli.Context) error {
+	if ctx.NArg() != 1 {
+		return errors.New("Error: A config key must be specified: `lingo config unset <key>`")
+	}
+	key := ctx.Args().First()
+	if err := commonConfig.ValidKey(key); err != nil {
+		return errors.Trace(err)
+	}
+
+	if ctx.Bool("project") {
+		unset, err := commonConfig.UnsetProject(key)
+		if err != nil {
+			return errors.Trace(err)
+		}
+		if !unset {
+			return errors.Errorf("%s is not set in the project config", key)
+		}
+	} else {
+		cfg, err := commonConfig.Platform()
+		if err != nil {
+			return errors.Trace(err)
+		}
+		unset, err := cfg.Unset(key)
+		if err != nil {
+			return errors.Trace(err)
+		}
+		if !unset {
+			return errors.Errorf("%s is not set in %s for this environment", key, cfg.Path())
+		}
+	}
+	fmt.Printf("Unset %s.\n", key)
+	return nil
+}
+
+// warnOverridden warns when a value just written to the layer origin is
+// hidden by one of a layer with higher precedence.
+func warnOverridden(key, origin string) error {
+	settings, err := configSettings()
+	if err != nil {
+		return errors.Trace(err)
+	}
+	if setting := settings.Get(key); setting != nil && setting.Origin != origin {
+		util.UserFacingWarning(fmt.Sprintf("Warning: %s is overridden by %s.", key, setting))
+	}
+	return nil
+}
+
+func configSettings() (*commonConfig.Settings, error) {
+	cfg, err := commonConfig.Platform()
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	settings, err := cfg.Settings()
+	return settings, errors.Trace(err)
+}
+
+func gitCredentialAction(ctx *cli.Context) {
+	if err := gitCredential(ctx); err != nil {
+		util.FatalOSErr(err)
+		return
+	}
+}
+
+// gitCredential implements a git credential helper, see
+// https://git-scm.com/docs/gitcredentials. Only "get" is answered, and only
+// for the current environment's git server; git storing or erasing
+// credentials is left to lingo config setup.
+func gitCredential(ctx *cli.Context) error {
+	if ctx.Args().First() != "get" {
+		return nil
+	}
+	request, err := readCredentialRequest(os.Stdin)
+	if err != nil {
+		return errors.Trace(err)
+	}
+
+	platCfg, err := commonConfig.Platform()
+	if err != nil {
+		return errors.Trace(err)
+	}
+	addr, err := platCfg.GitServerAddr()
+	if err != nil {
+		return errors.Trace(err)
+	}
+	// The helper is set per git server, but the same server may be used by
+	// another environment or profile with other credentials.
+	if !isGitServer(addr, request) {
+		return nil
+	}
 
+	authCfg, err := commonConfig.Auth()
+	if err != nil {
+		return errors.Trace(err)
+	}
+	username, err := authCfg.GetGitUserName()
+	if err != nil {
+		return errors.Trace(err)
+	}
+	token, err := authCfg.GetGitUserPassword()
+	if err != nil {
+		return errors.Trace(err)
+	}
+	fmt.Printf("username=%s\npassword=%s\n", username, token)
 	return nil
 }
 
+// readCredentialRequest reads the attributes git describes the credentials
+// it wants with, one key=value pair per line.
+func readCredentialRequest(r io.Reader) (map[string]string, error) {
+	request := map[string]string{}
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		// A blank line ends the request.
+		if scanner.Text() == "" {
+			break
+		}
+		parts := strings.SplitN(scanner.Text(), "=", 2)
+		if len(parts) == 2 {
+			request[parts[0]] = parts[1]
+		}
+	}
+	return request, errors.Trace(scanner.Err())
+}
+
+// isGitServer returns whether a credential request is for the git server
+// at addr, comparing the protocol, host and port, if given.
+func isGitServer(addr string, request map[string]string) bool {
+	u, err := url.Parse(addr)
+	if err != nil || u.Host == "" {
+		return false
+	}
+	if request["protocol"] != u.Scheme {
+		return false
+	}
+	return hostPort(u.Scheme, request["host"]) == hostPort(u.Scheme, u.Host)
+}
+
+// hostPort returns host with the scheme's default port if it has none.
+func hostPort(scheme, host string) string {
+	if _, _, err := net.SplitHostPort(host); err == nil {
+		return strings.ToLower(host)
+	}
+	port := map[string]string{"http": "80", "https": "443"}[scheme]
+	return strings.ToLower(net.JoinHostPort(strings.Trim(host, "[]"), port))
+}
+
 func useEnvAction(ctx *cli.Context) {
 	err := useEnv(ctx)
 	if err != nil {
@@ -127,13 +455,13 @@ func useEnv(ctx *cli.Context) error {
 		return err
 	}
 
-	configsHome, err := util.ConfigHome()
+	profileHome, err := util.ProfileHome()
 	if err != nil {
 		return errors.Trace(err)
 	}
 
 	newEnv := ctx.Args()[0]
-	envFilepath := filepath.Join(configsHome, commonConfig.EnvCfgFile)
+	envFilepath := filepath.Join(profileHome, commonConfig.EnvCfgFile)
 
 	cfg := serviceConfig.New(envFilepath)
 	err = cfg.SetEnv(newEnv)
@@ -141,32 +469,17 @@ func useEnv(ctx *cli.Context) error {
 		return errors.Trace(err)
 	}
 	if newEnv == "onprem" {
-		ip := ctx.String("ip")
-		if ip == "" {
-			fmt.Print("Enter the Platform IP: ")
-			fmt.Scanln(&ip)
-		}
-		cfg, err := commonConfig.Platform()
-		if err != nil {
-			return errors.Trace(err)
-		}
-		if err := cfg.Set(websiteAddr, ip); err != nil {
-			return errors.Trace(err)
-		}
-		if err := cfg.Set(platformAddr, ip); err != nil {
-			return errors.Trace(err)
-		}
-		if err := cfg.Set(flowAddr, ip); err != nil {
-			return errors.Trace(err)
-		}
-		if err := cfg.Set(gitServerAddr, ip); err != nil {
-			return errors.Trace(err)
-		}
-		if err := cfg.Set(p4ServerAddr, ip); err != nil {
-			return errors.Trace(err)
-		}
-		if err := cfg.Set(messagequeueAddr, ip); err != nil {
-			return errors.Trace(err)
+		if ip := ctx.String("ip"); ip != "" {
+			// Run every service on the one host.
+			o := &commonConfig.OnPrem{Version: 1, Services: map[string]*commonConfig.OnPremService{}}
+			if err := setHost(o, ip); err != nil {
+				return errors.Trace(err)
+			}
+			if err := saveOnPrem(newEnv, o, true); err != nil {
+				return errors.Trace(err)
+			}
+		} else {
+			fmt.Println("Set the addresses of the on-prem services with `lingo config onprem`.")
 		}
 	}
 	fmt.Printf("Success! Environment set to '%v'.\n", newEnv)
@@ -262,11 +575,6 @@ func setupLingo(c *cli.Context) (string, error) {
 		return "", errors.Trace(err)
 	}
 
-	// recieve generated token from server
-	// open codelingo.io/authclient/<token>
-	// user verifies on website
-	// recieve username, password and same token from server
-	// but for now, we'll grab them from flags.
 	username := c.String("username")
 	password := c.String("token")
 
@@ -275,145 +583,211 @@ func setupLingo(c *cli.Context) (string, error) {
 	if c.Bool("keep-creds") {
 		if username == "" {
 			username, err = authConfig.GetGitUserName()
-			if err != nil && !strings.Contains(err.Error(), `"username" not found`) {
-				// TODO(waigani) Check error type
-				errors.Annotate(err, "setup --keep-creds failed.")
-				return "", errors.Trace(err)
+			if err != nil && !strings.Contains(err.Error(), "Could not find value") {
+				return "", errors.Annotate(err, "setup --keep-creds failed")
 			}
 		}
 
 		if password == "" {
 			password, err = authConfig.GetGitUserPassword()
-			if err != nil && !strings.Contains(err.Error(), `"password" not found`) {
-				// TODO(waigani) Check error type
-				errors.Annotate(err, "setup --keep-creds failed.")
-				return "", errors.Trace(err)
+			if err != nil && !strings.Contains(err.Error(), "Could not find value") {
+				return "", errors.Annotate(err, "setup --keep-creds failed")
 			}
 		}
 	}
-	// TODO (Junyu) set proper Perforce Username and password
-	// Prompt for user
-	if username == "" || password == "" {
+
+	stdin := bufio.NewReader(os.Stdin)
+	interactive := terminal.IsTerminal(int(os.Stdin.Fd()))
+
+	// Sign in from the browser unless the credentials were given, or can't
+	// be approved by someone at the terminal.
+	var token *login.Token
+	if username == "" && password == "" && interactive && !c.Bool("manual") {
+		token, err = browserLogin(webAddr, !c.Bool("no-browser"))
+		if errors.Cause(err) == login.ErrUnsupported {
+			fmt.Fprintln(os.Stderr, webAddr, "does not support signing in from the browser.")
+		} else if err != nil {
+			return "", errors.Trace(err)
+		}
+	}
+
+	if token != nil {
+		if username == "" {
+			username = token.Username
+		}
+	} else if username == "" || password == "" {
 		lingoTokenAddr := webAddr + "/settings/profile"
 		fmt.Println("Please sign in to " + lingoTokenAddr + " to generate a new Token linked with your CodeLingo User account.")
 	}
 
 	if username == "" {
 		fmt.Print("Enter Your CodeLingo Username: ")
-		fmt.Scanln(&username)
+		username, err = readLine(stdin)
+		if err != nil {
+			return "", errors.Trace(err)
+		}
 	}
 	if username == "" {
 		return "", errors.New("username cannot be empty")
 	}
 
-	if password == "" {
-		fmt.Print("Enter User-Token:")
-
-		// If pwd can be executed,this is an Unix-like shell (i.e. GitBash)
-		// If it runs into an error (i.e. Windows Command Prompt)
-		// use terminal instead of gopass package to read password
-		cmd := exec.Command("bash", "-c", "/usr/bin/pwd")
-		var byt []byte
-		if err := cmd.Run(); err != nil {
-			byt, err = terminal.ReadPassword(int(syscall.Stdin))
+	if token == nil && password == "" {
+		fmt.Print("Enter User-Token: ")
+		if interactive {
+			byt, err := terminal.ReadPassword(int(os.Stdin.Fd()))
+			fmt.Println("")
 			if err != nil {
 				return "", errors.Trace(err)
 			}
+			password = string(byt)
 		} else {
-			byt, err = gopass.GetPasswd()
+			// The token is piped in, e.g. from a CI secret.
+			password, err = readLine(stdin)
 			if err != nil {
 				return "", errors.Trace(err)
 			}
 		}
-
-		password = string(byt)
-		fmt.Println("")
 	}
 
-	if password == "" {
+	if token == nil && password == "" {
 		return "", errors.New("token cannot be empty")
 	}
 
-	// TODO(waigani) check token matches again
-
-	// Set username & password in auth.yaml
+	// Keep the username in auth.yaml and the token in the credentials
+	// store.
 	if err := authConfig.SetGitUserName(username); err != nil {
 		return "", errors.Trace(err)
 	}
-	if err := authConfig.SetGitUserPassword(password); err != nil {
-		return "", errors.Trace(err)
-	}
-
-	// TODo (Junyu) md5 is not secure. All communication should be over tls
-	p4Password := strings.ToUpper(GetMD5Hash(password))
 	if err := authConfig.SetP4UserName(username); err != nil {
 		return "", errors.Trace(err)
 	}
-	if err := authConfig.SetP4UserPassword(p4Password); err != nil {
-		return "", errors.Trace(err)
+	if token != nil {
+		err = authConfig.SaveLogin(token)
+	} else {
+		err = authConfig.SetGitUserPassword(password)
 	}
-
-	credFilename, err := authConfig.GetGitCredentialsFilename()
 	if err != nil {
 		return "", errors.Trace(err)
 	}
 
+	// Have git ask lingo for the token, rather than reading it from a
+	// plaintext file.
 	gitAddr, err := platConfig.GitServerAddr()
 	if err != nil {
 		return "", errors.Trace(err)
 	}
-	cfgDir, err := util.ConfigHome()
-	if err != nil {
+	if err := commonConfig.SetGitCredentialHelper(gitAddr); err != nil {
 		return "", errors.Trace(err)
 	}
 
-	// Set creds in github.
-	// TODO(waigani) these should all be consts.
-	gitCredFile := filepath.Join(cfgDir, credFilename)
-	gitCfgFile := gitCredFile
-	if runtime.GOOS == "windows" {
-		b, err := exec.Command("pwd").CombinedOutput()
-		if err == nil {
-			// Potential bug if the username contains "\\" which is unlikely to happen
-			gitCredFile = strings.Replace(gitCredFile, "\\", "/", -1)
-			gitCfgFile = strings.Replace(gitCredFile, "C:", "/C", 1)
-		} else if !strings.Contains(err.Error(), "executable file not found") {
-			return "", errors.Annotate(err, string(b))
-		}
+	return username, nil
+}
+
+func logoutAction(c *cli.Context) {
+	if err := logout(c); err != nil {
+		util.FatalOSErr(err)
 	}
-	out, err := gitCMD("config",
-		"--global", fmt.Sprintf("credential.%s.helper", gitAddr),
-		fmt.Sprintf("store --file %s", gitCfgFile),
-	)
-	if err != nil {
-		return "", errors.Annotate(err, out)
+}
+
+func logout(c *cli.Context) error {
+	if err := util.MaxArgs(c, 0); err != nil {
+		return errors.Trace(err)
 	}
 
-	// Set git server details
-	gitURL, err := url.Parse(gitAddr)
+	authConfig, err := commonConfig.Auth()
 	if err != nil {
-		return "", errors.Trace(err)
+		return errors.Trace(err)
+	}
+	envs := []string{c.String("env")}
+	if envs[0] == "" {
+		if envs, err = authConfig.LoggedInEnvs(); err != nil {
+			return errors.Trace(err)
+		}
 	}
-	gitURL.User = url.UserPassword(username, password)
-	data := []byte(gitURL.String())
 
-	// Write creds to config file
-	if err := ioutil.WriteFile(gitCredFile, data, 0755); err != nil {
-		return "", errors.Trace(err)
+	var loggedOut, unrevoked []string
+	for _, env := range envs {
+		token, err := authConfig.Logout(env)
+		if err != nil {
+			return errors.Annotatef(err, "failed to log out of %s", env)
+		}
+		if token == nil {
+			continue
+		}
+		loggedOut = append(loggedOut, env)
+
+		if c.Bool("revoke") {
+			webAddr, err := authConfig.WebSiteAddressForEnv(env)
+			if err == nil {
+				err = revokeToken(webAddr, token)
+			}
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Could not revoke the %s token: %v\n", env, err)
+				unrevoked = append(unrevoked, env)
+			}
+		}
 	}
 
-	return username, nil
+	switch {
+	case len(loggedOut) > 0:
+		fmt.Printf("Logged out of %s.\n", strings.Join(loggedOut, ", "))
+	case c.String("env") != "":
+		fmt.Printf("You are not logged in to %s.\n", c.String("env"))
+	default:
+		fmt.Println("You are not logged in.")
+	}
+	if tokenVar := credentials.EnvVar(credentials.TokenName); os.Getenv(tokenVar) != "" {
+		fmt.Fprintf(os.Stderr, "Your token is still read from %s. Unset it to log out completely.\n", tokenVar)
+	}
+	if len(unrevoked) > 0 {
+		return errors.Errorf("the %s token could not be revoked. Revoke it on the website instead", strings.Join(unrevoked, ", "))
+	}
+	return nil
 }
 
-func gitCMD(args ...string) (out string, err error) {
-	cmd := exec.Command("git", args...)
-	b, err := cmd.CombinedOutput()
-	out = strings.TrimSpace(string(b))
-	return out, errors.Annotate(err, out)
+// revokeToken revokes token on the website at webAddr.
+func revokeToken(webAddr string, token *login.Token) error {
+	if token.AccessToken == "" && token.RefreshToken == "" {
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	return errors.Trace((&login.Client{BaseURL: webAddr}).Revoke(ctx, token))
 }
 
-func GetMD5Hash(text string) string {
-	hasher := md5.New()
-	hasher.Write([]byte(text))
-	return hex.EncodeToString(hasher.Sum(nil))
+// browserLogin has the user approve the sign in on the website, opening it
+// in their browser if open is true.
+func browserLogin(webAddr string, open bool) (*login.Token, error) {
+	ctx, cancel := util.UserCancelContext(context.Background())
+	defer cancel()
+
+	client := &login.Client{BaseURL: webAddr}
+	code, err := client.RequestCode(ctx)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+
+	fmt.Printf("To sign in, visit %s and enter the code %s\n", code.VerificationURI, code.UserCode)
+	if open {
+		uri := code.VerificationURIComplete
+		if uri == "" {
+			uri = code.VerificationURI
+		}
+		if err := openBrowser(uri); err != nil {
+			fmt.Fprintln(os.Stderr, "Could not open your browser:", err)
+		}
+	}
+	fmt.Println("Waiting for the sign in to be approved...")
+
+	token, err := client.Poll(ctx, code)
+	return token, errors.Trace(err)
+}
+
+// readLine reads a line from r, without its line ending.
+func readLine(r *bufio.Reader) (string, error) {
+	line, err := r.ReadString('\n')
+	if err != nil && err != io.EOF {
+		return "", errors.Trace(err)
+	}
+	return strings.TrimSpace(line), nil
 }
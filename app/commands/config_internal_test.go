@@ -0,0 +1,35 @@
+package commands
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestIsGitServer(t *testing.T) {
+	const addr = "https://git.codelingo.io:443"
+
+	cases := []struct {
+		request  string
+		expected bool
+	}{
+		{request: "protocol=https\nhost=git.codelingo.io\n", expected: true},
+		{request: "protocol=https\nhost=git.codelingo.io:443\npath=owner/repo.git\n\n", expected: true},
+		{request: "protocol=https\nhost=GIT.codelingo.io\n", expected: true},
+		{request: "protocol=http\nhost=git.codelingo.io\n", expected: false},
+		{request: "protocol=https\nhost=git.codelingo.io:8443\n", expected: false},
+		{request: "protocol=https\nhost=github.com\n", expected: false},
+		{request: "protocol=https\nhost=git.codelingo.io.example.com\n", expected: false},
+		{request: "", expected: false},
+	}
+
+	for _, c := range cases {
+		request, err := readCredentialRequest(strings.NewReader(c.request))
+		if err != nil {
+			t.Errorf("readCredentialRequest(%q): unexpected error: %v", c.request, err)
+			continue
+		}
+		if got := isGitServer(addr, request); got != c.expected {
+			t.Errorf("isGitServer(%q, %q) = %v, expected %v", addr, c.request, got, c.expected)
+		}
+	}
+}
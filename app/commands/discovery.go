@@ -0,0 +1,176 @@
+package commands
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/codelingo/lingo/app/action"
+	"github.com/codelingo/lingo/app/commands/verify"
+	"github.com/codelingo/lingo/app/discovery"
+	"github.com/codelingo/lingo/app/util"
+	"github.com/juju/errors"
+	"github.com/urfave/cli"
+	"gopkg.in/yaml.v2"
+)
+
+func init() {
+	register(&cli.Command{
+		Name:  "discovery",
+		Usage: "Manage the index of published Tenets, Bots, Actions and Lexicons",
+		Subcommands: []cli.Command{
+			{
+				Name:      "mirror",
+				Usage:     "Copy the discovery index and released Actions to a directory for offline use",
+				ArgsUsage: "<dir>",
+				Description: `Reads the whole discovery index configured for the current environment
+   into <dir>, along with the releases of every Action it lists from the
+   configured Actions registry, laid out as a registry in <dir>/actions.
+
+   Copy <dir> to a machine without access to the index and set, in
+   platform.yaml for its environment:
+
+     discovery:
+       root: <dir>
+     actions:
+       registry: file://<dir>/actions
+
+   Running mirror again updates the copy.`,
+				Action: mirrorDiscoveryAction,
+				Flags: []cli.Flag{
+					cli.StringSliceFlag{
+						Name:  "platform",
+						Usage: "Only copy Action archives for the given <os>/<arch>. May be repeated",
+					},
+					cli.BoolFlag{
+						Name:  "no-actions",
+						Usage: "Only copy the index, not the Actions it lists",
+					},
+				},
+			},
+		},
+	}, false, false, verify.HomeRq, verify.ConfigRq)
+}
+
+func mirrorDiscoveryAction(ctx *cli.Context) {
+	if err := mirrorDiscovery(ctx); err != nil {
+		util.FatalOSErr(err)
+		return
+	}
+}
+
+func mirrorDiscovery(ctx *cli.Context) error {
+	if ctx.NArg() != 1 {
+		return errors.New("Failed to mirror the discovery index - give the directory to copy it to.")
+	}
+	dir, err := filepath.Abs(ctx.Args().First())
+	if err != nil {
+		return errors.Trace(err)
+	}
+
+	// Always read the index afresh, so the mirror isn't a stale cache.
+	src, err := discoverySource(true)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	ix, err := discovery.Load(&discovery.Mirror{Source: src, Dir: dir})
+	if err != nil {
+		return errors.Annotate(err, "failed to read the discovery index")
+	}
+	fmt.Printf("Copied the discovery index, listing %d items, to %s.\n", len(ix.Items), dir)
+	if ctx.Bool("no-actions") {
+		return nil
+	}
+
+	registry, err := actionRegistry("")
+	if err != nil {
+		return errors.Trace(err)
+	}
+	actionsDir := filepath.Join(dir, "actions")
+	var archives int
+	for _, item := range ix.Search(&discovery.Query{Kinds: []discovery.Kind{discovery.KindAction}}) {
+		n, err := mirrorAction(registry, item.Owner, item.Name, actionsDir, ctx.StringSlice("platform"))
+		if err != nil {
+			// Actions may be listed before they are released.
+			fmt.Fprintf(os.Stderr, "Skipping Action %s: %v\n", item.FullName(), err)
+			continue
+		}
+		archives += n
+	}
+	fmt.Printf("Copied %d Action archives to %s.\n", archives, actionsDir)
+	fmt.Printf(`
+To use the mirror, set in platform.yaml for the environment:
+
+  discovery:
+    root: %s
+  actions:
+    registry: file://%s
+`, dir, filepath.ToSlash(actionsDir))
+	return nil
+}
+
+// mirrorAction copies the released archives of an Action for the given
+// platforms, or all platforms if none are given, into a registry in dir. It
+// returns the number of archives copied.
+func mirrorAction(registry *action.Registry, owner, name, dir string, platforms []string) (int, error) {
+	baseURL, releases, err := registry.Releases(owner, name)
+	if err != nil {
+		return 0, errors.Trace(err)
+	}
+
+	var copied int
+	for version, archives := range releases.Versions {
+		for platform, archive := range archives {
+			if archive == nil || (len(platforms) > 0 && !stringIn(platform, platforms)) {
+				delete(archives, platform)
+				continue
+			}
+			parts := strings.SplitN(platform, "/", 2)
+			if len(parts) != 2 {
+				return 0, errors.Errorf("invalid platform %q in the release manifest", platform)
+			}
+			goos, goarch := parts[0], parts[1]
+
+			data, err := action.Fetch(archive.ArchiveURL(baseURL, version, goos, goarch))
+			if err != nil {
+				return 0, errors.Trace(err)
+			}
+			if err := archive.Verify(data, registry.PublicKey); err != nil {
+				return 0, errors.Annotatef(err, "refusing to copy %s@%s for %s", owner+"/"+name, version, platform)
+			}
+			path := filepath.Join(dir, owner, name, "bin", goos, goarch, version, action.ArchiveName(goos))
+			if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+				return 0, errors.Trace(err)
+			}
+			if err := ioutil.WriteFile(path, data, 0644); err != nil {
+				return 0, errors.Trace(err)
+			}
+			// The copy is at the default location in the mirror.
+			archive.URL = ""
+			copied++
+		}
+		if len(archives) == 0 {
+			delete(releases.Versions, version)
+		}
+	}
+	if len(releases.Versions) == 0 {
+		return 0, errors.New("it has no releases for the given platforms")
+	}
+
+	data, err := yaml.Marshal(releases)
+	if err != nil {
+		return 0, errors.Trace(err)
+	}
+	return copied, errors.Trace(ioutil.WriteFile(filepath.Join(dir, owner, name, action.ReleasesFile), data, 0644))
+}
+
+func stringIn(s string, list []string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
@@ -1,10 +1,7 @@
 package commands
 
 import (
-	"fmt"
-	"io/ioutil"
-	"net/http"
-
+	"github.com/codelingo/lingo/app/discovery"
 	"github.com/codelingo/lingo/app/util"
 
 	"github.com/juju/errors"
@@ -16,7 +13,7 @@ func init() {
 		Name:   "actions",
 		Usage:  "List Actions",
 		Action: listFlowsAction,
-		Flags: []cli.Flag{
+		Flags: append([]cli.Flag{
 			cli.BoolFlag{
 				Name:  util.InstalledFlg.String(),
 				Usage: "Only show installed Actions",
@@ -29,7 +26,7 @@ func init() {
 				Name:  util.NameFlg.String(),
 				Usage: "Describe the named Actions",
 			},
-		},
+		}, discoveryFlags()...),
 	}, false, true)
 }
 
@@ -42,36 +39,9 @@ func listFlowsAction(ctx *cli.Context) {
 }
 
 func listFlows(ctx *cli.Context) error {
-	owner := ctx.String("owner")
-	name := ctx.String("name")
-
-	baseFlowURL := baseDiscoveryURL + "flows"
-	url := baseFlowURL + "/lingo_flows.yaml"
-	switch {
-	case name != "":
-
-		if owner == "" {
-			return errors.New("owner flag must be set")
-		}
-
-		url = fmt.Sprintf("%s/%s/%s/lingo_flow.yaml",
-			baseFlowURL, owner, name)
-
-	case owner != "":
-		url = fmt.Sprintf("%s/%s/lingo_owner.yaml",
-			baseFlowURL, owner)
-	}
-	resp, err := http.Get(url)
-	if err != nil {
-		return errors.Trace(err)
-	}
-
-	defer resp.Body.Close()
-	data, err := ioutil.ReadAll(resp.Body)
-	if err != nil {
-		return errors.Trace(err)
-	}
-
-	fmt.Println(string(data))
-	return nil
+	return errors.Trace(listDiscovered(ctx, &discovery.Query{
+		Kinds: []discovery.Kind{discovery.KindAction},
+		Owner: ctx.String(util.OwnerFlg.Long),
+		Name:  ctx.String(util.NameFlg.Long),
+	}))
 }
@@ -0,0 +1,124 @@
+package commands
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/codelingo/lingo/app/clql"
+	"github.com/codelingo/lingo/app/util"
+	"github.com/codelingo/lingo/app/util/common"
+	"github.com/juju/errors"
+	"github.com/urfave/cli"
+)
+
+func init() {
+	register(&cli.Command{
+		Name:      "fmt",
+		Usage:     "Format the queries of every Tenet in the given codelingo.yaml files or directories.",
+		ArgsUsage: "[paths...]",
+		Action:    fmtAction,
+		Flags: []cli.Flag{
+			cli.BoolFlag{
+				Name:  "check",
+				Usage: "Don't write the formatted files. Exit with a non-zero status if any file is not formatted.",
+			},
+		},
+	}, false, false)
+}
+
+func fmtAction(ctx *cli.Context) {
+	if err := fmtLingo(ctx); err != nil {
+		util.FatalOSErr(err)
+		return
+	}
+}
+
+func fmtLingo(c *cli.Context) error {
+	paths := []string(c.Args())
+	if len(paths) == 0 {
+		paths = []string{"."}
+	}
+
+	files, err := findDotlingoFiles(paths)
+	if err != nil {
+		return errors.Trace(err)
+	}
+
+	var unformatted []string
+	for _, file := range files {
+		src, err := ioutil.ReadFile(file)
+		if err != nil {
+			return errors.Trace(err)
+		}
+
+		formatted, err := clql.FormatFile(src)
+		if err != nil {
+			return errors.Annotatef(err, "could not format %s", file)
+		}
+		if bytes.Equal(src, formatted) {
+			continue
+		}
+
+		if c.Bool("check") {
+			unformatted = append(unformatted, file)
+			fmt.Println(file)
+			continue
+		}
+
+		info, err := os.Stat(file)
+		if err != nil {
+			return errors.Trace(err)
+		}
+		if err := ioutil.WriteFile(file, formatted, info.Mode()); err != nil {
+			return errors.Trace(err)
+		}
+		fmt.Println("Formatted", file)
+	}
+
+	if len(unformatted) > 0 {
+		return errors.Errorf("%d file(s) are not formatted. Run `lingo fmt` to format them.", len(unformatted))
+	}
+	return nil
+}
+
+// findDotlingoFiles returns the codelingo.yaml files found in the given
+// files and directories. Directories are searched recursively, skipping
+// hidden and vendor directories.
+func findDotlingoFiles(paths []string) ([]string, error) {
+	var files []string
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		if !info.IsDir() {
+			files = append(files, path)
+			continue
+		}
+
+		err = filepath.Walk(path, func(p string, info os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+			if info.IsDir() {
+				name := info.Name()
+				if p != path && (strings.HasPrefix(name, ".") || name == "vendor") {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			if common.IsDotlingoFile(p) {
+				files = append(files, p)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+	}
+	return files, nil
+}
@@ -2,12 +2,16 @@ package commands
 
 import (
 	"fmt"
-	"io"
-	"net/http"
+	"io/ioutil"
 	"os"
+	"path/filepath"
 	"runtime"
+	"strings"
 
+	"github.com/codelingo/lingo/app/action"
+	"github.com/codelingo/lingo/app/commands/verify"
 	"github.com/codelingo/lingo/app/util"
+	"github.com/codelingo/lingo/app/util/common/config"
 	"github.com/juju/errors"
 	"github.com/mholt/archiver"
 	"github.com/urfave/cli"
@@ -15,17 +19,35 @@ import (
 
 func init() {
 	register(&cli.Command{
-		Name:   "install",
-		Usage:  "Install an Action",
+		Name:      "install",
+		Usage:     "Install an Action, or the Actions pinned in the project's codelingo.lock if none is given",
+		ArgsUsage: "[[owner/]name[@version] | path]",
+		Description: `The version may be an exact version, a range such as ">=1.2.0 <2.0.0",
+   or "latest", which is the default.
+
+   Actions are installed from the registry set by actions.registry in
+   platform.yaml, or from a local path: a .tar.gz or .zip archive, a
+   directory holding a built cmd, or a directory laid out as a registry.`,
 		Action: installAction,
 		Flags: []cli.Flag{
 			cli.StringFlag{
 				Name:  "owner",
-				Value: "codelingo",
-				Usage: "Owner of the Action",
+				Usage: "Owner of the Action. Defaults to the owner setting, or codelingo",
+			},
+			cli.StringFlag{
+				Name:  "name",
+				Usage: "Name of an Action installed from a local path. Defaults to the path's base name",
+			},
+			cli.StringFlag{
+				Name:  "registry",
+				Usage: "URL of the registry to install from, overriding actions.registry in platform.yaml",
+			},
+			cli.BoolFlag{
+				Name:  "save",
+				Usage: "Pin the installed Action in the project's codelingo.lock, creating it in the current directory if needed",
 			},
 		},
-	}, false, false)
+	}, false, false, verify.HomeRq, verify.ConfigRq)
 }
 
 func installAction(ctx *cli.Context) {
@@ -33,73 +55,259 @@ func installAction(ctx *cli.Context) {
 		util.FatalOSErr(err)
 		return
 	}
-	fmt.Printf("Success! You can now run the installed Action with `lingo run %s`\n", ctx.Args()[0])
 }
 
 func install(c *cli.Context) error {
 	args := c.Args()
+	registry, err := actionRegistry(c.String("registry"))
+	if err != nil {
+		return errors.Trace(err)
+	}
 	if len(args) == 0 {
-		return errors.New("Failed to install Action - no Action given.")
+		return errors.Trace(installLocked(registry))
 	}
 
-	home, err := util.LingoHome()
-	if err != nil {
-		return errors.Trace(err)
+	var source action.Source = registry
+	var ownerName, actionName, version string
+	if action.IsLocalPath(args[0]) {
+		source = &action.Local{Path: args[0]}
+		actionName = c.String("name")
+		if actionName == "" {
+			actionName = action.LocalName(args[0])
+		}
+	} else {
+		ownerName, actionName, version, err = action.ParseRef(args[0])
+		if err != nil {
+			return errors.Trace(err)
+		}
+	}
+	if ownerName == "" {
+		if ownerName, err = ownerFlag(c); err != nil {
+			return errors.Trace(err)
+		}
 	}
 
-	ownerName := c.String("owner")
-	actionName := args[0]
-	actionPath := fmt.Sprintf("%s/flows/%s/%s", home, ownerName, actionName)
+	// Check where the Action would be pinned before installing it.
+	var lockPath, localPath string
+	if c.Bool("save") {
+		if lockPath, err = action.FindLockFile("."); err != nil {
+			return errors.Trace(err)
+		}
+		if lockPath == "" {
+			lockPath = action.LockFile
+		}
+		if local, ok := source.(*action.Local); ok {
+			if localPath, err = lockedPath(lockPath, local.Path); err != nil {
+				return errors.Trace(err)
+			}
+		}
+	}
 
-	if err := os.MkdirAll(actionPath, 0755); err != nil {
+	installed, err := installVersion(source, ownerName, actionName, version, "")
+	if err != nil {
 		return errors.Trace(err)
 	}
 
-	// TODO(waigani) pass in version
-	version := "0.0.0"
+	if lockPath != "" {
+		lock, err := action.LoadManifest(lockPath)
+		if err != nil {
+			return errors.Trace(err)
+		}
+		pinnedSource := installed.Source
+		if localPath != "" {
+			pinnedSource = localPath
+		}
+		lock.Set(&action.Installed{
+			Owner:   installed.Owner,
+			Name:    installed.Name,
+			Version: installed.Version,
+			Source:  pinnedSource,
+			SHA256:  installed.SHA256,
+		})
+		if err := lock.Save(); err != nil {
+			return errors.Trace(err)
+		}
+		fmt.Printf("Pinned %s@%s in %s\n", installed.FullName(), installed.Version, lockPath)
+	}
+
+	fmt.Printf("Success! Installed %s@%s. You can now run it with `lingo run %s`\n", installed.FullName(), installed.Version, installed.FullName())
+	return nil
+}
 
-	currentOS := runtime.GOOS
-	archiveName := "cmd.tar.gz"
-	if currentOS == "windows" {
-		archiveName = "cmd.exe.zip"
+// installLocked installs exactly the Actions pinned by the project's
+// lockfile.
+func installLocked(registry *action.Registry) error {
+	lockPath, err := action.FindLockFile(".")
+	if err != nil {
+		return errors.Trace(err)
+	}
+	if lockPath == "" {
+		return errors.Errorf("Failed to install Action - no Action given and no %s found.", action.LockFile)
+	}
+	lock, err := action.LoadManifest(lockPath)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	if len(lock.Actions) == 0 {
+		return errors.Errorf("%s does not pin any Actions.", lockPath)
 	}
 
-	fileUrl := fmt.Sprintf("https://github.com/codelingo/actions/raw/master/actions/%s/%s/bin/%s/%s/%s/%s", ownerName, actionName, currentOS, runtime.GOARCH, version, archiveName)
-	fmt.Println("Installing Action:", fileUrl)
-	if err := DownloadFile(actionPath+"/"+archiveName, fileUrl); err != nil {
+	manifest, err := actionsManifest()
+	if err != nil {
 		return errors.Trace(err)
 	}
+	for _, pinned := range lock.Actions {
+		if current := manifest.Get(pinned.Owner, pinned.Name); current != nil &&
+			current.Version == pinned.Version && current.SHA256 == pinned.SHA256 {
+			fmt.Printf("%s@%s is already installed\n", pinned.FullName(), pinned.Version)
+			continue
+		}
 
-	return errors.Trace(extractCMD(actionPath, archiveName))
+		var source action.Source = registry
+		if pinned.Version == action.LocalVersion {
+			// Local paths are pinned relative to the lockfile.
+			path := filepath.FromSlash(pinned.Source)
+			if !filepath.IsAbs(path) {
+				path = filepath.Join(filepath.Dir(lockPath), path)
+			}
+			source = &action.Local{Path: path}
+		}
+		if _, err := installVersion(source, pinned.Owner, pinned.Name, pinned.Version, pinned.SHA256); err != nil {
+			return errors.Annotatef(err, "installing %s@%s from %s", pinned.FullName(), pinned.Version, lockPath)
+		}
+	}
+	fmt.Printf("Success! Installed the %d Action(s) pinned in %s\n", len(lock.Actions), lockPath)
+	return nil
 }
 
-func DownloadFile(filepath string, url string) error {
+// lockedPath returns the path of an Action installed from a local path,
+// relative to the lockfile's directory so the lockfile works wherever the
+// project is checked out.
+func lockedPath(lockPath, path string) (string, error) {
+	lockDir, err := filepath.Abs(filepath.Dir(lockPath))
+	if err != nil {
+		return "", errors.Trace(err)
+	}
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return "", errors.Trace(err)
+	}
+	rel, err := filepath.Rel(lockDir, abs)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", errors.Errorf("cannot pin an Action installed from %s, which is outside %s. Move it into the project first", path, lockDir)
+	}
+	return filepath.ToSlash(rel), nil
+}
 
-	out, err := os.Create(filepath)
+// installVersion installs the version of an Action matching the constraint
+// from the source, and records it in the installed Actions manifest. If
+// pinnedSHA256 is set, the fetched command must have that checksum.
+func installVersion(source action.Source, ownerName, actionName, version, pinnedSHA256 string) (*action.Installed, error) {
+	home, err := util.LingoHome()
 	if err != nil {
-		return errors.Trace(err)
+		return nil, errors.Trace(err)
 	}
-	defer out.Close()
+	actionPath := fmt.Sprintf("%s/flows/%s/%s", home, ownerName, actionName)
 
-	resp, err := http.Get(url)
+	fmt.Printf("Installing Action: %s/%s\n", ownerName, actionName)
+	fetched, err := source.Fetch(ownerName, actionName, version)
 	if err != nil {
-		return errors.Trace(err)
+		return nil, errors.Trace(err)
+	}
+	if pinnedSHA256 != "" && !strings.EqualFold(pinnedSHA256, fetched.SHA256) {
+		return nil, errors.Errorf("refusing to install %s/%s@%s: its checksum %s does not match the pinned checksum %s", ownerName, actionName, fetched.Version, fetched.SHA256, pinnedSHA256)
 	}
-	defer resp.Body.Close()
 
-	_, err = io.Copy(out, resp.Body)
+	// Prepare the new version next to the installed one, then swap it in so
+	// a failed install never leaves a broken Action behind.
+	staging, err := action.StagingDir(actionPath)
 	if err != nil {
-		return errors.Trace(err)
+		return nil, errors.Trace(err)
 	}
-	return nil
+	defer os.RemoveAll(staging)
+	if fetched.ArchiveName == "" {
+		if err := ioutil.WriteFile(filepath.Join(staging, "cmd"+cmdExt()), fetched.Data, 0755); err != nil {
+			return nil, errors.Trace(err)
+		}
+		if fetched.Spec != nil {
+			if err := ioutil.WriteFile(filepath.Join(staging, action.SpecFile), fetched.Spec, 0644); err != nil {
+				return nil, errors.Trace(err)
+			}
+		}
+	} else {
+		if err := ioutil.WriteFile(staging+"/"+fetched.ArchiveName, fetched.Data, 0644); err != nil {
+			return nil, errors.Trace(err)
+		}
+		if err := extractCMD(staging, fetched.ArchiveName); err != nil {
+			return nil, errors.Trace(err)
+		}
+	}
+	if err := action.Swap(actionPath, staging); err != nil {
+		return nil, errors.Annotatef(err, "failed to install %s/%s@%s", ownerName, actionName, fetched.Version)
+	}
+
+	installed := &action.Installed{
+		Owner:   ownerName,
+		Name:    actionName,
+		Version: fetched.Version,
+		Source:  fetched.Origin,
+		SHA256:  fetched.SHA256,
+	}
+	manifest, err := actionsManifest()
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	if previous := manifest.Get(ownerName, actionName); previous != nil {
+		previous.Previous = nil
+		installed.Previous = previous
+	}
+	manifest.Set(installed)
+	return installed, errors.Trace(manifest.Save())
 }
 
-func extractCMD(dir, archiveName string) error {
+// actionRegistry returns the registry to install Actions from. The URL
+// overrides the configured registry if set.
+func actionRegistry(url string) (*action.Registry, error) {
+	pCfg, err := config.Platform()
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	publicKey, err := pCfg.ActionsPublicKey()
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	if url == "" {
+		url, err = pCfg.ActionsRegistry()
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+	}
+	if url == "" {
+		url = action.DefaultRegistry
+	}
+	return &action.Registry{URL: url, PublicKey: publicKey}, nil
+}
+
+// actionsManifest loads the manifest of installed Actions.
+func actionsManifest() (*action.Manifest, error) {
+	home, err := util.LingoHome()
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	return action.LoadManifest(filepath.Join(home, "flows", action.ManifestFile))
+}
 
-	var ext string
+func cmdExt() string {
 	if runtime.GOOS == "windows" {
+		return ".exe"
+	}
+	return ""
+}
+
+func extractCMD(dir, archiveName string) error {
+
+	if strings.HasSuffix(archiveName, ".zip") {
 
-		ext = ".exe"
 		err := archiver.DefaultZip.Unarchive(dir+"/"+archiveName, dir)
 		if err != nil {
 			return errors.Trace(err)
@@ -114,5 +322,5 @@ func extractCMD(dir, archiveName string) error {
 
 	}
 
-	return errors.Trace(os.Chmod(dir+"/cmd"+ext, 0755))
+	return errors.Trace(os.Chmod(dir+"/cmd"+cmdExt(), 0755))
 }
@@ -0,0 +1,39 @@
+package commands
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestLockedPath(t *testing.T) {
+	project := filepath.FromSlash("/src/project")
+	lockPath := filepath.Join(project, "codelingo.lock")
+
+	cases := []struct {
+		path     string
+		expected string
+		err      bool
+	}{
+		{path: filepath.Join(project, "actions", "lint"), expected: "actions/lint"},
+		{path: filepath.Join(project, "build", "lint.tar.gz"), expected: "build/lint.tar.gz"},
+		{path: filepath.FromSlash("/src/other/lint"), err: true},
+		{path: filepath.FromSlash("/src"), err: true},
+	}
+
+	for _, c := range cases {
+		rel, err := lockedPath(lockPath, c.path)
+		if c.err {
+			if err == nil {
+				t.Errorf("%s: expected an error, got %q", c.path, rel)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", c.path, err)
+			continue
+		}
+		if rel != c.expected {
+			t.Errorf("%s: want %q, got %q", c.path, c.expected, rel)
+		}
+	}
+}
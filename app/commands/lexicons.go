@@ -5,9 +5,9 @@ import (
 	"encoding/json"
 	"fmt"
 	"io/ioutil"
-	"net/http"
 
 	"github.com/codelingo/lingo/app/commands/verify"
+	"github.com/codelingo/lingo/app/discovery"
 	"github.com/codelingo/lingo/app/util"
 	"github.com/juju/errors"
 	"github.com/urfave/cli"
@@ -23,7 +23,7 @@ func init() {
 		Name:   "lexicons",
 		Usage:  "List Lexicons",
 		Action: listLexiconsAction,
-		Flags: []cli.Flag{
+		Flags: append([]cli.Flag{
 			cli.StringFlag{
 				Name:  util.OwnerFlg.String(),
 				Usage: "List all Lexicons of the given owner",
@@ -36,11 +36,11 @@ func init() {
 				Name:  util.TypeFlg.String(),
 				Usage: "List all Lexicons of the given type",
 			},
-			cli.StringFlag{
+			cli.BoolFlag{
 				Name:  util.InstalledFlg.String(),
 				Usage: "List Lexicons used in current project",
 			},
-		},
+		}, discoveryFlags()...),
 	}, false, false, verify.VersionRq)
 }
 
@@ -53,49 +53,12 @@ func listLexiconsAction(ctx *cli.Context) {
 }
 
 func listLexicons(ctx *cli.Context) error {
-	owner := ctx.String("owner")
-	lexType := ctx.String("type")
-	name := ctx.String("name")
-
-	baseLexURL := baseDiscoveryURL + "lexicons"
-	url := baseLexURL + "/lingo_lexicon_type.yaml"
-	switch {
-	case name != "":
-
-		if owner == "" {
-			return errors.New("owner flag must be set")
-		}
-
-		if lexType == "" {
-			return errors.New("type flag must be set")
-		}
-		url = fmt.Sprintf("%s/%s/%s/%s/lingo_lexicon.yaml",
-			baseLexURL, lexType, owner, name)
-
-	case owner != "":
-		if lexType == "" {
-			return errors.New("type flag must be set")
-		}
-		url = fmt.Sprintf("%s/%s/%s/lingo_owner.yaml",
-			baseLexURL, lexType, owner)
-	case lexType != "":
-
-		url = fmt.Sprintf("%s/%s/lingo_lexicons.yaml",
-			baseLexURL, lexType)
-	}
-	resp, err := http.Get(url)
-	if err != nil {
-		return errors.Trace(err)
-	}
-
-	defer resp.Body.Close()
-	data, err := ioutil.ReadAll(resp.Body)
-	if err != nil {
-		return errors.Trace(err)
-	}
-
-	fmt.Println(string(data))
-	return nil
+	return errors.Trace(listDiscovered(ctx, &discovery.Query{
+		Kinds: []discovery.Kind{discovery.KindLexicon},
+		Owner: ctx.String(util.OwnerFlg.Long),
+		Type:  ctx.String(util.TypeFlg.Long),
+		Name:  ctx.String(util.NameFlg.Long),
+	}))
 }
 
 func getFormat(format string, lexicons []string) []byte {
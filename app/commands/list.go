@@ -63,9 +63,18 @@ func listLocalFlowsAction(ctx *cli.Context) {
 }
 
 func listLocalFlows(c *cli.Context) (string, error) {
-	str := `Actions:
-  - review
-  - search`
+	manifest, err := actionsManifest()
+	if err != nil {
+		return "", errors.Trace(err)
+	}
+
+	str := "Actions:"
+	if len(manifest.Actions) == 0 {
+		str += "\n  No Actions are installed. Install one with `lingo install <action>`."
+	}
+	for _, installed := range manifest.Actions {
+		str += fmt.Sprintf("\n  - %s@%s", installed.FullName(), installed.Version)
+	}
 	return str, nil
 }
 
@@ -0,0 +1,60 @@
+package commands
+
+import (
+	"context"
+	"os"
+
+	"github.com/codelingo/lingo/app/commands/verify"
+	"github.com/codelingo/lingo/app/lsp"
+	"github.com/codelingo/lingo/app/util"
+	"github.com/codelingo/lingo/service"
+	rpc "github.com/codelingo/rpc/service"
+	"github.com/juju/errors"
+	"github.com/urfave/cli"
+)
+
+func init() {
+	register(&cli.Command{
+		Name:   "lsp",
+		Usage:  "Run a language server for codelingo.yaml files over stdin and stdout.",
+		Action: lspAction,
+		Flags: []cli.Flag{
+			cli.BoolFlag{
+				Name:  util.InsecureFlg.String(),
+				Usage: "Allow the server to run against an insecure development environment",
+			},
+		},
+		// Don't check for updates: anything written to stdout would corrupt
+		// the protocol stream.
+	}, false, false, verify.HomeRq, verify.ConfigRq)
+}
+
+func lspAction(ctx *cli.Context) {
+	if err := runLSP(ctx); err != nil {
+		util.FatalOSErr(err)
+		return
+	}
+}
+
+func runLSP(cliCtx *cli.Context) error {
+	server := lsp.NewServer(&servicePlatform{insecure: cliCtx.Bool(util.InsecureFlg.Long)})
+	return errors.Trace(server.Serve(context.Background(), os.Stdin, os.Stdout))
+}
+
+// servicePlatform serves the language server's platform requests using the
+// CodeLingo service.
+type servicePlatform struct {
+	insecure bool
+}
+
+func (p *servicePlatform) ListFacts(ctx context.Context, owner, name, version string) (map[string][]string, error) {
+	return service.ListFacts(ctx, owner, name, version)
+}
+
+func (p *servicePlatform) DescribeFact(ctx context.Context, owner, name, version, fact string) (*rpc.DescribeFactReply, error) {
+	return service.DescribeFact(ctx, owner, name, version, fact)
+}
+
+func (p *servicePlatform) QueryFromOffset(ctx context.Context, req *rpc.QueryFromOffsetRequest) (*rpc.QueryFromOffsetReply, error) {
+	return service.QueryFromOffset(ctx, req, p.insecure)
+}
@@ -0,0 +1,193 @@
+package commands
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+
+	"github.com/codelingo/lingo/app/util"
+	commonConfig "github.com/codelingo/lingo/app/util/common/config"
+	"github.com/juju/errors"
+	"github.com/urfave/cli"
+	"golang.org/x/crypto/ssh/terminal"
+)
+
+// onpremCommand is `lingo config onprem`.
+var onpremCommand = cli.Command{
+	Name:  "onprem",
+	Usage: "Configure the addresses of the services of an on-prem platform.",
+	Description: `Each service's address is given as [scheme://]host[:port]. The https and ssl
+   schemes, or none, connect over TLS, and http and tcp connect without it.
+   The port defaults to the service's usual one.
+
+   Addresses are read from a config bundle given with --bundle, then from the
+   flags, and any still missing are asked for. A bundle is a yaml file:
+
+     version: 1
+     services:
+       website: {host: lingo.example.com}
+       platform: {host: grpc.example.com, port: 8001}
+       flow: {host: grpc.example.com, port: 8002, tls: false}
+       gitserver: {host: git.example.com}
+       p4server: {host: p4.example.com, port: 1666}
+
+   Every address is checked by connecting to it before any is saved.`,
+	Action: onpremAction,
+	Flags: []cli.Flag{
+		cli.StringFlag{
+			Name:  "env",
+			Value: "onprem",
+			Usage: "The environment to configure, which becomes the current one.",
+		},
+		cli.StringFlag{
+			Name:  "bundle",
+			Usage: "Import the addresses from a config bundle.",
+		},
+		cli.StringFlag{
+			Name:  "host",
+			Usage: "Use this host, with the usual ports, for every service not given otherwise.",
+		},
+		cli.StringFlag{
+			Name:  commonConfig.ServiceWebsite,
+			Usage: "The address of the website.",
+		},
+		cli.StringFlag{
+			Name:  commonConfig.ServicePlatform,
+			Usage: "The address of the platform's gRPC server.",
+		},
+		cli.StringFlag{
+			Name:  commonConfig.ServiceFlow,
+			Usage: "The address of the flow gRPC server.",
+		},
+		cli.StringFlag{
+			Name:  commonConfig.ServiceGitServer,
+			Usage: "The address of the git server.",
+		},
+		cli.StringFlag{
+			Name:  commonConfig.ServiceP4Server,
+			Usage: "The address of the Perforce server, if there is one.",
+		},
+		cli.BoolFlag{
+			Name:  "no-validate",
+			Usage: "Save the addresses without checking they can be reached.",
+		},
+	},
+}
+
+func onpremAction(ctx *cli.Context) {
+	if err := onprem(ctx); err != nil {
+		util.FatalOSErr(err)
+	}
+}
+
+func onprem(ctx *cli.Context) error {
+	if err := util.MaxArgs(ctx, 0); err != nil {
+		return errors.Trace(err)
+	}
+	env := ctx.String("env")
+
+	o := &commonConfig.OnPrem{Version: 1, Services: map[string]*commonConfig.OnPremService{}}
+	if path := ctx.String("bundle"); path != "" {
+		var err error
+		if o, err = commonConfig.ReadOnPremBundle(path); err != nil {
+			return errors.Trace(err)
+		}
+		if o.Services == nil {
+			o.Services = map[string]*commonConfig.OnPremService{}
+		}
+	}
+	for _, name := range commonConfig.OnPremServices {
+		if addr := ctx.String(name); addr != "" {
+			s, err := commonConfig.ParseService(name, addr)
+			if err != nil {
+				return errors.Trace(err)
+			}
+			o.Services[name] = s
+		}
+	}
+	if host := ctx.String("host"); host != "" {
+		if err := setHost(o, host); err != nil {
+			return errors.Trace(err)
+		}
+	}
+
+	// Guide the user through any addresses that weren't given.
+	if o.Check() != nil && terminal.IsTerminal(int(os.Stdin.Fd())) {
+		if err := promptOnPrem(env, o); err != nil {
+			return errors.Trace(err)
+		}
+	}
+	if err := saveOnPrem(env, o, !ctx.Bool("no-validate")); err != nil {
+		return errors.Trace(err)
+	}
+	fmt.Printf("Success! Environment set to '%v'.\n", env)
+	return nil
+}
+
+// setHost sets every required service not yet given to host, on its usual
+// port.
+func setHost(o *commonConfig.OnPrem, host string) error {
+	for _, name := range commonConfig.OnPremServices {
+		if o.Services[name] != nil || name == commonConfig.ServiceP4Server {
+			continue
+		}
+		s, err := commonConfig.ParseService(name, host)
+		if err != nil {
+			return errors.Trace(err)
+		}
+		o.Services[name] = s
+	}
+	return nil
+}
+
+// promptOnPrem asks for the address of each service not yet given,
+// offering the one already configured for env.
+func promptOnPrem(env string, o *commonConfig.OnPrem) error {
+	current, err := commonConfig.CurrentOnPrem(env)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	stdin := bufio.NewReader(os.Stdin)
+	for _, name := range commonConfig.OnPremServices {
+		if o.Services[name] != nil {
+			continue
+		}
+		prompt := fmt.Sprintf("Address of the %s", name)
+		if name == commonConfig.ServiceP4Server {
+			prompt += ", if there is one"
+		}
+		if s := current.Services[name]; s != nil {
+			prompt += fmt.Sprintf(" [%s]", s)
+		}
+		fmt.Print(prompt + ": ")
+
+		addr, err := readLine(stdin)
+		if err != nil {
+			return errors.Trace(err)
+		}
+		if addr == "" {
+			// Keep the current address, if there is one.
+			o.Services[name] = current.Services[name]
+			continue
+		}
+		if o.Services[name], err = commonConfig.ParseService(name, addr); err != nil {
+			return errors.Trace(err)
+		}
+	}
+	return nil
+}
+
+// saveOnPrem writes the services' addresses for env, after checking they
+// can be reached if validate is true.
+func saveOnPrem(env string, o *commonConfig.OnPrem, validate bool) error {
+	if err := o.Check(); err != nil {
+		return errors.Annotate(err, "on-prem config is incomplete")
+	}
+	if validate {
+		fmt.Println("Checking the services can be reached...")
+		if err := o.Dial(); err != nil {
+			return errors.Annotate(err, "nothing was saved. Use --no-validate to save the addresses anyway")
+		}
+	}
+	return errors.Trace(commonConfig.WriteOnPrem(env, o))
+}
@@ -0,0 +1,161 @@
+package commands
+
+import (
+	"fmt"
+
+	"github.com/codelingo/lingo/app/util"
+	commonConfig "github.com/codelingo/lingo/app/util/common/config"
+	"github.com/juju/errors"
+	"github.com/urfave/cli"
+)
+
+// profileCommand is `lingo config profile`. A profile has its own platform
+// addresses, environment, credentials and default owner.
+var profileCommand = cli.Command{
+	Name:   "profile",
+	Usage:  "List the profiles, each with its own platform addresses and credentials.",
+	Action: listProfilesAction,
+	Subcommands: []cli.Command{
+		{
+			Name:      "create",
+			Usage:     "Create a profile with the default platform addresses.",
+			ArgsUsage: "<name>",
+			Action:    createProfileAction,
+			Flags: []cli.Flag{
+				cli.StringFlag{
+					Name:  "env",
+					Value: "paas",
+					Usage: "The environment of the profile.",
+				},
+				cli.StringFlag{
+					Name:  "owner",
+					Usage: "The owner of Actions named without one, instead of codelingo.",
+				},
+			},
+		},
+		{
+			Name:   "list",
+			Usage:  "List the profiles, marking the one in use.",
+			Action: listProfilesAction,
+		},
+		{
+			Name:      "use",
+			Aliases:   []string{"switch"},
+			Usage:     "Use the given profile.",
+			ArgsUsage: "<name>",
+			Action:    useProfileAction,
+		},
+		{
+			Name:      "copy",
+			Usage:     "Create a profile with the config of another, but not its credentials.",
+			ArgsUsage: "<from> <to>",
+			Action:    copyProfileAction,
+		},
+		{
+			Name:      "delete",
+			Usage:     "Log out of a profile and delete it.",
+			ArgsUsage: "<name>",
+			Action:    deleteProfileAction,
+		},
+	},
+}
+
+func listProfilesAction(ctx *cli.Context) {
+	if err := listProfiles(ctx); err != nil {
+		util.FatalOSErr(err)
+	}
+}
+
+func listProfiles(ctx *cli.Context) error {
+	if err := util.MaxArgs(ctx, 0); err != nil {
+		return errors.Trace(err)
+	}
+	profiles, err := commonConfig.Profiles()
+	if err != nil {
+		return errors.Trace(err)
+	}
+	current, err := util.Profile()
+	if err != nil {
+		return errors.Trace(err)
+	}
+	for _, profile := range profiles {
+		mark := " "
+		if profile == current {
+			mark = "*"
+		}
+		fmt.Println(mark, profile)
+	}
+	return nil
+}
+
+func createProfileAction(ctx *cli.Context) {
+	if err := createProfile(ctx); err != nil {
+		util.FatalOSErr(err)
+	}
+}
+
+func createProfile(ctx *cli.Context) error {
+	if ctx.NArg() != 1 {
+		return errors.New("Error: A profile name must be specified: `lingo config profile create <name>`")
+	}
+	name := ctx.Args().First()
+	if err := commonConfig.CreateProfile(name, ctx.String("env"), ctx.String("owner")); err != nil {
+		return errors.Trace(err)
+	}
+	fmt.Printf("Created profile %s. Sign in to it with `lingo --profile %[1]s config setup`.\n", name)
+	return nil
+}
+
+func useProfileAction(ctx *cli.Context) {
+	if err := useProfile(ctx); err != nil {
+		util.FatalOSErr(err)
+	}
+}
+
+func useProfile(ctx *cli.Context) error {
+	if ctx.NArg() != 1 {
+		return errors.New("Error: A profile name must be specified: `lingo config profile use <name>`")
+	}
+	name := ctx.Args().First()
+	if err := commonConfig.UseProfile(name); err != nil {
+		return errors.Trace(err)
+	}
+	fmt.Println("Using profile", name)
+	return nil
+}
+
+func copyProfileAction(ctx *cli.Context) {
+	if err := copyProfile(ctx); err != nil {
+		util.FatalOSErr(err)
+	}
+}
+
+func copyProfile(ctx *cli.Context) error {
+	if ctx.NArg() != 2 {
+		return errors.New("Error: Two profile names must be specified: `lingo config profile copy <from> <to>`")
+	}
+	from, to := ctx.Args()[0], ctx.Args()[1]
+	if err := commonConfig.CopyProfile(from, to); err != nil {
+		return errors.Trace(err)
+	}
+	fmt.Printf("Copied profile %s to %s. Sign in to it with `lingo --profile %[2]s config setup`.\n", from, to)
+	return nil
+}
+
+func deleteProfileAction(ctx *cli.Context) {
+	if err := deleteProfile(ctx); err != nil {
+		util.FatalOSErr(err)
+	}
+}
+
+func deleteProfile(ctx *cli.Context) error {
+	if ctx.NArg() != 1 {
+		return errors.New("Error: A profile name must be specified: `lingo config profile delete <name>`")
+	}
+	name := ctx.Args().First()
+	if err := commonConfig.DeleteProfile(name); err != nil {
+		return errors.Trace(err)
+	}
+	fmt.Println("Deleted profile", name)
+	return nil
+}
@@ -8,8 +8,11 @@ import (
 	"io/ioutil"
 	"os"
 	"path/filepath"
+	"regexp"
 	"strconv"
+	"unicode/utf8"
 
+	"github.com/codelingo/lingo/app/clql"
 	"github.com/codelingo/lingo/app/util"
 	"github.com/codelingo/lingo/service"
 	codelingo "github.com/codelingo/rpc/service"
@@ -22,7 +25,14 @@ const usage = `
 lingo query-from-offset - Generate CLQL to match segment of code within a given file
  
 USAGE:
-	lingo query-from-offset <filename> <start> <end>`
+	lingo query-from-offset [--lang <lexicon>] [--format json|clql|tenet] <filename> <start> <end>
+	lingo query-from-offset [--lang <lexicon>] [--format json|clql|tenet] <filename>:<line>:<col>-<line>:<col>
+
+Start and end are byte offsets. Lines and columns start at 1, columns count
+characters and the end position is exclusive.`
+
+// rangeRegexp matches a file:line:col-line:col selection.
+var rangeRegexp = regexp.MustCompile(`^(.+):(\d+):(\d+)-(\d+):(\d+)$`)
 
 func queryFromOffsetAction(ctx *cli.Context) {
 	err := queryFromOffset(ctx)
@@ -41,36 +51,43 @@ type genFact struct {
 
 func queryFromOffset(cliCtx *cli.Context) error {
 	badArgsErr := errors.New(usage)
-	if len(cliCtx.Args()) != 3 {
+
+	var path string
+	switch args := cliCtx.Args(); len(args) {
+	case 1:
+		m := rangeRegexp.FindStringSubmatch(args[0])
+		if m == nil {
+			return errors.Annotate(badArgsErr, "selection must be of the form <filename>:<line>:<col>-<line>:<col>")
+		}
+		path = m[1]
+	case 3:
+		path = args[0]
+	default:
 		return badArgsErr
 	}
 
-	file, err := validateFilePath(cliCtx.Args()[0])
+	file, err := validateFilePath(path)
 	if err != nil {
 		return errors.Annotate(badArgsErr, err.Error())
 	}
 
-	start, err := strconv.ParseInt(cliCtx.Args()[1], 10, 64)
+	lang, err := clql.LexiconForFile(file, cliCtx.String("lang"))
 	if err != nil {
-		return errors.Annotate(badArgsErr, "start must be an integer")
+		return errors.Annotate(badArgsErr, err.Error())
 	}
 
-	end, err := strconv.ParseInt(cliCtx.Args()[2], 10, 64)
+	contents, err := ioutil.ReadFile(file)
 	if err != nil {
-		return errors.Annotate(badArgsErr, "end must be an integer")
+		return errors.Trace(err)
 	}
 
-	if start > end {
-		return errors.Annotate(badArgsErr, "start must be smaller than end")
+	start, end, err := selectionOffsets(contents, cliCtx.Args())
+	if err != nil {
+		return errors.Annotate(badArgsErr, err.Error())
 	}
 
-	lang := filepath.Ext(file)[1:]
 	dir := filepath.Base(filepath.Dir(file))
 	filename := filepath.Base(file)
-	contents, err := ioutil.ReadFile(file)
-	if err != nil {
-		return errors.Trace(err)
-	}
 
 	ctx, _ := util.UserCancelContext(context.Background())
 	src := string(contents[:])
@@ -95,11 +112,39 @@ func queryFromOffset(cliCtx *cli.Context) error {
 		facts[i] = gFact
 	}
 
-	var buf bytes.Buffer
-	json.NewEncoder(&buf).Encode(facts)
-	content := buf.Bytes()
-	outputBytes("", content)
-	return nil
+	content, err := getQueryFormat(cliCtx, lang, facts)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	return errors.Trace(outputBytes("", content))
+}
+
+func getQueryFormat(cliCtx *cli.Context, lang string, facts []*genFact) ([]byte, error) {
+	switch format := cliCtx.String("format"); format {
+	case "json", "":
+		var buf bytes.Buffer
+		json.NewEncoder(&buf).Encode(facts)
+		return buf.Bytes(), nil
+	case "clql":
+		return []byte(clql.Render(lang, clqlFacts(facts))), nil
+	case "tenet":
+		query := clql.Render(lang, clqlFacts(facts))
+		return []byte(clql.RenderTenet(cliCtx.String("tenet-name"), cliCtx.String("comment"), query)), nil
+	default:
+		return nil, errors.Errorf("unknown format %q", format)
+	}
+}
+
+func clqlFacts(facts []*genFact) []*clql.Fact {
+	cFacts := make([]*clql.Fact, len(facts))
+	for i, fact := range facts {
+		cFacts[i] = &clql.Fact{
+			Name:       fact.FactName,
+			Properties: fact.Properties,
+			Children:   clqlFacts(fact.Children),
+		}
+	}
+	return cFacts
 }
 
 // buildGenFact builds a new genFact from the codelingo.GenFact.
@@ -113,7 +158,7 @@ func buildGenFact(cliCtx *cli.Context, fact *codelingo.GenFact) (*genFact, error
 	if cliCtx.Bool("all-properties") || (cliCtx.Bool("final-fact-properties") && len(fact.Children) == 0) {
 		gFact.Properties = make(map[string]interface{})
 		for name, prop := range fact.Properties {
-			iProp, err := genPropToInterface(prop)
+			iProp, err := clql.PropertyValue(prop)
 			if err != nil {
 				return nil, errors.Trace(err)
 			}
@@ -132,18 +177,86 @@ func buildGenFact(cliCtx *cli.Context, fact *codelingo.GenFact) (*genFact, error
 	return gFact, nil
 }
 
-func genPropToInterface(property *codelingo.GenProperty) (interface{}, error) {
-	switch val := property.Value.(type) {
-	case *codelingo.GenProperty_Int:
-		return val.Int, nil
-	case *codelingo.GenProperty_Float:
-		return val.Float, nil
-	case *codelingo.GenProperty_Bool:
-		return val.Bool, nil
-	case *codelingo.GenProperty_String_:
-		return val.String_, nil
-	}
-	return nil, errors.Errorf("unknown property type %T", property.Value)
+// selectionOffsets returns the byte offsets of the selection given in args,
+// which is either a filename with a start and end offset, or a single
+// file:line:col-line:col range.
+func selectionOffsets(src []byte, args []string) (int64, int64, error) {
+	var start, end int64
+	if len(args) == 1 {
+		m := rangeRegexp.FindStringSubmatch(args[0])
+		if m == nil {
+			return 0, 0, errors.Errorf("invalid selection %q", args[0])
+		}
+		pos := make([]int, 4)
+		for i := range pos {
+			n, err := strconv.Atoi(m[i+2])
+			if err != nil {
+				return 0, 0, errors.Trace(err)
+			}
+			pos[i] = n
+		}
+
+		var err error
+		start, err = lineColToOffset(src, pos[0], pos[1])
+		if err != nil {
+			return 0, 0, errors.Annotate(err, "invalid start")
+		}
+		end, err = lineColToOffset(src, pos[2], pos[3])
+		if err != nil {
+			return 0, 0, errors.Annotate(err, "invalid end")
+		}
+	} else {
+		var err error
+		start, err = strconv.ParseInt(args[1], 10, 64)
+		if err != nil {
+			return 0, 0, errors.New("start must be an integer")
+		}
+		end, err = strconv.ParseInt(args[2], 10, 64)
+		if err != nil {
+			return 0, 0, errors.New("end must be an integer")
+		}
+	}
+
+	switch {
+	case start > end:
+		return 0, 0, errors.New("start must be smaller than end")
+	case start < 0:
+		return 0, 0, errors.New("start must not be negative")
+	case end > int64(len(src)):
+		return 0, 0, errors.Errorf("end %d is past the end of the file (%d bytes)", end, len(src))
+	}
+	return start, end, nil
+}
+
+// lineColToOffset converts a 1-based line and column to a byte offset in
+// src. Columns count UTF-8 encoded characters rather than bytes. The column
+// may point one past the last character of the line.
+func lineColToOffset(src []byte, line, col int) (int64, error) {
+	if line < 1 || col < 1 {
+		return 0, errors.Errorf("line and column must be greater than 0, got %d:%d", line, col)
+	}
+
+	offset := 0
+	for l := 1; l < line; l++ {
+		i := bytes.IndexByte(src[offset:], '\n')
+		if i < 0 {
+			return 0, errors.Errorf("line %d is past the end of the file", line)
+		}
+		offset += i + 1
+	}
+
+	lineEnd := len(src)
+	if i := bytes.IndexByte(src[offset:], '\n'); i >= 0 {
+		lineEnd = offset + i
+	}
+	for c := 1; c < col; c++ {
+		if offset >= lineEnd {
+			return 0, errors.Errorf("column %d is past the end of line %d", col, line)
+		}
+		_, size := utf8.DecodeRune(src[offset:lineEnd])
+		offset += size
+	}
+	return int64(offset), nil
 }
 
 func validateFilePath(path string) (string, error) {
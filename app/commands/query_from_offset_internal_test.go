@@ -0,0 +1,76 @@
+package commands
+
+import (
+	"testing"
+)
+
+func TestLineColToOffset(t *testing.T) {
+	src := []byte("package main\n\n// héllo wörld\nfunc main() {}\n")
+
+	cases := []struct {
+		line, col int
+		expected  int64
+		err       bool
+	}{
+		{line: 1, col: 1, expected: 0},
+		{line: 1, col: 13, expected: 12},
+		{line: 3, col: 5, expected: 18},
+		// Multi-byte characters count as a single column.
+		{line: 3, col: 6, expected: 20},
+		{line: 3, col: 13, expected: 28},
+		{line: 4, col: 1, expected: 31},
+		{line: 1, col: 14, err: true},
+		{line: 6, col: 1, err: true},
+		{line: 0, col: 1, err: true},
+	}
+
+	for _, c := range cases {
+		offset, err := lineColToOffset(src, c.line, c.col)
+		if c.err {
+			if err == nil {
+				t.Errorf("%d:%d: expected an error, got offset %d", c.line, c.col, offset)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%d:%d: unexpected error: %v", c.line, c.col, err)
+			continue
+		}
+		if offset != c.expected {
+			t.Errorf("%d:%d: want %d, got %d", c.line, c.col, c.expected, offset)
+		}
+	}
+}
+
+func TestSelectionOffsets(t *testing.T) {
+	src := []byte("package main\n\nfunc main() {}\n")
+
+	cases := []struct {
+		args       []string
+		start, end int64
+		err        bool
+	}{
+		{args: []string{"main.go:3:1-3:15"}, start: 14, end: 28},
+		{args: []string{"main.go", "14", "28"}, start: 14, end: 28},
+		{args: []string{"main.go:3:15-3:1"}, err: true},
+		{args: []string{"main.go", "0", "100"}, err: true},
+		{args: []string{"main.go", "-1", "3"}, err: true},
+	}
+
+	for _, c := range cases {
+		start, end, err := selectionOffsets(src, c.args)
+		if c.err {
+			if err == nil {
+				t.Errorf("%v: expected an error", c.args)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%v: unexpected error: %v", c.args, err)
+			continue
+		}
+		if start != c.start || end != c.end {
+			t.Errorf("%v: want %d-%d, got %d-%d", c.args, c.start, c.end, start, end)
+		}
+	}
+}
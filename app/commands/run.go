@@ -1,37 +1,75 @@
 package commands
 
 import (
+	"context"
+	"encoding/json"
 	"fmt"
+	"io/ioutil"
 	"os"
 	"os/exec"
-	"runtime"
+	"path/filepath"
 	"strings"
+	"syscall"
+	"time"
 
+	"github.com/codelingo/lingo/app/action"
 	"github.com/codelingo/lingo/app/commands/verify"
+	"github.com/codelingo/lingo/app/sandbox"
 	"github.com/codelingo/lingo/app/util"
+	"github.com/codelingo/lingo/app/util/common/config"
+	"github.com/codelingo/lingo/vcs"
 	"github.com/urfave/cli"
+	"golang.org/x/crypto/ssh/terminal"
 
 	"github.com/juju/errors"
 )
 
 func init() {
 	register(&cli.Command{
-		Name:            "run",
-		Usage:           "Run the given Action in the current directory.",
+		Name:      "run",
+		Usage:     "Run the given Action in the current directory.",
+		ArgsUsage: "[--format text|json] [--sandbox] [--timeout duration] [owner/]name [args...]",
+		Description: `If no owner is given, the Action is found among the installed Actions.
+   When more than one owner provides it, the first owner listed in
+   actions.owners in platform.yaml is used, e.g. "actions.owners: acme,codelingo".
+   By default, Actions owned by codelingo are preferred.
+
+   The Action is given a JSON description of the repository, platform and
+   user, and may write back results for lingo to render as text or forward
+   as JSON with --format json. See doc/ACTIONS.md for the contract.
+
+   With --sandbox, or actions.sandbox: true in platform.yaml, the Action is
+   isolated on Linux: it gets a scrubbed environment, read-only access to
+   the repository, a private /tmp, no network and limited CPU time and
+   memory, unless its lingo_action.yaml asks for more.
+
+   With --timeout, or actions.timeout in platform.yaml, e.g. "30m", the Action
+   is sent SIGTERM once it has run for that long. Interrupting lingo sends
+   the Action the same signal. An Action which hasn't exited 10 seconds
+   later is killed. lingo exits with the Action's exit status.`,
 		Action:          runAction,
 		SkipFlagParsing: true,
-	}, false, false, verify.VersionRq)
+		BashComplete:    completeInstalledActions,
+	}, false, false, verify.VersionRq, verify.HomeRq, verify.ConfigRq)
 }
 
 func runAction(ctx *cli.Context) {
 	if err := run(ctx); err != nil {
+		if exitErr, ok := errors.Cause(err).(*actionExitError); ok && exitErr.code > 0 {
+			util.UserFacingError(err)
+			util.Exiter(exitErr.code)
+			return
+		}
 		util.FatalOSErr(err)
 		return
 	}
 }
 
 func run(c *cli.Context) error {
-	args := c.Args()
+	flags, args, err := parseRunFlags(c.Args())
+	if err != nil {
+		return errors.Trace(err)
+	}
 	if len(args) == 0 {
 		return errors.New("Failed to run Action - no Action given.")
 	}
@@ -46,28 +84,282 @@ func run(c *cli.Context) error {
 		return errors.Errorf("Action %[1]q not found. Try installing it with `lingo install %[1]s`", flowName)
 	}
 
-	var strArgs []string
-	for _, arg := range args[1:] {
-		strArgs = append(strArgs, arg)
+	pCfg, err := config.Platform()
+	if err != nil {
+		return errors.Trace(err)
+	}
+	if !flags.sandbox {
+		if flags.sandbox, err = pCfg.ActionsSandbox(); err != nil {
+			return errors.Trace(err)
+		}
+	}
+	if flags.timeout == 0 {
+		if flags.timeout, err = pCfg.ActionsTimeout(); err != nil {
+			return errors.Trace(err)
+		}
+	}
+
+	actionCtx, err := actionContext()
+	if err != nil {
+		return errors.Trace(err)
+	}
+	dir, err := ioutil.TempDir("", "lingo-run-")
+	if err != nil {
+		return errors.Trace(err)
+	}
+	defer os.RemoveAll(dir)
+	contextPath, err := action.WriteContext(dir, actionCtx)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	resultsPath := filepath.Join(dir, "results.json")
+	env := []string{
+		action.ContextEnv + "=" + contextPath,
+		action.ResultsEnv + "=" + resultsPath,
+		fmt.Sprintf("%s=%d", action.ContractVersionEnv, action.ContractVersion),
+	}
+
+	var cmd *exec.Cmd
+	var box *sandbox.Config
+	if flags.sandbox {
+		spec, err := action.LoadSpec(filepath.Dir(cmdPath))
+		if err != nil {
+			return errors.Trace(err)
+		}
+		repoRoot := actionCtx.RepoRoot
+		if repoRoot == "" {
+			repoRoot = actionCtx.WorkingDir
+		}
+		box = &sandbox.Config{
+			Path:     cmdPath,
+			Args:     args[1:],
+			Env:      append(sandbox.ScrubEnv(os.Environ(), spec.Permissions.Env), env...),
+			Dir:      actionCtx.WorkingDir,
+			ReadOnly: []string{repoRoot, filepath.Dir(cmdPath)},
+			Shared:   []string{dir},
+			Network:  spec.Permissions.Network,
+			CPU:      spec.CPULimit(),
+			Memory:   spec.MemoryLimit(),
+		}
+		if cmd, err = sandbox.Command(box); err != nil {
+			return errors.Trace(err)
+		}
+	} else {
+		cmd = exec.Command(cmdPath, args[1:]...)
+		cmd.Env = append(os.Environ(), env...)
+	}
+
+	ctx := context.Background()
+	if flags.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, flags.timeout)
+		defer cancel()
+	}
+	code, stopped, err := runFlowCmd(ctx, cmd)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	if stopped != nil {
+		if ctx.Err() == context.DeadlineExceeded {
+			return &actionExitError{errors.Errorf("Action %s timed out after %s.", flowName, flags.timeout), code}
+		}
+		return &actionExitError{errors.Errorf("Action %s was stopped by %v.", flowName, stopped), code}
+	}
+	if box != nil && code != action.ExitOK {
+		if err := sandbox.Violation(box, cmd.ProcessState); err == sandbox.ErrSetupFailed {
+			return errors.Trace(err)
+		} else if err != nil {
+			return &actionExitError{errors.Errorf("Action %s violated its sandbox: %v. Actions declare the limits they need in their %s.", flowName, err, action.SpecFile), code}
+		}
+	}
+	if status, ok := cmd.ProcessState.Sys().(syscall.WaitStatus); ok && status.Signaled() {
+		err := errors.Errorf("Action %s was killed by %v.", flowName, status.Signal())
+		if box != nil {
+			err = errors.Errorf("%v\nIt was run in a sandbox with %s. Actions declare the limits they need in their %s.", err, box.Describe(), action.SpecFile)
+		}
+		return &actionExitError{err, code}
+	}
+
+	results, err := action.ReadResults(resultsPath)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	if results != nil {
+		if flags.format == "json" {
+			if err := json.NewEncoder(os.Stdout).Encode(results); err != nil {
+				return errors.Trace(err)
+			}
+		} else {
+			results.Render(os.Stdout)
+		}
+	}
+
+	if code == action.ExitIssues && results != nil && len(results.Issues) > 0 {
+		return &actionExitError{errors.Errorf("Action %s found %d issue(s).", flowName, len(results.Issues)), code}
+	}
+	err = action.ExitError(flowName, code)
+	if err != nil && box != nil && code != action.ExitIssues && code != action.ExitUsage {
+		// The Action may have failed because the sandbox denied it something.
+		err = errors.Errorf("%v\nIt was run in a sandbox with %s. Actions declare the permissions they need in their %s.", err, box.Describe(), action.SpecFile)
 	}
+	if err != nil {
+		return &actionExitError{err, code}
+	}
+	return nil
+}
 
-	return errors.Trace(runFlowCmd(cmdPath, strArgs))
+// actionExitError is the failure of an Action, after which lingo exits with
+// the Action's exit status.
+type actionExitError struct {
+	error
+	code int
 }
 
-func runFlowCmd(flowCmd string, args []string) error {
-	cmd := exec.Command(flowCmd, args...)
+// runFlags are lingo's own flags to `lingo run`.
+type runFlags struct {
+	// format is the format results are written in: text or json.
+	format  string
+	sandbox bool
+	// timeout is how long the Action may run for, or zero for no limit.
+	timeout time.Duration
+}
+
+// parseRunFlags splits lingo's own flags, which come before the Action's
+// name, from the Action's name and arguments.
+func parseRunFlags(args []string) (*runFlags, []string, error) {
+	flags := &runFlags{format: "text"}
+	for len(args) > 0 && strings.HasPrefix(args[0], "-") {
+		flag := strings.TrimLeft(args[0], "-")
+		args = args[1:]
+
+		if flag == "sandbox" {
+			flags.sandbox = true
+			continue
+		}
+
+		value := ""
+		if i := strings.Index(flag, "="); i >= 0 {
+			flag, value = flag[:i], flag[i+1:]
+		} else if len(args) > 0 {
+			value, args = args[0], args[1:]
+		}
+		switch flag {
+		case "format":
+			if value != "text" && value != "json" {
+				return nil, nil, errors.Errorf("Failed to run Action - --format must be text or json, not %q.", value)
+			}
+			flags.format = value
+		case "timeout":
+			timeout, err := time.ParseDuration(value)
+			if err != nil || timeout <= 0 {
+				return nil, nil, errors.Errorf("Failed to run Action - --timeout must be a duration such as 10m, not %q.", value)
+			}
+			flags.timeout = timeout
+		default:
+			return nil, nil, errors.Errorf("Failed to run Action - unknown flag %q.", "--"+flag)
+		}
+	}
+	return flags, args, nil
+}
+
+// runFlowCmd runs an Action's command until it exits or ctx is done,
+// returning its exit status and the signal it was stopped with, if any.
+func runFlowCmd(ctx context.Context, cmd *exec.Cmd) (int, os.Signal, error) {
 	cmd.Stderr = os.Stderr
-	cmd.Stdin = os.Stdin
 	cmd.Stdout = os.Stdout
-	return errors.Trace(cmd.Run())
+	// The Action runs in the background of lingo's terminal, so it would be
+	// stopped if it read from it.
+	if !terminal.IsTerminal(int(os.Stdin.Fd())) {
+		cmd.Stdin = os.Stdin
+	}
+	stopped, err := action.Run(ctx, cmd, action.GracePeriod)
+	if err != nil {
+		return 0, nil, errors.Trace(err)
+	}
+	return action.ExitStatus(cmd.ProcessState), stopped, nil
 }
 
-func findInstalledCmd(name string) (string, error) {
+// actionContext describes the environment an Action is run in. Only the
+// working directory is required; everything else is left empty if it can't
+// be found, e.g. outside of a repository or before `lingo config setup`.
+func actionContext() (*action.Context, error) {
+	cwd, err := os.Getwd()
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	ctx := &action.Context{
+		Version:    action.ContractVersion,
+		WorkingDir: cwd,
+		Dotlingos:  []string{},
+	}
+
+	searchDir := cwd
+	if vcsType, repo, err := vcs.New(); err == nil {
+		if ctx.VCS, err = vcs.TypeToString(vcsType); err != nil {
+			return nil, errors.Trace(err)
+		}
+		if root, ok := repoRoot(repo, cwd); ok {
+			ctx.RepoRoot = root
+			searchDir = root
+		}
+		if sha, err := repo.CurrentCommitId(); err == nil {
+			ctx.Commit = sha
+		}
+	}
+
+	dotlingos, err := absDotlingoFiles(searchDir)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	ctx.Dotlingos = append(ctx.Dotlingos, dotlingos...)
+
+	if pCfg, err := config.Platform(); err == nil {
+		ctx.Platform.Website, _ = pCfg.WebSiteAddress()
+		ctx.Platform.Platform, _ = pCfg.PlatformAddress()
+		ctx.Platform.Flow, _ = pCfg.FlowAddress()
+	}
+	if authCfg, err := config.Auth(); err == nil {
+		ctx.Auth.Username, _ = authCfg.GetGitUserName()
+		ctx.Auth.Token, _ = authCfg.GetGitUserPassword()
+	}
+	return ctx, nil
+}
 
-	// TODO(waigani) we should cycle through all installed owners if owner is
-	// not specified.
-	owner := "codelingo"
+// repoRoot returns the root of repo, given the working directory cwd inside
+// it.
+func repoRoot(repo vcs.Repo, cwd string) (string, bool) {
+	prefix, err := repo.WorkingDir()
+	if err != nil || filepath.IsAbs(prefix) {
+		return "", false
+	}
+	return filepath.Clean(strings.TrimSuffix(filepath.ToSlash(cwd), strings.TrimSuffix(prefix, "/"))), true
+}
+
+// absDotlingoFiles returns the absolute paths of the codelingo.yaml files
+// under dir.
+func absDotlingoFiles(dir string) ([]string, error) {
+	dotlingos, err := findDotlingoFiles([]string{dir})
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	for i, dotlingo := range dotlingos {
+		if dotlingos[i], err = filepath.Abs(dotlingo); err != nil {
+			return nil, errors.Trace(err)
+		}
+	}
+	return dotlingos, nil
+}
+
+// findInstalledCmd returns the path of an installed Action's command. If
+// the name has no owner, it is resolved from the installed Actions.
+func findInstalledCmd(name string) (string, error) {
+	home, err := util.LingoHome()
+	if err != nil {
+		return "", errors.Trace(err)
+	}
+	flowsDir := filepath.Join(home, "flows")
 
+	var owner string
 	parts := strings.Split(name, "/")
 	switch {
 	case len(parts) == 2:
@@ -75,17 +367,85 @@ func findInstalledCmd(name string) (string, error) {
 		name = parts[1]
 	case len(parts) > 2:
 		return "", errors.Errorf("%q is not a valid Action name", name)
+	default:
+		order, err := actionOwners()
+		if err != nil {
+			return "", errors.Trace(err)
+		}
+		owner, err = action.ResolveOwner(flowsDir, name, order)
+		if err != nil {
+			return "", errors.Trace(err)
+		}
 	}
 
-	home, err := util.LingoHome()
+	return filepath.Join(flowsDir, owner, name, "cmd"+cmdExt()), nil
+}
+
+// actionOwners returns the owners to prefer when an Action is named without
+// one. Actions owned by the default owner, then codelingo, are preferred
+// unless configured otherwise.
+func actionOwners() ([]string, error) {
+	pCfg, err := config.Platform()
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	owners, err := pCfg.ActionsOwners()
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	if len(owners) > 0 {
+		return owners, nil
+	}
+	owner, err := pCfg.DefaultOwner()
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	if owner != "" && owner != "codelingo" {
+		return []string{owner, "codelingo"}, nil
+	}
+	return []string{"codelingo"}, nil
+}
+
+// ownerFlag returns the owner given with --owner, else the default owner of
+// the profile, else codelingo.
+func ownerFlag(c *cli.Context) (string, error) {
+	if c.IsSet("owner") {
+		return c.String("owner"), nil
+	}
+	pCfg, err := config.Platform()
 	if err != nil {
 		return "", errors.Trace(err)
 	}
+	owner, err := pCfg.DefaultOwner()
+	if err != nil || owner != "" {
+		return owner, errors.Trace(err)
+	}
+	return "codelingo", nil
+}
 
-	ext := ""
-	if runtime.GOOS == "windows" {
-		ext = ".exe"
+// completeInstalledActions prints the installed Actions for shell
+// completion: their full names, and their bare names where unambiguous.
+func completeInstalledActions(c *cli.Context) {
+	if c.NArg() > 0 {
+		return
+	}
+	home, err := util.LingoHome()
+	if err != nil {
+		return
+	}
+	names, err := action.InstalledNames(filepath.Join(home, "flows"))
+	if err != nil {
+		return
 	}
 
-	return fmt.Sprintf("%s/flows/%s/%s/cmd%s", home, owner, name, ext), nil
+	owners := make(map[string]int)
+	for _, name := range names {
+		owners[strings.SplitN(name, "/", 2)[1]]++
+	}
+	for _, name := range names {
+		fmt.Println(name)
+		if bare := strings.SplitN(name, "/", 2)[1]; owners[bare] == 1 {
+			fmt.Println(bare)
+		}
+	}
 }
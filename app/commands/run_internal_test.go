@@ -0,0 +1,77 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"testing"
+)
+
+func TestParseRunFlags(t *testing.T) {
+	cases := []struct {
+		args     []string
+		format   string
+		sandbox  bool
+		expected []string
+		err      string
+	}{
+		{args: []string{"review", "--format", "json"}, format: "text", expected: []string{"review", "--format", "json"}},
+		{args: []string{"--format", "json", "review", "-v"}, format: "json", expected: []string{"review", "-v"}},
+		{args: []string{"--format=text", "acme/lint"}, format: "text", expected: []string{"acme/lint"}},
+		{args: []string{"--sandbox", "--format", "json", "review"}, format: "json", sandbox: true, expected: []string{"review"}},
+		{args: []string{"--format", "xml", "review"}, err: `Failed to run Action - --format must be text or json, not "xml".`},
+		{args: []string{"--timeout", "soon", "review"}, err: `Failed to run Action - --timeout must be a duration such as 10m, not "soon".`},
+		{args: []string{"--verbose", "review"}, err: `Failed to run Action - unknown flag "--verbose".`},
+	}
+
+	for _, c := range cases {
+		flags, args, err := parseRunFlags(c.args)
+		if c.err != "" {
+			if err == nil || err.Error() != c.err {
+				t.Errorf("parseRunFlags(%v): expected error %q, got %v", c.args, c.err, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("parseRunFlags(%v): unexpected error: %v", c.args, err)
+			continue
+		}
+		if flags.format != c.format || flags.sandbox != c.sandbox || !reflect.DeepEqual(args, c.expected) {
+			t.Errorf("parseRunFlags(%v) = %+v, %v, expected %q, %v, %v", c.args, *flags, args, c.format, c.sandbox, c.expected)
+		}
+	}
+}
+
+func TestRunFlowCmdExitCode(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("Actions are shell scripts in this test")
+	}
+	dir, err := ioutil.TempDir("", "lingo-run-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	cmd := filepath.Join(dir, "cmd")
+	script := "#!/bin/sh\ntest \"$LINGO_CONTEXT\" = /tmp/context.json || exit 1\nexit $1\n"
+	if err := ioutil.WriteFile(cmd, []byte(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, expected := range []int{0, 3, 42} {
+		flowCmd := exec.Command(cmd, fmt.Sprint(expected))
+		flowCmd.Env = []string{"LINGO_CONTEXT=/tmp/context.json"}
+		code, stopped, err := runFlowCmd(context.Background(), flowCmd)
+		if err != nil || stopped != nil || code != expected {
+			t.Errorf("runFlowCmd exiting %d = %d, %v", expected, code, err)
+		}
+	}
+
+	if _, _, err := runFlowCmd(context.Background(), exec.Command(filepath.Join(os.TempDir(), "missing-cmd"))); err == nil {
+		t.Error("runFlowCmd of a missing command succeeded")
+	}
+}
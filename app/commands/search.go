@@ -0,0 +1,208 @@
+package commands
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/codelingo/lingo/app/action"
+	"github.com/codelingo/lingo/app/commands/verify"
+	"github.com/codelingo/lingo/app/discovery"
+	"github.com/codelingo/lingo/app/util"
+	"github.com/codelingo/lingo/app/util/common/config"
+	"github.com/codelingo/lingo/vcs"
+	"github.com/juju/errors"
+	"github.com/urfave/cli"
+)
+
+func init() {
+	register(&cli.Command{
+		Name:      "search",
+		Usage:     "Search the published Tenets, bundles, Bots, Actions and Lexicons",
+		ArgsUsage: "[term]",
+		Description: `Lists everything published in the discovery index whose name, description
+   or tags contain the term, ignoring case. Without a term, everything matching
+   the flags is listed.
+
+   The index is read from the discovery.root set in platform.yaml for the
+   current environment, or from CodeLingo's index if it isn't set. Index
+   files are cached in $LINGO_HOME/cache/discovery for a day. Use --refresh
+   to read them again.`,
+		Action: searchAction,
+		Flags: append([]cli.Flag{
+			cli.BoolFlag{
+				Name:  util.InstalledFlg.String(),
+				Usage: "Only search what the current project uses and the installed Actions",
+			},
+			cli.StringFlag{
+				Name:  util.OwnerFlg.String(),
+				Usage: "Only show items of the given owner",
+			},
+			cli.StringSliceFlag{
+				Name:  util.TypeFlg.String(),
+				Usage: "Only show items of the given type: tenet, bundle, bot, action or lexicon. May be repeated",
+			},
+			cli.StringSliceFlag{
+				Name:  util.TagsFlg.String(),
+				Usage: "Only show items with the given tag. May be repeated",
+			},
+		}, discoveryFlags()...),
+	}, false, false, verify.HomeRq, verify.ConfigRq)
+}
+
+// discoveryFlags are the flags of the commands listing items from the
+// discovery index.
+func discoveryFlags() []cli.Flag {
+	return []cli.Flag{
+		cli.StringFlag{
+			Name:  util.FormatFlg.String(),
+			Value: discovery.FormatTable,
+			Usage: "The format of the output: table or json",
+		},
+		cli.BoolFlag{
+			Name:  util.RefreshFlg.String(),
+			Usage: "Read the discovery index again instead of using the cached copy",
+		},
+	}
+}
+
+func searchAction(ctx *cli.Context) {
+	err := search(ctx)
+	if err != nil {
+		util.FatalOSErr(err)
+		return
+	}
+}
+
+func search(ctx *cli.Context) error {
+	if ctx.NArg() > 1 {
+		return errors.New("expected at most one search term")
+	}
+
+	var kinds []discovery.Kind
+	for _, typ := range ctx.StringSlice(util.TypeFlg.Long) {
+		for _, name := range strings.Split(typ, ",") {
+			kind, ok := discovery.ParseKind(strings.TrimSpace(name))
+			if !ok {
+				return errors.Errorf("unknown type %q, expected tenet, bundle, bot, action or lexicon", name)
+			}
+			kinds = append(kinds, kind)
+		}
+	}
+
+	return errors.Trace(listDiscovered(ctx, &discovery.Query{
+		Term:  ctx.Args().First(),
+		Kinds: kinds,
+		Owner: ctx.String(util.OwnerFlg.Long),
+		Tags:  ctx.StringSlice(util.TagsFlg.Long),
+	}))
+}
+
+// listDiscovered prints the items in the discovery index matching q or,
+// with --installed, those used by the current project.
+func listDiscovered(ctx *cli.Context, q *discovery.Query) error {
+	format := ctx.String(util.FormatFlg.Long)
+	if format != discovery.FormatTable && format != discovery.FormatJSON {
+		return errors.Errorf("unknown format %q, expected table or json", format)
+	}
+
+	get := discoveryIndex
+	if ctx.Bool(util.InstalledFlg.Long) {
+		get = installedIndex
+	}
+	ix, err := get(ctx, q.Kinds...)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	return errors.Trace(discovery.Render(os.Stdout, ix.Search(q), format))
+}
+
+// installedIndex returns the items of the given kinds the current project
+// uses: the Tenets, bundles, Bots and Lexicons of its codelingo.yaml files
+// and their imports, and the installed Actions.
+func installedIndex(ctx *cli.Context, kinds ...discovery.Kind) (*discovery.Index, error) {
+	project, actions := len(kinds) == 0, len(kinds) == 0
+	for _, kind := range kinds {
+		if kind == discovery.KindAction {
+			actions = true
+		} else {
+			project = true
+		}
+	}
+
+	ix := &discovery.Index{}
+	if project {
+		cwd, err := os.Getwd()
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		if _, repo, err := vcs.New(); err == nil {
+			if root, ok := repoRoot(repo, cwd); ok {
+				cwd = root
+			}
+		}
+		dotlingos, err := absDotlingoFiles(cwd)
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		src, err := discoverySource(ctx.Bool(util.RefreshFlg.Long))
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		if ix, err = discovery.Project(dotlingos, src); err != nil {
+			return nil, errors.Annotate(err, "failed to read the project's codelingo.yaml files")
+		}
+	}
+
+	if !actions {
+		return ix, nil
+	}
+	manifest, err := actionsManifest()
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	for _, installed := range manifest.Actions {
+		item := &discovery.Item{
+			Kind:    discovery.KindAction,
+			Owner:   installed.Owner,
+			Name:    installed.Name,
+			Version: installed.Version,
+		}
+		spec, err := action.LoadSpec(filepath.Join(filepath.Dir(manifest.Path()), installed.Owner, installed.Name))
+		if err == nil {
+			item.Description = spec.Description
+		}
+		ix.Items = append(ix.Items, item)
+	}
+	return ix, nil
+}
+
+// discoveryIndex reads the items of the given kinds from the discovery
+// index.
+func discoveryIndex(ctx *cli.Context, kinds ...discovery.Kind) (*discovery.Index, error) {
+	src, err := discoverySource(ctx.Bool(util.RefreshFlg.Long))
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	ix, err := discovery.Load(src, kinds...)
+	return ix, errors.Annotate(err, "failed to read the discovery index")
+}
+
+// discoverySource returns the Source of the discovery index configured for
+// the current environment, whose files are cached in
+// $LINGO_HOME/cache/discovery.
+func discoverySource(refresh bool) (discovery.Source, error) {
+	pCfg, err := config.Platform()
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	root, err := pCfg.DiscoveryRoot()
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	home, err := util.LingoHome()
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	return discovery.Open(root, filepath.Join(home, "cache", "discovery"), refresh)
+}
@@ -1,10 +1,7 @@
 package commands
 
 import (
-	"fmt"
-	"io/ioutil"
-	"net/http"
-
+	"github.com/codelingo/lingo/app/discovery"
 	"github.com/codelingo/lingo/app/util"
 	"github.com/juju/errors"
 	"github.com/urfave/cli"
@@ -15,7 +12,7 @@ func init() {
 		Name:   "tenets",
 		Usage:  "List Tenets",
 		Action: listTenetsAction,
-		Flags: []cli.Flag{
+		Flags: append([]cli.Flag{
 			cli.BoolFlag{
 				Name:  util.InstalledFlg.String(),
 				Usage: "Only show installed Tenets",
@@ -32,7 +29,7 @@ func init() {
 				Name:  util.BundleFlg.String(),
 				Usage: "List all Tenets of the given bundle",
 			},
-		},
+		}, discoveryFlags()...),
 	}, false, true)
 }
 
@@ -45,47 +42,10 @@ func listTenetsAction(ctx *cli.Context) {
 }
 
 func listTenets(ctx *cli.Context) error {
-	owner := ctx.String("owner")
-	bundle := ctx.String("bundle")
-	name := ctx.String("name")
-
-	baseTenetURL := baseDiscoveryURL + "tenets"
-	url := baseTenetURL + "/lingo_tenets.yaml"
-	switch {
-	case name != "":
-
-		if owner == "" {
-			return errors.New("owner flag must be set")
-		}
-
-		if bundle == "" {
-			return errors.New("bundle flag must be set")
-		}
-		url = fmt.Sprintf("%s/%s/%s/%s/codelingo.yaml",
-			baseTenetURL, owner, bundle, name)
-	case bundle != "":
-		if owner == "" {
-			return errors.New("owner flag must be set")
-		}
-		url = fmt.Sprintf("%s/%s/%s/lingo_bundle.yaml",
-			baseTenetURL, owner, bundle)
-
-	case owner != "":
-		url = fmt.Sprintf("%s/%s/lingo_owner.yaml",
-			baseTenetURL, owner)
-	}
-	resp, err := http.Get(url)
-	if err != nil {
-		return errors.Trace(err)
-	}
-
-	defer resp.Body.Close()
-	data, err := ioutil.ReadAll(resp.Body)
-	if err != nil {
-		return errors.Trace(err)
-	}
-
-	fmt.Println(string(data))
-
-	return nil
+	return errors.Trace(listDiscovered(ctx, &discovery.Query{
+		Kinds:  []discovery.Kind{discovery.KindTenet},
+		Owner:  ctx.String(util.OwnerFlg.Long),
+		Bundle: ctx.String(util.BundleFlg.Long),
+		Name:   ctx.String(util.NameFlg.Long),
+	}))
 }
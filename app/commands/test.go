@@ -0,0 +1,223 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+
+	"github.com/codelingo/lingo/app/commands/verify"
+	"github.com/codelingo/lingo/app/dotlingo"
+	"github.com/codelingo/lingo/app/tenettest"
+	"github.com/codelingo/lingo/app/util"
+	"github.com/codelingo/lingo/service"
+	"github.com/codelingo/lingo/vcs"
+	"github.com/codelingo/rpc/flow"
+	"github.com/juju/errors"
+	"github.com/urfave/cli"
+)
+
+func init() {
+	register(&cli.Command{
+		Name:  "test",
+		Usage: "Test the Tenets in the given codelingo.yaml files or directories against their fixtures.",
+		Description: `Fixtures are the files in the testdata directory next to a codelingo.yaml.
+   Mark each line a Tenet should match with a comment containing
+   "lingo:expect <tenet-name>". A marker on a line of its own applies to the
+   next line.`,
+		ArgsUsage: "[paths...]",
+		Action:    testAction,
+		Flags: []cli.Flag{
+			cli.StringFlag{
+				Name:  "runner",
+				Usage: "Run the Tenets with a local command instead of the platform. The command is given the codelingo.yaml path and fixture directory, and must print a JSON array of {tenet, file, line} matches.",
+			},
+			cli.BoolFlag{
+				Name:  util.InsecureFlg.String(),
+				Usage: "Allow command to run against an insecure development environment",
+			},
+		},
+		// Authentication is only needed by the platform runner, so it is
+		// verified once the runner is known.
+	}, false, false, verify.HomeRq, verify.ConfigRq)
+}
+
+func testAction(ctx *cli.Context) {
+	if err := testTenets(ctx); err != nil {
+		util.FatalOSErr(err)
+		return
+	}
+}
+
+func testTenets(c *cli.Context) error {
+	paths := []string(c.Args())
+	if len(paths) == 0 {
+		paths = []string{"."}
+	}
+
+	files, err := findDotlingoFiles(paths)
+	if err != nil {
+		return errors.Trace(err)
+	}
+
+	var runner tenettest.Runner
+	if cmd := c.String("runner"); cmd != "" {
+		runner = &tenettest.CommandRunner{Command: cmd}
+	} else {
+		if err := verify.AuthRq.Verify(); err != nil {
+			return errors.Trace(err)
+		}
+		runner = &platformRunner{insecure: c.Bool(util.InsecureFlg.Long)}
+	}
+
+	ctx, _ := util.UserCancelContext(context.Background())
+	tested, failed := 0, 0
+	for _, file := range files {
+		fixtureDir := filepath.Join(filepath.Dir(file), tenettest.FixtureDir)
+		if info, err := os.Stat(fixtureDir); err != nil || !info.IsDir() {
+			continue
+		}
+
+		results, err := testDotlingo(ctx, runner, file, fixtureDir)
+		if err != nil {
+			return errors.Annotate(err, file)
+		}
+		fmt.Println(file)
+		failed += tenettest.Report(os.Stdout, results)
+		tested += len(results)
+	}
+
+	if tested == 0 {
+		return errors.Errorf("no Tenets with fixtures were found. Add fixtures to a %s directory next to a codelingo.yaml.", tenettest.FixtureDir)
+	}
+	if failed > 0 {
+		return errors.Errorf("%d of %d Tenet(s) failed.", failed, tested)
+	}
+	fmt.Printf("All %d Tenet(s) passed.\n", tested)
+	return nil
+}
+
+func testDotlingo(ctx context.Context, runner tenettest.Runner, file, fixtureDir string) ([]*tenettest.Result, error) {
+	src, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	tenets, err := dotlingo.TenetNames(src)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+
+	expected, err := tenettest.Expectations(fixtureDir)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	known := make(map[string]bool)
+	for _, tenet := range tenets {
+		known[tenet] = true
+	}
+	for _, m := range expected {
+		if !known[m.Tenet] {
+			return nil, errors.Errorf("%s:%d expects unknown Tenet %q", m.File, m.Line, m.Tenet)
+		}
+	}
+
+	actual, err := runner.Run(ctx, file, fixtureDir)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	return tenettest.Compare(tenets, expected, actual), nil
+}
+
+// platformRunner runs Tenets by reviewing the fixtures on the CodeLingo
+// platform. The fixtures must be in the current repository.
+type platformRunner struct {
+	insecure bool
+}
+
+func (r *platformRunner) Run(ctx context.Context, dotlingoPath, fixtureDir string) ([]tenettest.Match, error) {
+	vcsType, repo, err := vcs.New()
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	if err := vcs.SyncRepo(vcsType, repo); err != nil {
+		return nil, errors.Trace(err)
+	}
+	vcsName, err := vcs.TypeToString(vcsType)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	owner, name, err := repo.OwnerAndNameFromRemote()
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	sha, err := repo.CurrentCommitId()
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	patches, err := repo.Patches()
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	workingDir, err := repo.WorkingDir()
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	hostname, err := os.Hostname()
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	dotlingo, err := ioutil.ReadFile(dotlingoPath)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+
+	// The platform expects the directory relative to the repository root.
+	absDir, err := filepath.Abs(fixtureDir)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	cwd, err := os.Getwd()
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	relDir, err := filepath.Rel(cwd, absDir)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	dir := path.Join(workingDir, filepath.ToSlash(relDir))
+
+	issues, err := service.Review(ctx, &flow.ReviewRequest{
+		Host:         "local",
+		Hostname:     hostname,
+		OwnerOrDepot: &flow.ReviewRequest_Owner{Owner: owner},
+		Repo:         name,
+		Sha:          sha,
+		Patches:      patches,
+		Vcs:          vcsName,
+		Dotlingo:     string(dotlingo),
+		Dir:          dir,
+	}, r.insecure)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+
+	var matches []tenettest.Match
+	for _, issue := range issues {
+		if issue.Discard || issue.Position == nil || issue.Position.Start == nil {
+			continue
+		}
+		start := issue.Position.Start
+		file, err := filepath.Rel(dir, start.Filename)
+		if err != nil {
+			file = start.Filename
+		}
+		matches = append(matches, tenettest.Match{
+			Tenet: issue.Name,
+			File:  filepath.ToSlash(file),
+			Line:  int(start.Line),
+		})
+	}
+	return matches, nil
+}
@@ -64,6 +64,25 @@ func init() {
 						Name:  util.InsecureFlg.String(),
 						Usage: "Allow command to run against an insecure development environment",
 					},
+					cli.StringFlag{
+						Name:  "lang",
+						Usage: "The lexicon of the file, e.g. \"go\". Detected from the file extension if not set.",
+					},
+					cli.StringFlag{
+						Name:  util.FormatFlg.Long,
+						Value: "json",
+						Usage: "The format for the output. Can be \"json\" encoded facts, a \"clql\" query or a \"tenet\" ready to append to codelingo.yaml.",
+					},
+					cli.StringFlag{
+						Name:  "tenet-name",
+						Value: "generated-tenet",
+						Usage: "The name of the Tenet when the format is \"tenet\".",
+					},
+					cli.StringFlag{
+						Name:  "comment",
+						Value: "This code matches the generated query.",
+						Usage: "The review comment of the Tenet when the format is \"tenet\".",
+					},
 				},
 			},
 		},
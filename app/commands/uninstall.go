@@ -1,9 +1,16 @@
 package commands
 
 import (
+	"bufio"
 	"fmt"
 	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
 
+	"github.com/codelingo/lingo/app/action"
+	"github.com/codelingo/lingo/app/commands/verify"
 	"github.com/codelingo/lingo/app/util"
 	"github.com/urfave/cli"
 
@@ -12,17 +19,27 @@ import (
 
 func init() {
 	register(&cli.Command{
-		Name:   "uninstall",
-		Usage:  "uninstall an Action",
+		Name:      "uninstall",
+		Usage:     "uninstall an Action",
+		ArgsUsage: "[owner/]name...",
+		Description: `The owner and name may contain wildcards, e.g. "codelingo/*" uninstalls
+   every Action owned by codelingo.`,
 		Action: uninstallAction,
 		Flags: []cli.Flag{
 			cli.StringFlag{
 				Name:  "owner",
-				Value: "codelingo",
-				Usage: "Owner of the Action",
+				Usage: "Owner of the Action. Defaults to the owner setting, or codelingo",
+			},
+			cli.BoolFlag{
+				Name:  "all",
+				Usage: "Uninstall every installed Action",
+			},
+			cli.BoolFlag{
+				Name:  "yes, y",
+				Usage: "Don't ask for confirmation",
 			},
 		},
-	}, false, false)
+	}, false, false, verify.HomeRq, verify.ConfigRq)
 }
 
 func uninstallAction(ctx *cli.Context) {
@@ -30,12 +47,14 @@ func uninstallAction(ctx *cli.Context) {
 		util.FatalOSErr(err)
 		return
 	}
-	fmt.Printf("Success! %s Action has been uninstalled.\n", ctx.Args()[0])
 }
 
 func uninstall(c *cli.Context) error {
 	args := c.Args()
-	if len(args) == 0 {
+	switch {
+	case c.Bool("all") && len(args) > 0:
+		return errors.New("Failed to uninstall Action - give either Actions or --all, not both.")
+	case !c.Bool("all") && len(args) == 0:
 		return errors.New("Failed to uninstall Action - no Action given.")
 	}
 
@@ -43,10 +62,129 @@ func uninstall(c *cli.Context) error {
 	if err != nil {
 		return errors.Trace(err)
 	}
+	flowsDir := filepath.Join(home, "flows")
+
+	manifest, err := actionsManifest()
+	if err != nil {
+		return errors.Trace(err)
+	}
+	installed, err := installedNames(manifest, flowsDir)
+	if err != nil {
+		return errors.Trace(err)
+	}
+
+	patterns := []string{"*/*"}
+	if !c.Bool("all") {
+		owner, err := ownerFlag(c)
+		if err != nil {
+			return errors.Trace(err)
+		}
+		patterns = nil
+		for _, arg := range args {
+			if !strings.Contains(arg, "/") {
+				arg = owner + "/" + arg
+			}
+			patterns = append(patterns, arg)
+		}
+	}
+
+	selected, err := matchInstalled(installed, patterns)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	if len(selected) == 0 {
+		fmt.Println("No Actions are installed.")
+		return nil
+	}
+
+	if !c.Bool("yes") {
+		fmt.Println("The following Actions will be uninstalled:")
+		for _, name := range selected {
+			fmt.Println("  -", name)
+		}
+		fmt.Print("Do you want to continue? (y/n): ")
+		input, err := bufio.NewReader(os.Stdin).ReadString('\n')
+		if err != nil || (input != "y\n" && input != "n\n") {
+			return errors.New("Invalid input")
+		}
+		if input == "n\n" {
+			fmt.Println("Uninstall cancelled.")
+			return nil
+		}
+	}
+
+	for _, name := range selected {
+		dir := filepath.Join(flowsDir, filepath.FromSlash(name))
+		if err := os.RemoveAll(dir); err != nil {
+			return errors.Trace(err)
+		}
+		if err := os.RemoveAll(action.PreviousDir(dir)); err != nil {
+			return errors.Trace(err)
+		}
+		// Remove the owner's directory if this was its last Action.
+		os.Remove(filepath.Dir(dir))
 
-	ownerName := c.String("owner")
-	flowName := args[0]
-	flowPath := fmt.Sprintf("%s/flows/%s/%s", home, ownerName, flowName)
+		parts := strings.SplitN(name, "/", 2)
+		manifest.Remove(parts[0], parts[1])
+		if err := manifest.Save(); err != nil {
+			return errors.Trace(err)
+		}
+		fmt.Printf("Success! %s Action has been uninstalled.\n", name)
+	}
+	return nil
+}
 
-	return errors.Trace(os.Remove(flowPath + "/cmd"))
+// installedNames returns the full names of the Actions listed in the
+// manifest or installed under flowsDir.
+func installedNames(manifest *action.Manifest, flowsDir string) ([]string, error) {
+	names, err := action.InstalledNames(flowsDir)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	seen := make(map[string]bool)
+	for _, name := range names {
+		seen[name] = true
+	}
+	for _, installed := range manifest.Actions {
+		if !seen[installed.FullName()] {
+			seen[installed.FullName()] = true
+			names = append(names, installed.FullName())
+		}
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// matchInstalled returns the installed Actions matching any of the patterns.
+// Every pattern must match at least one Action.
+func matchInstalled(installed, patterns []string) ([]string, error) {
+	var selected []string
+	seen := make(map[string]bool)
+	for _, pattern := range patterns {
+		if strings.Count(pattern, "/") != 1 {
+			return nil, errors.Errorf("%q is not a valid Action name", pattern)
+		}
+
+		matched := false
+		for _, name := range installed {
+			ok, err := path.Match(pattern, name)
+			if err != nil {
+				return nil, errors.Annotatef(err, "invalid pattern %q", pattern)
+			}
+			if !ok {
+				continue
+			}
+			matched = true
+			if !seen[name] {
+				seen[name] = true
+				selected = append(selected, name)
+			}
+		}
+
+		// Uninstalling everything when nothing is installed isn't an error.
+		if !matched && pattern != "*/*" {
+			return nil, errors.Errorf("Action %q is not installed", pattern)
+		}
+	}
+	return selected, nil
 }
@@ -0,0 +1,45 @@
+package commands
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestMatchInstalled(t *testing.T) {
+	installed := []string{"acme/lint", "codelingo/review", "codelingo/search"}
+
+	cases := []struct {
+		patterns []string
+		expected []string
+		err      string
+	}{
+		{patterns: []string{"codelingo/review"}, expected: []string{"codelingo/review"}},
+		{patterns: []string{"codelingo/*"}, expected: []string{"codelingo/review", "codelingo/search"}},
+		{patterns: []string{"*/lint", "acme/*"}, expected: []string{"acme/lint"}},
+		{patterns: []string{"*/*"}, expected: installed},
+		{patterns: []string{"codelingo/missing"}, err: `Action "codelingo/missing" is not installed`},
+		{patterns: []string{"review"}, err: `"review" is not a valid Action name`},
+	}
+
+	for _, c := range cases {
+		selected, err := matchInstalled(installed, c.patterns)
+		if c.err != "" {
+			if err == nil || err.Error() != c.err {
+				t.Errorf("matchInstalled(%v): expected error %q, got %v", c.patterns, c.err, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("matchInstalled(%v): unexpected error: %v", c.patterns, err)
+			continue
+		}
+		if !reflect.DeepEqual(selected, c.expected) {
+			t.Errorf("matchInstalled(%v) = %v, expected %v", c.patterns, selected, c.expected)
+		}
+	}
+
+	selected, err := matchInstalled(nil, []string{"*/*"})
+	if err != nil || len(selected) != 0 {
+		t.Errorf("matchInstalled with nothing installed = %v, %v", selected, err)
+	}
+}
@@ -0,0 +1,245 @@
+package commands
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"text/tabwriter"
+
+	"github.com/blang/semver"
+	"github.com/codelingo/lingo/app/action"
+	"github.com/codelingo/lingo/app/commands/verify"
+	"github.com/codelingo/lingo/app/util"
+	"github.com/juju/errors"
+	"github.com/urfave/cli"
+)
+
+func init() {
+	register(&cli.Command{
+		Name:   "outdated",
+		Usage:  "List the installed Actions which have a newer release",
+		Action: outdatedAction,
+		Flags: []cli.Flag{
+			cli.StringFlag{
+				Name:  "registry",
+				Usage: "URL of the registry to check, overriding actions.registry in platform.yaml",
+			},
+		},
+	}, false, false, verify.HomeRq, verify.ConfigRq)
+
+	register(&cli.Command{
+		Name:      "upgrade",
+		Usage:     "Upgrade an installed Action, or every outdated Action if none is given",
+		ArgsUsage: "[[owner/]name[@version]]",
+		Action:    upgradeAction,
+		Flags: []cli.Flag{
+			cli.StringFlag{
+				Name:  "owner",
+				Usage: "Owner of the Action. Defaults to the owner setting, or codelingo",
+			},
+			cli.StringFlag{
+				Name:  "registry",
+				Usage: "URL of the registry to upgrade from, overriding actions.registry in platform.yaml",
+			},
+			cli.BoolFlag{
+				Name:  "rollback",
+				Usage: "Restore the version the last upgrade of the Action replaced",
+			},
+		},
+	}, false, false, verify.HomeRq, verify.ConfigRq)
+}
+
+func outdatedAction(ctx *cli.Context) {
+	if err := outdated(ctx); err != nil {
+		util.FatalOSErr(err)
+		return
+	}
+}
+
+// outdatedRelease is an installed Action and its latest release.
+type outdatedRelease struct {
+	installed *action.Installed
+	latest    string
+}
+
+func outdated(c *cli.Context) error {
+	manifest, err := actionsManifest()
+	if err != nil {
+		return errors.Trace(err)
+	}
+	if len(manifest.Actions) == 0 {
+		fmt.Println("No Actions are installed.")
+		return nil
+	}
+
+	registry, err := actionRegistry(c.String("registry"))
+	if err != nil {
+		return errors.Trace(err)
+	}
+	actions, err := outdatedActions(manifest, registry)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	if len(actions) == 0 {
+		fmt.Println("All installed Actions are up to date.")
+		return nil
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ACTION\tINSTALLED\tLATEST")
+	for _, a := range actions {
+		fmt.Fprintf(w, "%s\t%s\t%s\n", a.installed.FullName(), a.installed.Version, a.latest)
+	}
+	return errors.Trace(w.Flush())
+}
+
+// outdatedActions returns the Actions in the manifest which have a release
+// newer than the installed version. Actions installed from local paths are
+// skipped.
+func outdatedActions(manifest *action.Manifest, registry *action.Registry) ([]*outdatedRelease, error) {
+	var actions []*outdatedRelease
+	for _, installed := range manifest.Actions {
+		if installed.Version == action.LocalVersion {
+			continue
+		}
+		_, releases, err := registry.Releases(installed.Owner, installed.Name)
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		latest, err := releases.Select(action.Latest)
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+
+		newer, err := isNewer(latest, installed.Version)
+		if err != nil {
+			return nil, errors.Annotatef(err, "comparing versions of %s", installed.FullName())
+		}
+		if newer {
+			actions = append(actions, &outdatedRelease{installed: installed, latest: latest})
+		}
+	}
+	return actions, nil
+}
+
+func isNewer(version, than string) (bool, error) {
+	v, err := semver.ParseTolerant(version)
+	if err != nil {
+		return false, errors.Trace(err)
+	}
+	t, err := semver.ParseTolerant(than)
+	if err != nil {
+		return false, errors.Trace(err)
+	}
+	return v.GT(t), nil
+}
+
+func upgradeAction(ctx *cli.Context) {
+	if err := upgrade(ctx); err != nil {
+		util.FatalOSErr(err)
+		return
+	}
+}
+
+func upgrade(c *cli.Context) error {
+	manifest, err := actionsManifest()
+	if err != nil {
+		return errors.Trace(err)
+	}
+	registry, err := actionRegistry(c.String("registry"))
+	if err != nil {
+		return errors.Trace(err)
+	}
+
+	if !c.Args().Present() {
+		if c.Bool("rollback") {
+			return errors.New("Failed to roll back - no Action given.")
+		}
+		actions, err := outdatedActions(manifest, registry)
+		if err != nil {
+			return errors.Trace(err)
+		}
+		if len(actions) == 0 {
+			fmt.Println("All installed Actions are up to date.")
+			return nil
+		}
+		for _, a := range actions {
+			if err := upgradeInstalled(registry, a.installed, a.latest); err != nil {
+				return errors.Trace(err)
+			}
+		}
+		return nil
+	}
+
+	ownerName, actionName, version, err := action.ParseRef(c.Args().First())
+	if err != nil {
+		return errors.Trace(err)
+	}
+	if ownerName == "" {
+		if ownerName, err = ownerFlag(c); err != nil {
+			return errors.Trace(err)
+		}
+	}
+	installed := manifest.Get(ownerName, actionName)
+	if installed == nil {
+		return errors.Errorf("Action %s/%s is not installed. Install it with `lingo install %[1]s/%[2]s`", ownerName, actionName)
+	}
+
+	if c.Bool("rollback") {
+		return errors.Trace(rollbackInstalled(manifest, installed))
+	}
+
+	if installed.Version == action.LocalVersion {
+		return errors.Errorf("%s was installed from %s. Reinstall it with `lingo install %[2]s`", installed.FullName(), installed.Source)
+	}
+	if version == "" {
+		version = action.Latest
+	}
+	_, releases, err := registry.Releases(ownerName, actionName)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	version, err = releases.Select(version)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	if version == installed.Version {
+		fmt.Printf("%s is already at %s\n", installed.FullName(), version)
+		return nil
+	}
+	return errors.Trace(upgradeInstalled(registry, installed, version))
+}
+
+func upgradeInstalled(registry *action.Registry, installed *action.Installed, version string) error {
+	from := installed.Version
+	upgraded, err := installVersion(registry, installed.Owner, installed.Name, version, "")
+	if err != nil {
+		return errors.Trace(err)
+	}
+	fmt.Printf("Upgraded %s from %s to %s. Undo with `lingo upgrade --rollback %s`\n", upgraded.FullName(), from, upgraded.Version, upgraded.FullName())
+	return nil
+}
+
+func rollbackInstalled(manifest *action.Manifest, installed *action.Installed) error {
+	if installed.Previous == nil {
+		return errors.Errorf("there is no previous version of %s to roll back to", installed.FullName())
+	}
+
+	home, err := util.LingoHome()
+	if err != nil {
+		return errors.Trace(err)
+	}
+	if err := action.Rollback(filepath.Join(home, "flows", installed.Owner, installed.Name)); err != nil {
+		return errors.Trace(err)
+	}
+
+	previous := installed.Previous
+	installed.Previous = nil
+	previous.Previous = installed
+	manifest.Set(previous)
+	if err := manifest.Save(); err != nil {
+		return errors.Trace(err)
+	}
+	fmt.Printf("Rolled %s back from %s to %s\n", previous.FullName(), installed.Version, previous.Version)
+	return nil
+}
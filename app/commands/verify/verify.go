@@ -12,6 +12,7 @@ import (
 	"time"
 
 	"github.com/blang/semver"
+	"github.com/codelingo/lingo/app/credentials"
 	"github.com/codelingo/lingo/app/util"
 	"github.com/codelingo/lingo/app/util/common"
 	"github.com/codelingo/lingo/app/util/common/config"
@@ -122,23 +123,10 @@ func verifyAuth() error {
 	if err != nil {
 		return errors.Annotate(err, errMsg)
 	}
-	authFile, err := authCfg.GetGitCredentialsFilename()
-	if err != nil {
+	if _, err := authCfg.GetGitUserPassword(); err != nil {
 		return errors.Annotate(err, errMsg)
-	} else if authFile == "" {
-		return errors.New(errMsg)
-	}
-
-	cfgDir, err := util.ConfigHome()
-	if err != nil {
-		return errors.Trace(err)
 	}
-
-	_, err = os.Stat(filepath.Join(cfgDir, authFile))
-	if os.IsNotExist(err) {
-		return errors.New(errMsg)
-	}
-	return errors.Trace(err)
+	return nil
 }
 
 func verifyDotLingo() error {
@@ -213,7 +201,11 @@ func verifyConfig() error {
 		}
 	}
 
-	envCfg := filepath.Join(configsHome, utilConfig.EnvCfgFile)
+	profileHome, err := util.ProfileHome()
+	if err != nil {
+		return errors.Trace(err)
+	}
+	envCfg := filepath.Join(profileHome, utilConfig.EnvCfgFile)
 	if _, err := os.Stat(envCfg); os.IsNotExist(err) {
 		err := ioutil.WriteFile(envCfg, []byte("paas"), 0644)
 		if err != nil {
@@ -241,6 +233,8 @@ func verifyConfig() error {
 		return errors.Trace(err)
 	}
 
+	migrateSecrets()
+
 	// servicesCfg := filepath.Join(configsHome, utilConfig.ServicesCfgFile)
 	// if _, err := os.Stat(servicesCfg); os.IsNotExist(err) {
 	// 	err := ioutil.WriteFile(servicesCfg, []byte(utilConfig.ServicesTmpl), 0644)
@@ -259,6 +253,28 @@ func verifyConfig() error {
 	return nil
 }
 
+// migrateSecrets moves any tokens left in plaintext by earlier versions of
+// lingo into the credentials store. Failing to is only a warning, so lingo
+// can still be configured to fix it.
+func migrateSecrets() {
+	authCfg, err := utilConfig.Auth()
+	if err == nil {
+		var envs []string
+		envs, err = authCfg.MigrateSecrets()
+		for _, env := range envs {
+			store, _ := authCfg.Store()
+			if store.Backend() == credentials.BackendEnv {
+				fmt.Fprintf(os.Stderr, "Removed the %s CodeLingo token from %s, as it is read from %s instead.\n", env, utilConfig.AuthCfgFile, credentials.EnvVar(credentials.TokenName))
+				continue
+			}
+			fmt.Fprintf(os.Stderr, "Moved the %s CodeLingo token out of %s into the %s credentials store.\n", env, utilConfig.AuthCfgFile, store.Backend())
+		}
+	}
+	if err != nil {
+		util.UserFacingWarning(fmt.Sprintf("Warning: your CodeLingo token is still stored in plaintext: %v", err))
+	}
+}
+
 const MissingConfigError string = "Could not get %s config. Please run `lingo config setup`."
 
 func verifyClientVersion() error {
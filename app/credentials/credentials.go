@@ -0,0 +1,96 @@
+// Package credentials keeps the secrets lingo authenticates with, such as
+// the user's CodeLingo token, out of its plaintext config files.
+//
+// Secrets are held by one of several backends: the desktop keyring through
+// the Secret Service API, a file encrypted with a passphrase, or, for CI,
+// environment variables alone.
+package credentials
+
+import (
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/juju/errors"
+)
+
+// The backends secrets can be kept in.
+const (
+	BackendKeyring = "keyring"
+	BackendFile    = "file"
+	BackendEnv     = "env"
+)
+
+// Backends lists every backend, in the order they are preferred when none
+// is configured.
+var Backends = []string{BackendEnv, BackendKeyring, BackendFile}
+
+// Store keeps secrets by name. Names are of the form <environment>/<secret>,
+// for example paas/token.
+type Store interface {
+	// Backend returns the name of the backend the store uses.
+	Backend() string
+
+	// Get returns the named secret, or an error satisfying
+	// errors.IsNotFound if it isn't set.
+	Get(name string) (string, error)
+
+	// Set sets the named secret.
+	Set(name, secret string) error
+
+	// Delete removes the named secret. It is not an error if it isn't set.
+	Delete(name string) error
+}
+
+// Open returns the store for backend, keeping any files it needs in dir. If
+// backend is empty, the environment is used when it sets a token, then the
+// keyring when there is one, then an encrypted file.
+func Open(backend, dir string) (Store, error) {
+	if backend == "" {
+		backend = defaultBackend()
+	}
+	switch backend {
+	case BackendKeyring:
+		k := &Keyring{}
+		if !k.Available() {
+			return nil, errors.New("no keyring is available. Install secret-tool (libsecret) and run a Secret Service provider such as GNOME Keyring, or set credentials.backend to file or env")
+		}
+		return k, nil
+	case BackendFile:
+		return NewFile(dir), nil
+	case BackendEnv:
+		return Env{}, nil
+	}
+	return nil, errors.Errorf("unknown credentials backend %q, expected one of %s", backend, strings.Join(Backends, ", "))
+}
+
+func defaultBackend() string {
+	if _, ok := os.LookupEnv(EnvVar(TokenName)); ok {
+		return BackendEnv
+	}
+	if (&Keyring{}).Available() {
+		return BackendKeyring
+	}
+	return BackendFile
+}
+
+// TokenName is the name of the user's CodeLingo token within an
+// environment.
+const TokenName = "token"
+
+// Name returns the name of secret in the environment env.
+func Name(env, secret string) string {
+	return env + "/" + secret
+}
+
+// splitName returns the environment and secret of a name.
+func splitName(name string) (string, string, error) {
+	parts := strings.SplitN(name, "/", 2)
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return "", "", errors.Errorf("invalid secret name %q, expected <environment>/<secret>", name)
+	}
+	return parts[0], parts[1], nil
+}
+
+// lookPath is a var for mocking in tests.
+var lookPath = exec.LookPath
@@ -0,0 +1,142 @@
+package credentials
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/juju/errors"
+	jc "github.com/juju/testing/checkers"
+	gc "gopkg.in/check.v1"
+)
+
+func Test(t *testing.T) {
+	gc.TestingT(t)
+}
+
+type credentialsSuite struct{}
+
+var _ = gc.Suite(&credentialsSuite{})
+
+func passphrase(p string) func(bool) ([]byte, error) {
+	return func(bool) ([]byte, error) {
+		return []byte(p), nil
+	}
+}
+
+// checkStore checks the round trip of a secret through a store.
+func checkStore(c *gc.C, store Store) {
+	_, err := store.Get("paas/token")
+	c.Assert(errors.IsNotFound(err), jc.IsTrue, gc.Commentf("%v", err))
+
+	c.Assert(store.Set("paas/token", "s3cret"), jc.ErrorIsNil)
+	c.Assert(store.Set("dev/token", "other"), jc.ErrorIsNil)
+	secret, err := store.Get("paas/token")
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(secret, gc.Equals, "s3cret")
+
+	c.Assert(store.Delete("paas/token"), jc.ErrorIsNil)
+	c.Assert(store.Delete("paas/token"), jc.ErrorIsNil)
+	_, err = store.Get("paas/token")
+	c.Assert(errors.IsNotFound(err), jc.IsTrue)
+	secret, err = store.Get("dev/token")
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(secret, gc.Equals, "other")
+
+	c.Assert(store.Set("token", "x"), gc.ErrorMatches, `invalid secret name "token", expected <environment>/<secret>`)
+}
+
+func (s *credentialsSuite) TestFile(c *gc.C) {
+	path := filepath.Join(c.MkDir(), "configs", FileName)
+	checkStore(c, &File{Path: path, Passphrase: passphrase("pw")})
+
+	info, err := os.Stat(path)
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(info.Mode().Perm(), gc.Equals, os.FileMode(0600))
+	data, err := ioutil.ReadFile(path)
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(string(data), gc.Not(jc.Contains), "other")
+
+	_, err = (&File{Path: path, Passphrase: passphrase("wrong")}).Get("dev/token")
+	c.Assert(err, gc.ErrorMatches, "failed to decrypt .*. Is the passphrase right\\?")
+
+	// The file is removed with its last secret.
+	c.Assert((&File{Path: path, Passphrase: passphrase("pw")}).Delete("dev/token"), jc.ErrorIsNil)
+	_, err = os.Stat(path)
+	c.Assert(os.IsNotExist(err), jc.IsTrue)
+}
+
+func (s *credentialsSuite) TestFileEmptyPassphrase(c *gc.C) {
+	f := &File{Path: filepath.Join(c.MkDir(), FileName), Passphrase: passphrase("")}
+	c.Assert(f.Set("paas/token", "s3cret"), gc.ErrorMatches, "the passphrase cannot be empty")
+}
+
+func (s *credentialsSuite) TestKeyring(c *gc.C) {
+	dir := c.MkDir()
+	// A fake secret-tool keeping each secret in a file named after its
+	// attributes.
+	script := filepath.Join(dir, "secret-tool")
+	err := ioutil.WriteFile(script, []byte(`#!/bin/sh
+db=`+dir+`/db
+mkdir -p $db
+op=$1
+shift
+[ "$op" = store ] && shift 2
+key=$(echo "$@" | tr ' ' '_')
+case $op in
+store) cat > $db/$key ;;
+lookup) [ -f $db/$key ] || exit 1; cat $db/$key ;;
+clear) rm -f $db/$key ;;
+esac
+`), 0755)
+	c.Assert(err, jc.ErrorIsNil)
+
+	checkStore(c, &Keyring{Command: script})
+}
+
+func (s *credentialsSuite) TestEnv(c *gc.C) {
+	os.Unsetenv("LINGO_TOKEN")
+	_, err := Env{}.Get("paas/token")
+	c.Assert(errors.IsNotFound(err), jc.IsTrue)
+	c.Assert(Env{}.Delete("paas/token"), jc.ErrorIsNil)
+
+	os.Setenv("LINGO_TOKEN", "s3cret")
+	defer os.Unsetenv("LINGO_TOKEN")
+	for _, env := range []string{"paas", "dev"} {
+		secret, err := Env{}.Get(Name(env, TokenName))
+		c.Assert(err, jc.ErrorIsNil)
+		c.Assert(secret, gc.Equals, "s3cret")
+	}
+	c.Assert(Env{}.Set("paas/token", "x"), gc.ErrorMatches, "credentials are only read from the environment. Set LINGO_TOKEN instead")
+	c.Assert(Env{}.Delete("paas/token"), gc.ErrorMatches, "credentials are only read from the environment. Unset LINGO_TOKEN instead")
+}
+
+func (s *credentialsSuite) TestOpen(c *gc.C) {
+	defer func(orig func(string) (string, error)) { lookPath = orig }(lookPath)
+	lookPath = func(string) (string, error) { return "", errors.New("not found") }
+	dir := c.MkDir()
+
+	store, err := Open("", dir)
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(store.Backend(), gc.Equals, BackendFile)
+	c.Assert(store.(*File).Path, gc.Equals, filepath.Join(dir, FileName))
+
+	_, err = Open(BackendKeyring, dir)
+	c.Assert(err, gc.ErrorMatches, "no keyring is available.*")
+	_, err = Open("vault", dir)
+	c.Assert(err, gc.ErrorMatches, `unknown credentials backend "vault", expected one of env, keyring, file`)
+
+	os.Setenv("DBUS_SESSION_BUS_ADDRESS", "unix:path=/bus")
+	defer os.Unsetenv("DBUS_SESSION_BUS_ADDRESS")
+	lookPath = func(string) (string, error) { return "/usr/bin/secret-tool", nil }
+	store, err = Open("", dir)
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(store.Backend(), gc.Equals, BackendKeyring)
+
+	os.Setenv("LINGO_TOKEN", "s3cret")
+	defer os.Unsetenv("LINGO_TOKEN")
+	store, err = Open("", dir)
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(store.Backend(), gc.Equals, BackendEnv)
+}
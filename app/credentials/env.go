@@ -0,0 +1,53 @@
+package credentials
+
+import (
+	"os"
+	"strings"
+
+	"github.com/juju/errors"
+)
+
+// Env reads secrets from environment variables, which suits CI where
+// nothing should be written to disk. The token is read from LINGO_TOKEN,
+// whatever the environment.
+type Env struct{}
+
+// EnvVar returns the environment variable a secret is read from.
+func EnvVar(secret string) string {
+	return "LINGO_" + strings.ToUpper(secret)
+}
+
+func (Env) Backend() string {
+	return BackendEnv
+}
+
+func (Env) Get(name string) (string, error) {
+	_, secret, err := splitName(name)
+	if err != nil {
+		return "", errors.Trace(err)
+	}
+	value, ok := os.LookupEnv(EnvVar(secret))
+	if !ok {
+		return "", errors.NotFoundf("%s", EnvVar(secret))
+	}
+	return value, nil
+}
+
+func (Env) Set(name, secret string) error {
+	_, s, err := splitName(name)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	return errors.Errorf("credentials are only read from the environment. Set %s instead", EnvVar(s))
+}
+
+func (Env) Delete(name string) error {
+	_, secret, err := splitName(name)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	if _, ok := os.LookupEnv(EnvVar(secret)); ok {
+		return errors.Errorf("credentials are only read from the environment. Unset %s instead", EnvVar(secret))
+	}
+	return nil
+}
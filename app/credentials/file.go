@@ -0,0 +1,261 @@
+package credentials
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/juju/errors"
+	"golang.org/x/crypto/scrypt"
+	"golang.org/x/crypto/ssh/terminal"
+	"gopkg.in/yaml.v2"
+)
+
+// FileName is the encrypted file's name in the lingo config directory.
+const FileName = "credentials.enc"
+
+// PassphraseEnvVar may hold the passphrase of the encrypted file, so it
+// needn't be typed.
+const PassphraseEnvVar = "LINGO_CREDENTIALS_PASSPHRASE"
+
+// The scrypt parameters used to derive the file's key from its passphrase.
+const (
+	scryptN      = 1 << 15
+	scryptR      = 8
+	scryptP      = 1
+	scryptKeyLen = 32
+)
+
+// File keeps secrets in a file encrypted with AES-256-GCM, under a key
+// derived from a passphrase with scrypt.
+type File struct {
+	Path string
+
+	// Passphrase returns the passphrase, confirming it when the file is
+	// being created.
+	Passphrase func(confirm bool) ([]byte, error)
+
+	passphrase []byte
+}
+
+// encryptedFile is the file's layout on disk, with its fields base64
+// encoded.
+type encryptedFile struct {
+	Version int    `yaml:"version"`
+	Salt    string `yaml:"salt"`
+	Nonce   string `yaml:"nonce"`
+	Data    string `yaml:"data"`
+}
+
+// NewFile returns the encrypted file in dir, reading its passphrase from
+// $LINGO_CREDENTIALS_PASSPHRASE or the terminal.
+func NewFile(dir string) *File {
+	return &File{
+		Path:       filepath.Join(dir, FileName),
+		Passphrase: promptPassphrase,
+	}
+}
+
+func (f *File) Backend() string {
+	return BackendFile
+}
+
+func (f *File) Get(name string) (string, error) {
+	secrets, err := f.read()
+	if err != nil {
+		return "", errors.Trace(err)
+	}
+	secret, ok := secrets[name]
+	if !ok {
+		return "", errors.NotFoundf("%s in %s", name, f.Path)
+	}
+	return secret, nil
+}
+
+func (f *File) Set(name, secret string) error {
+	if _, _, err := splitName(name); err != nil {
+		return errors.Trace(err)
+	}
+	secrets, err := f.read()
+	if err != nil {
+		return errors.Trace(err)
+	}
+	secrets[name] = secret
+	return errors.Trace(f.write(secrets))
+}
+
+func (f *File) Delete(name string) error {
+	if _, err := os.Stat(f.Path); os.IsNotExist(err) {
+		return nil
+	}
+	secrets, err := f.read()
+	if err != nil {
+		return errors.Trace(err)
+	}
+	if _, ok := secrets[name]; !ok {
+		return nil
+	}
+	delete(secrets, name)
+	if len(secrets) == 0 {
+		return errors.Trace(os.Remove(f.Path))
+	}
+	return errors.Trace(f.write(secrets))
+}
+
+// read decrypts the file, returning no secrets if it doesn't exist.
+func (f *File) read() (map[string]string, error) {
+	data, err := ioutil.ReadFile(f.Path)
+	if os.IsNotExist(err) {
+		return map[string]string{}, nil
+	}
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	var enc encryptedFile
+	if err := yaml.Unmarshal(data, &enc); err != nil {
+		return nil, errors.Annotatef(err, "failed to parse %s", f.Path)
+	}
+	if enc.Version != 1 {
+		return nil, errors.Errorf("%s has unknown version %d", f.Path, enc.Version)
+	}
+
+	var salt, nonce, ciphertext []byte
+	for _, field := range []struct {
+		dst *[]byte
+		src string
+	}{{&salt, enc.Salt}, {&nonce, enc.Nonce}, {&ciphertext, enc.Data}} {
+		if *field.dst, err = base64.StdEncoding.DecodeString(field.src); err != nil {
+			return nil, errors.Annotatef(err, "failed to parse %s", f.Path)
+		}
+	}
+
+	gcm, err := f.cipher(salt, false)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	if len(nonce) != gcm.NonceSize() {
+		return nil, errors.Errorf("failed to parse %s: invalid nonce", f.Path)
+	}
+	plain, err := gcm.Open(nil, nonce, ciphertext, nil)
+	if err != nil {
+		f.passphrase = nil
+		return nil, errors.Errorf("failed to decrypt %s. Is the passphrase right?", f.Path)
+	}
+	secrets := map[string]string{}
+	return secrets, errors.Trace(json.Unmarshal(plain, &secrets))
+}
+
+// write encrypts secrets under a new salt and nonce, and replaces the file
+// with them.
+func (f *File) write(secrets map[string]string) error {
+	plain, err := json.Marshal(secrets)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	salt := make([]byte, 16)
+	if _, err := rand.Read(salt); err != nil {
+		return errors.Trace(err)
+	}
+	_, statErr := os.Stat(f.Path)
+	gcm, err := f.cipher(salt, os.IsNotExist(statErr))
+	if err != nil {
+		return errors.Trace(err)
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return errors.Trace(err)
+	}
+
+	data, err := yaml.Marshal(&encryptedFile{
+		Version: 1,
+		Salt:    base64.StdEncoding.EncodeToString(salt),
+		Nonce:   base64.StdEncoding.EncodeToString(nonce),
+		Data:    base64.StdEncoding.EncodeToString(gcm.Seal(nil, nonce, plain, nil)),
+	})
+	if err != nil {
+		return errors.Trace(err)
+	}
+	return errors.Trace(WriteFile(f.Path, data))
+}
+
+func (f *File) cipher(salt []byte, creating bool) (cipher.AEAD, error) {
+	if f.passphrase == nil {
+		passphrase, err := f.Passphrase(creating)
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		if len(passphrase) == 0 {
+			return nil, errors.New("the passphrase cannot be empty")
+		}
+		f.passphrase = passphrase
+	}
+	key, err := scrypt.Key(f.passphrase, salt, scryptN, scryptR, scryptP, scryptKeyLen)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	gcm, err := cipher.NewGCM(block)
+	return gcm, errors.Trace(err)
+}
+
+// WriteFile replaces the file at path with data, readable only by the
+// user. The file is written beside path and renamed into place, so it is
+// never left half written.
+func WriteFile(path string, data []byte) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return errors.Trace(err)
+	}
+	tmp, err := ioutil.TempFile(filepath.Dir(path), "."+filepath.Base(path))
+	if err != nil {
+		return errors.Trace(err)
+	}
+	defer os.Remove(tmp.Name())
+	if err := tmp.Chmod(0600); err != nil {
+		tmp.Close()
+		return errors.Trace(err)
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return errors.Trace(err)
+	}
+	if err := tmp.Close(); err != nil {
+		return errors.Trace(err)
+	}
+	return errors.Trace(os.Rename(tmp.Name(), path))
+}
+
+func promptPassphrase(confirm bool) ([]byte, error) {
+	if passphrase, ok := os.LookupEnv(PassphraseEnvVar); ok {
+		return []byte(passphrase), nil
+	}
+	fd := int(os.Stdin.Fd())
+	if !terminal.IsTerminal(fd) {
+		return nil, errors.Errorf("the credentials file is encrypted. Set %s to its passphrase", PassphraseEnvVar)
+	}
+
+	fmt.Fprint(os.Stderr, "Passphrase for the CodeLingo credentials file: ")
+	passphrase, err := terminal.ReadPassword(fd)
+	fmt.Fprintln(os.Stderr)
+	if err != nil || !confirm {
+		return passphrase, errors.Trace(err)
+	}
+	fmt.Fprint(os.Stderr, "Enter it again to confirm: ")
+	again, err := terminal.ReadPassword(fd)
+	fmt.Fprintln(os.Stderr)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	if string(again) != string(passphrase) {
+		return nil, errors.New("the passphrases don't match")
+	}
+	return passphrase, nil
+}
@@ -0,0 +1,103 @@
+package credentials
+
+import (
+	"bytes"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/juju/errors"
+)
+
+// keyringService identifies lingo's secrets in the keyring.
+const keyringService = "codelingo-lingo"
+
+// Keyring keeps secrets in the desktop keyring through the Secret Service
+// API, using libsecret's secret-tool.
+type Keyring struct {
+	// Command is the secret-tool executable. It defaults to secret-tool on
+	// the PATH.
+	Command string
+}
+
+// Available reports whether secret-tool is installed and there is a
+// session bus to reach the keyring on.
+func (k *Keyring) Available() bool {
+	if os.Getenv("DBUS_SESSION_BUS_ADDRESS") == "" {
+		return false
+	}
+	_, err := lookPath(k.command())
+	return err == nil
+}
+
+func (k *Keyring) Backend() string {
+	return BackendKeyring
+}
+
+func (k *Keyring) Get(name string) (string, error) {
+	attrs, err := keyringAttributes(name)
+	if err != nil {
+		return "", errors.Trace(err)
+	}
+	out, err := k.run("", append([]string{"lookup"}, attrs...)...)
+	if err != nil {
+		// secret-tool exits with 1, and prints nothing, when there is no
+		// such secret.
+		if exitErr, ok := errors.Cause(err).(*exec.ExitError); ok && exitErr.ExitCode() == 1 && out == "" {
+			return "", errors.NotFoundf("%s in the keyring", name)
+		}
+		return "", errors.Annotatef(err, "failed to read %s from the keyring", name)
+	}
+	return out, nil
+}
+
+func (k *Keyring) Set(name, secret string) error {
+	attrs, err := keyringAttributes(name)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	args := append([]string{"store", "--label", "CodeLingo " + name}, attrs...)
+	// The secret is written to stdin to keep it out of the process list.
+	_, err = k.run(secret, args...)
+	return errors.Annotatef(err, "failed to store %s in the keyring", name)
+}
+
+func (k *Keyring) Delete(name string) error {
+	attrs, err := keyringAttributes(name)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	if _, err := k.Get(name); errors.IsNotFound(err) {
+		return nil
+	}
+	_, err = k.run("", append([]string{"clear"}, attrs...)...)
+	return errors.Annotatef(err, "failed to remove %s from the keyring", name)
+}
+
+func (k *Keyring) command() string {
+	if k.Command != "" {
+		return k.Command
+	}
+	return "secret-tool"
+}
+
+func (k *Keyring) run(stdin string, args ...string) (string, error) {
+	cmd := exec.Command(k.command(), args...)
+	cmd.Stdin = strings.NewReader(stdin)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	err := cmd.Run()
+	if msg := strings.TrimSpace(stderr.String()); err != nil && msg != "" {
+		err = errors.Annotate(err, msg)
+	}
+	return strings.TrimSuffix(stdout.String(), "\n"), err
+}
+
+func keyringAttributes(name string) ([]string, error) {
+	env, secret, err := splitName(name)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	return []string{"service", keyringService, "environment", env, "secret", secret}, nil
+}
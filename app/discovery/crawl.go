@@ -0,0 +1,286 @@
+package discovery
+
+import (
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/juju/errors"
+	"gopkg.in/yaml.v2"
+)
+
+// names is a list of names in the index, written as a sequence of names or
+// of mappings with a name, or as a mapping keyed by name.
+type names []string
+
+func (n *names) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var list []string
+	if err := unmarshal(&list); err == nil {
+		*n = list
+		return nil
+	}
+	var named []struct {
+		Name string `yaml:"name"`
+	}
+	if err := unmarshal(&named); err == nil {
+		*n = nil
+		for _, item := range named {
+			*n = append(*n, item.Name)
+		}
+		return nil
+	}
+	var mapping map[string]interface{}
+	if err := unmarshal(&mapping); err != nil {
+		return errors.New("expected a list of names")
+	}
+	*n = nil
+	for name := range mapping {
+		*n = append(*n, name)
+	}
+	sort.Strings(*n)
+	return nil
+}
+
+// listFile is an index file listing owners, types or items.
+type listFile struct {
+	Owners   names `yaml:"owners"`
+	Types    names `yaml:"types"`
+	Bundles  names `yaml:"bundles"`
+	Bots     names `yaml:"bots"`
+	Flows    names `yaml:"flows"`
+	Lexicons names `yaml:"lexicons"`
+}
+
+// details describes a bundle, Bot, Action or Lexicon.
+type details struct {
+	Description string   `yaml:"description"`
+	Tags        []string `yaml:"tags"`
+	Tenets      names    `yaml:"tenets"`
+}
+
+// dotlingo is the part of a codelingo.yaml file describing its Tenets.
+type dotlingo struct {
+	Tenets []*dotlingoTenet `yaml:"tenets"`
+}
+
+type dotlingoTenet struct {
+	// Import is the owner/bundle or owner/bundle/tenet imported in place of
+	// a Tenet.
+	Import string   `yaml:"import"`
+	Name   string   `yaml:"name"`
+	Doc    string   `yaml:"doc"`
+	Tags   []string `yaml:"tags"`
+	// Actions configures the Bots acting on the Tenet's results, keyed by
+	// owner/name. Older files call them bots.
+	Actions map[string]interface{} `yaml:"actions"`
+	Bots    map[string]interface{} `yaml:"bots"`
+	Query   string                 `yaml:"query"`
+}
+
+// Load reads the items of the given kinds, or of every kind if none are
+// given, from src.
+func Load(src Source, kinds ...Kind) (*Index, error) {
+	if len(kinds) == 0 {
+		kinds = Kinds
+	}
+	c := &crawler{src: src}
+	var err error
+	if containsKind(kinds, KindTenet) || containsKind(kinds, KindBundle) {
+		err = c.tenets(containsKind(kinds, KindTenet), containsKind(kinds, KindBundle))
+	}
+	if err == nil && containsKind(kinds, KindBot) {
+		err = c.simple("bots", "lingo_bots.yaml", "lingo_bot.yaml", KindBot)
+	}
+	if err == nil && containsKind(kinds, KindAction) {
+		err = c.simple("flows", "lingo_flows.yaml", "lingo_flow.yaml", KindAction)
+	}
+	if err == nil && containsKind(kinds, KindLexicon) {
+		err = c.lexicons()
+	}
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	return &Index{Items: c.items}, nil
+}
+
+type crawler struct {
+	src   Source
+	items []*Item
+}
+
+// read unmarshals the index file at the path joined from elems into v,
+// returning false if there is no such file.
+func (c *crawler) read(v interface{}, elems ...string) (bool, error) {
+	for _, elem := range elems {
+		if !validName(elem) {
+			return false, errors.Errorf("invalid name %q in the discovery index", elem)
+		}
+	}
+	p := path.Join(elems...)
+	data, err := c.src.Read(p)
+	if errors.IsNotFound(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, errors.Trace(err)
+	}
+	if err := yaml.Unmarshal(data, v); err != nil {
+		return false, errors.Annotatef(err, "failed to parse %s", p)
+	}
+	return true, nil
+}
+
+// readTop reads a file at the top of a tree of the index, which must exist.
+func (c *crawler) readTop(v interface{}, elems ...string) error {
+	ok, err := c.read(v, elems...)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	if !ok {
+		return errors.Errorf("the discovery index has no %s", path.Join(elems...))
+	}
+	return nil
+}
+
+func (c *crawler) tenets(tenets, bundles bool) error {
+	var top listFile
+	if err := c.readTop(&top, "tenets", "lingo_tenets.yaml"); err != nil {
+		return errors.Trace(err)
+	}
+	for _, owner := range top.Owners {
+		var ownerFile listFile
+		if _, err := c.read(&ownerFile, "tenets", owner, "lingo_owner.yaml"); err != nil {
+			return errors.Trace(err)
+		}
+		for _, bundle := range ownerFile.Bundles {
+			var d details
+			if _, err := c.read(&d, "tenets", owner, bundle, "lingo_bundle.yaml"); err != nil {
+				return errors.Trace(err)
+			}
+			if bundles {
+				c.items = append(c.items, &Item{
+					Kind:        KindBundle,
+					Owner:       owner,
+					Name:        bundle,
+					Description: d.Description,
+					Tags:        d.Tags,
+				})
+			}
+			if !tenets {
+				continue
+			}
+			for _, tenet := range d.Tenets {
+				item := &Item{
+					Kind:   KindTenet,
+					Owner:  owner,
+					Name:   tenet,
+					Bundle: bundle,
+				}
+				var dl dotlingo
+				if _, err := c.read(&dl, "tenets", owner, bundle, tenet, "codelingo.yaml"); err != nil {
+					return errors.Trace(err)
+				}
+				describe(item, dl.tenet(tenet))
+				c.items = append(c.items, item)
+			}
+		}
+	}
+	return nil
+}
+
+// tenet returns the named Tenet, or the only Tenet, of a codelingo.yaml
+// file.
+func (dl *dotlingo) tenet(name string) *dotlingoTenet {
+	for _, t := range dl.Tenets {
+		if t.Name == name || len(dl.Tenets) == 1 {
+			return t
+		}
+	}
+	return &dotlingoTenet{}
+}
+
+// describe fills in item's description and tags from a Tenet.
+func describe(item *Item, t *dotlingoTenet) {
+	item.Tags = t.Tags
+	item.Description = t.Doc
+	if item.Description != "" {
+		return
+	}
+	if docs, ok := t.Actions["codelingo/docs"].(map[interface{}]interface{}); ok {
+		if title, ok := docs["title"].(string); ok {
+			item.Description = title
+		}
+	}
+}
+
+// simple reads a tree of items listed by owner, i.e. Bots and Actions.
+func (c *crawler) simple(dir, topFile, itemFile string, kind Kind) error {
+	var top listFile
+	if err := c.readTop(&top, dir, topFile); err != nil {
+		return errors.Trace(err)
+	}
+	for _, owner := range top.Owners {
+		var ownerFile listFile
+		if _, err := c.read(&ownerFile, dir, owner, "lingo_owner.yaml"); err != nil {
+			return errors.Trace(err)
+		}
+		list := ownerFile.Bots
+		if kind == KindAction {
+			list = ownerFile.Flows
+		}
+		for _, name := range list {
+			var d details
+			if _, err := c.read(&d, dir, owner, name, itemFile); err != nil {
+				return errors.Trace(err)
+			}
+			c.items = append(c.items, &Item{
+				Kind:        kind,
+				Owner:       owner,
+				Name:        name,
+				Description: d.Description,
+				Tags:        d.Tags,
+			})
+		}
+	}
+	return nil
+}
+
+func (c *crawler) lexicons() error {
+	var top listFile
+	if err := c.readTop(&top, "lexicons", "lingo_lexicon_type.yaml"); err != nil {
+		return errors.Trace(err)
+	}
+	for _, typ := range top.Types {
+		var typeFile listFile
+		if _, err := c.read(&typeFile, "lexicons", typ, "lingo_lexicons.yaml"); err != nil {
+			return errors.Trace(err)
+		}
+		for _, owner := range typeFile.Owners {
+			var ownerFile listFile
+			if _, err := c.read(&ownerFile, "lexicons", typ, owner, "lingo_owner.yaml"); err != nil {
+				return errors.Trace(err)
+			}
+			for _, name := range ownerFile.Lexicons {
+				var d details
+				if _, err := c.read(&d, "lexicons", typ, owner, name, "lingo_lexicon.yaml"); err != nil {
+					return errors.Trace(err)
+				}
+				c.items = append(c.items, &Item{
+					Kind:        KindLexicon,
+					Owner:       owner,
+					Name:        name,
+					Type:        typ,
+					Description: d.Description,
+					Tags:        d.Tags,
+				})
+			}
+		}
+	}
+	return nil
+}
+
+// validName reports whether name can be used as an element of a path in
+// the index, so that the index can't refer to files outside itself.
+func validName(name string) bool {
+	return name != "" && !strings.HasPrefix(name, ".") && !strings.ContainsAny(name, `/\`)
+}
@@ -0,0 +1,191 @@
+// Package discovery reads the index of published Tenets, Bots, Actions and
+// Lexicons, and searches it.
+//
+// The index is a tree of YAML files under a root:
+//
+//	tenets/lingo_tenets.yaml                         owners: [<owner>...]
+//	tenets/<owner>/lingo_owner.yaml                  bundles: [<bundle>...]
+//	tenets/<owner>/<bundle>/lingo_bundle.yaml        description, tags, tenets: [<tenet>...]
+//	tenets/<owner>/<bundle>/<tenet>/codelingo.yaml   the Tenet
+//	bots/lingo_bots.yaml                             owners: [<owner>...]
+//	bots/<owner>/lingo_owner.yaml                    bots: [<bot>...]
+//	bots/<owner>/<bot>/lingo_bot.yaml                description, tags
+//	flows/lingo_flows.yaml                           owners: [<owner>...]
+//	flows/<owner>/lingo_owner.yaml                   flows: [<action>...]
+//	flows/<owner>/<action>/lingo_flow.yaml           description, tags
+//	lexicons/lingo_lexicon_type.yaml                 types: [<type>...]
+//	lexicons/<type>/lingo_lexicons.yaml              owners: [<owner>...]
+//	lexicons/<type>/<owner>/lingo_owner.yaml         lexicons: [<lexicon>...]
+//	lexicons/<type>/<owner>/<lexicon>/lingo_lexicon.yaml  description, tags
+//
+// Lists of names may also be sequences of mappings with a name, or mappings
+// keyed by name. Missing description files are ignored, so an item is listed
+// as long as its parent names it.
+package discovery
+
+import (
+	"sort"
+	"strings"
+)
+
+// Kind is the kind of an item in the index.
+type Kind string
+
+const (
+	KindTenet   Kind = "tenet"
+	KindBundle  Kind = "bundle"
+	KindBot     Kind = "bot"
+	KindAction  Kind = "action"
+	KindLexicon Kind = "lexicon"
+)
+
+// Kinds are all the kinds of items in the index.
+var Kinds = []Kind{KindTenet, KindBundle, KindBot, KindAction, KindLexicon}
+
+// ParseKind returns the kind with the given name, accepting plurals.
+func ParseKind(name string) (Kind, bool) {
+	name = strings.TrimSuffix(strings.ToLower(name), "s")
+	for _, kind := range Kinds {
+		if string(kind) == name {
+			return kind, true
+		}
+	}
+	return "", false
+}
+
+// Item is a published Tenet, bundle, Bot, Action or Lexicon.
+type Item struct {
+	Kind  Kind   `json:"kind"`
+	Owner string `json:"owner"`
+	Name  string `json:"name"`
+	// Bundle is the bundle of a Tenet.
+	Bundle string `json:"bundle,omitempty"`
+	// Type is the type of a Lexicon, e.g. "ast".
+	Type        string   `json:"type,omitempty"`
+	Description string   `json:"description,omitempty"`
+	Tags        []string `json:"tags,omitempty"`
+	// Version is the version of an installed Action.
+	Version string `json:"version,omitempty"`
+	// Files are the codelingo.yaml files of a project using the item.
+	Files []string `json:"files,omitempty"`
+}
+
+// FullName returns the name the item is referred to by: owner/bundle/tenet
+// for Tenets, owner/type/name for Lexicons, as they are imported, and
+// owner/name otherwise. Tenets defined in a project have no owner, and are
+// referred to by name.
+func (i *Item) FullName() string {
+	if i.Owner == "" {
+		return i.Name
+	}
+	switch i.Kind {
+	case KindTenet:
+		return i.Owner + "/" + i.Bundle + "/" + i.Name
+	case KindLexicon:
+		return i.Owner + "/" + i.Type + "/" + i.Name
+	}
+	return i.Owner + "/" + i.Name
+}
+
+// Index is the set of published items.
+type Index struct {
+	Items []*Item
+}
+
+// Query selects items from the index. Empty fields match every item.
+type Query struct {
+	// Term is matched, ignoring case, against each item's name, bundle or
+	// type, description and tags, or its full name if it contains a slash.
+	Term  string
+	Kinds []Kind
+	Owner string
+	// Name must equal the item's name.
+	Name string
+	// Bundle must equal a Tenet's bundle.
+	Bundle string
+	// Type must equal a Lexicon's type.
+	Type string
+	// Tags must all be tags of the item.
+	Tags []string
+}
+
+// Search returns the items matching the query, sorted by kind and full
+// name.
+func (ix *Index) Search(q *Query) []*Item {
+	var items []*Item
+	for _, item := range ix.Items {
+		if q.matches(item) {
+			items = append(items, item)
+		}
+	}
+	sort.SliceStable(items, func(i, j int) bool {
+		if items[i].Kind != items[j].Kind {
+			return kindOrder(items[i].Kind) < kindOrder(items[j].Kind)
+		}
+		return items[i].FullName() < items[j].FullName()
+	})
+	return items
+}
+
+func (q *Query) matches(item *Item) bool {
+	if len(q.Kinds) > 0 && !containsKind(q.Kinds, item.Kind) {
+		return false
+	}
+	if (q.Owner != "" && item.Owner != q.Owner) ||
+		(q.Name != "" && item.Name != q.Name) ||
+		(q.Bundle != "" && item.Bundle != q.Bundle) ||
+		(q.Type != "" && item.Type != q.Type) {
+		return false
+	}
+	for _, tag := range q.Tags {
+		if !containsFold(item.Tags, tag) {
+			return false
+		}
+	}
+	if q.Term == "" {
+		return true
+	}
+
+	term := strings.ToLower(q.Term)
+	if strings.Contains(term, "/") {
+		return strings.Contains(strings.ToLower(item.FullName()), term)
+	}
+	for _, field := range []string{item.Name, item.Bundle, item.Type, item.Description} {
+		if strings.Contains(strings.ToLower(field), term) {
+			return true
+		}
+	}
+	for _, tag := range item.Tags {
+		if strings.Contains(strings.ToLower(tag), term) {
+			return true
+		}
+	}
+	return false
+}
+
+func containsKind(kinds []Kind, kind Kind) bool {
+	for _, k := range kinds {
+		if k == kind {
+			return true
+		}
+	}
+	return false
+}
+
+func containsFold(values []string, value string) bool {
+	for _, v := range values {
+		if strings.EqualFold(v, value) {
+			return true
+		}
+	}
+	return false
+}
+
+func kindOrder(kind Kind) int {
+	for i, k := range Kinds {
+		if k == kind {
+			return i
+		}
+	}
+	return len(Kinds)
+}
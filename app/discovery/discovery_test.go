@@ -0,0 +1,473 @@
+package discovery
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/juju/errors"
+	jc "github.com/juju/testing/checkers"
+	gc "gopkg.in/check.v1"
+)
+
+func Test(t *testing.T) {
+	gc.TestingT(t)
+}
+
+type discoverySuite struct{}
+
+var _ = gc.Suite(&discoverySuite{})
+
+// files is a Source reading from a map, counting reads.
+type files struct {
+	files map[string]string
+	reads int
+	err   error
+}
+
+func (f *files) Read(path string) ([]byte, error) {
+	f.reads++
+	if f.err != nil {
+		return nil, f.err
+	}
+	data, ok := f.files[path]
+	if !ok {
+		return nil, errors.NotFoundf("%s", path)
+	}
+	return []byte(data), nil
+}
+
+var testIndex = map[string]string{
+	"tenets/lingo_tenets.yaml":          "owners:\n  - codelingo\n",
+	"tenets/codelingo/lingo_owner.yaml": "bundles:\n  go:\n  rust:\n",
+	"tenets/codelingo/go/lingo_bundle.yaml": `
+description: Best practices for Go.
+tags: [go, golang]
+tenets:
+  - name: defer-close-file
+  - name: empty-slice
+`,
+	"tenets/codelingo/go/defer-close-file/codelingo.yaml": `
+tenets:
+  - name: defer-close-file
+    tags: [files]
+    actions:
+      codelingo/docs:
+        title: Defer closing files
+`,
+	"tenets/codelingo/go/empty-slice/codelingo.yaml": `
+tenets:
+  - name: empty-slice
+    doc: Declare empty slices as nil.
+`,
+	"bots/lingo_bots.yaml":                         "owners: [codelingo]\n",
+	"bots/codelingo/lingo_owner.yaml":              "bots: [review]\n",
+	"bots/codelingo/review/lingo_bot.yaml":         "description: Comments on pull requests.\ntags: [github]\n",
+	"flows/lingo_flows.yaml":                       "owners: [codelingo]\n",
+	"flows/codelingo/lingo_owner.yaml":             "flows: [rewrite, docs]\n",
+	"flows/codelingo/rewrite/lingo_flow.yaml":      "description: Rewrites code.\n",
+	"lexicons/lingo_lexicon_type.yaml":             "types: [ast]\n",
+	"lexicons/ast/lingo_lexicons.yaml":             "owners: [codelingo]\n",
+	"lexicons/ast/codelingo/lingo_owner.yaml":      "lexicons: [go]\n",
+	"lexicons/ast/codelingo/go/lingo_lexicon.yaml": "description: The Go AST.\ntags: [go]\n",
+}
+
+func fullNames(items []*Item) []string {
+	var names []string
+	for _, item := range items {
+		names = append(names, string(item.Kind)+" "+item.FullName())
+	}
+	return names
+}
+
+func (s *discoverySuite) TestLoad(c *gc.C) {
+	ix, err := Load(&files{files: testIndex})
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(fullNames(ix.Search(&Query{})), gc.DeepEquals, []string{
+		"tenet codelingo/go/defer-close-file",
+		"tenet codelingo/go/empty-slice",
+		"bundle codelingo/go",
+		"bundle codelingo/rust",
+		"bot codelingo/review",
+		"action codelingo/docs",
+		"action codelingo/rewrite",
+		"lexicon codelingo/ast/go",
+	})
+
+	items := ix.Search(&Query{Kinds: []Kind{KindTenet}})
+	c.Assert(items[0].Description, gc.Equals, "Defer closing files")
+	c.Assert(items[0].Tags, gc.DeepEquals, []string{"files"})
+	c.Assert(items[1].Description, gc.Equals, "Declare empty slices as nil.")
+
+	ix, err = Load(&files{files: testIndex}, KindBot)
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(fullNames(ix.Items), gc.DeepEquals, []string{"bot codelingo/review"})
+}
+
+func (s *discoverySuite) TestLoadErrors(c *gc.C) {
+	_, err := Load(&files{files: map[string]string{}}, KindBot)
+	c.Assert(err, gc.ErrorMatches, "the discovery index has no bots/lingo_bots.yaml")
+
+	_, err = Load(&files{files: map[string]string{
+		"bots/lingo_bots.yaml": "owners: [../../etc]\n",
+	}}, KindBot)
+	c.Assert(err, gc.ErrorMatches, `invalid name "../../etc" in the discovery index`)
+
+	_, err = Load(&files{files: map[string]string{
+		"bots/lingo_bots.yaml": "owners: 3\n",
+	}}, KindBot)
+	c.Assert(err, gc.ErrorMatches, "failed to parse bots/lingo_bots.yaml: expected a list of names")
+}
+
+func (s *discoverySuite) TestSearch(c *gc.C) {
+	ix, err := Load(&files{files: testIndex})
+	c.Assert(err, jc.ErrorIsNil)
+
+	for _, t := range []struct {
+		query Query
+		want  []string
+	}{{
+		query: Query{Term: "GO"},
+		want: []string{
+			"tenet codelingo/go/defer-close-file",
+			"tenet codelingo/go/empty-slice",
+			"bundle codelingo/go",
+			"lexicon codelingo/ast/go",
+		},
+	}, {
+		query: Query{Term: "pull request"},
+		want:  []string{"bot codelingo/review"},
+	}, {
+		query: Query{Tags: []string{"Golang"}},
+		want:  []string{"bundle codelingo/go"},
+	}, {
+		query: Query{Term: "go", Kinds: []Kind{KindLexicon, KindBundle}},
+		want:  []string{"bundle codelingo/go", "lexicon codelingo/ast/go"},
+	}, {
+		query: Query{Bundle: "go", Name: "empty-slice"},
+		want:  []string{"tenet codelingo/go/empty-slice"},
+	}, {
+		query: Query{Term: "codelingo/go/"},
+		want:  []string{"tenet codelingo/go/defer-close-file", "tenet codelingo/go/empty-slice"},
+	}, {
+		query: Query{Owner: "someone"},
+	}} {
+		c.Check(fullNames(ix.Search(&t.query)), gc.DeepEquals, t.want, gc.Commentf("%+v", t.query))
+	}
+}
+
+func (s *discoverySuite) TestParseKind(c *gc.C) {
+	kind, ok := ParseKind("Actions")
+	c.Assert(ok, jc.IsTrue)
+	c.Assert(kind, gc.Equals, KindAction)
+	_, ok = ParseKind("flow")
+	c.Assert(ok, jc.IsFalse)
+}
+
+func (s *discoverySuite) TestRender(c *gc.C) {
+	items := []*Item{{
+		Kind:        KindTenet,
+		Owner:       "codelingo",
+		Bundle:      "go",
+		Name:        "empty-slice",
+		Description: "Declare empty slices as nil.\nMore details.",
+		Tags:        []string{"go", "slices"},
+	}}
+
+	var buf bytes.Buffer
+	c.Assert(Render(&buf, items, FormatTable), jc.ErrorIsNil)
+	c.Assert(buf.String(), gc.Equals, ""+
+		"KIND   NAME                      DESCRIPTION                   TAGS\n"+
+		"tenet  codelingo/go/empty-slice  Declare empty slices as nil.  go,slices\n")
+
+	buf.Reset()
+	c.Assert(Render(&buf, items, FormatJSON), jc.ErrorIsNil)
+	var decoded []*Item
+	c.Assert(json.Unmarshal(buf.Bytes(), &decoded), jc.ErrorIsNil)
+	c.Assert(decoded, gc.DeepEquals, items)
+
+	buf.Reset()
+	c.Assert(Render(&buf, nil, FormatJSON), jc.ErrorIsNil)
+	c.Assert(buf.String(), gc.Equals, "[]\n")
+
+	c.Assert(Render(&buf, nil, "yaml"), gc.ErrorMatches, `unknown format "yaml", expected table or json`)
+	c.Assert(shorten(strings.Repeat("a", 100)), gc.HasLen, maxDescription)
+}
+
+func (s *discoverySuite) TestCache(c *gc.C) {
+	dir, err := ioutil.TempDir("", "discovery")
+	c.Assert(err, jc.ErrorIsNil)
+	defer os.RemoveAll(dir)
+
+	src := &files{files: map[string]string{"bots/lingo_bots.yaml": "owners: [a]\n"}}
+	cache := &Cache{Source: src, Dir: dir, TTL: time.Hour}
+
+	for i := 0; i < 2; i++ {
+		data, err := cache.Read("bots/lingo_bots.yaml")
+		c.Assert(err, jc.ErrorIsNil)
+		c.Assert(string(data), gc.Equals, "owners: [a]\n")
+		_, err = cache.Read("bots/a/lingo_owner.yaml")
+		c.Assert(errors.IsNotFound(err), jc.IsTrue)
+	}
+	c.Assert(src.reads, gc.Equals, 2)
+
+	// Refreshing reads the Source again.
+	src.files["bots/lingo_bots.yaml"] = "owners: [b]\n"
+	cache.Refresh = true
+	data, err := cache.Read("bots/lingo_bots.yaml")
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(string(data), gc.Equals, "owners: [b]\n")
+	c.Assert(src.reads, gc.Equals, 3)
+
+	// Stale files are used when the Source fails.
+	src.err = errors.New("offline")
+	data, err = cache.Read("bots/lingo_bots.yaml")
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(string(data), gc.Equals, "owners: [b]\n")
+	_, err = cache.Read("flows/lingo_flows.yaml")
+	c.Assert(err, gc.ErrorMatches, "offline")
+
+	stale := time.Now().Add(-2 * time.Hour)
+	path := filepath.Join(dir, "bots", "lingo_bots.yaml")
+	c.Assert(os.Chtimes(path, stale, stale), jc.ErrorIsNil)
+	cache.Refresh = false
+	src.err = nil
+	_, err = cache.Read("bots/lingo_bots.yaml")
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(src.reads, gc.Equals, 6)
+}
+
+func (s *discoverySuite) TestHTTP(c *gc.C) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/index/bots/lingo_bots.yaml":
+			w.Write([]byte("owners: [a]\n"))
+		case "/index/broken.yaml":
+			w.WriteHeader(http.StatusInternalServerError)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	src := &HTTP{BaseURL: server.URL + "/index/"}
+	data, err := src.Read("bots/lingo_bots.yaml")
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(string(data), gc.Equals, "owners: [a]\n")
+
+	_, err = src.Read("flows/lingo_flows.yaml")
+	c.Assert(errors.IsNotFound(err), jc.IsTrue)
+	_, err = src.Read("broken.yaml")
+	c.Assert(err, gc.ErrorMatches, "failed to read .*/index/broken.yaml: 500 Internal Server Error")
+}
+
+// writeIndex writes the files of an index under dir.
+func writeIndex(c *gc.C, dir string, index map[string]string) {
+	for path, data := range index {
+		path = filepath.Join(dir, filepath.FromSlash(path))
+		c.Assert(os.MkdirAll(filepath.Dir(path), 0755), jc.ErrorIsNil)
+		c.Assert(ioutil.WriteFile(path, []byte(data), 0644), jc.ErrorIsNil)
+	}
+}
+
+func (s *discoverySuite) TestOpen(c *gc.C) {
+	dir, err := ioutil.TempDir("", "discovery")
+	c.Assert(err, jc.ErrorIsNil)
+	defer os.RemoveAll(dir)
+	writeIndex(c, filepath.Join(dir, "index"), testIndex)
+
+	for _, root := range []string{filepath.Join(dir, "index"), "file://" + filepath.ToSlash(filepath.Join(dir, "index"))} {
+		src, err := Open(root, filepath.Join(dir, "cache"), false)
+		c.Assert(err, jc.ErrorIsNil)
+		c.Assert(src, gc.Equals, Dir(filepath.Join(dir, "index")))
+		ix, err := Load(src)
+		c.Assert(err, jc.ErrorIsNil)
+		c.Assert(ix.Items, gc.HasLen, 8)
+	}
+
+	src, err := Open("", filepath.Join(dir, "cache"), true)
+	c.Assert(err, jc.ErrorIsNil)
+	cache, ok := src.(*Cache)
+	c.Assert(ok, jc.IsTrue)
+	c.Assert(cache.Source, gc.DeepEquals, &HTTP{BaseURL: DefaultRoot})
+	c.Assert(cache.Refresh, jc.IsTrue)
+
+	src, err = Open("git@example.com:org/index.git#stable", filepath.Join(dir, "cache"), false)
+	c.Assert(err, jc.ErrorIsNil)
+	repo, ok := src.(*Git)
+	c.Assert(ok, jc.IsTrue)
+	c.Assert(repo.URL, gc.Equals, "git@example.com:org/index.git")
+	c.Assert(repo.Ref, gc.Equals, "stable")
+
+	_, err = Open("ftp://example.com/index", dir, false)
+	c.Assert(err, gc.ErrorMatches, `unsupported discovery root "ftp://example.com/index"`)
+	_, err = Open(filepath.Join(dir, "missing"), dir, false)
+	c.Assert(err, gc.ErrorMatches, "discovery root .*/missing is not a directory")
+}
+
+func (s *discoverySuite) TestGit(c *gc.C) {
+	if _, err := exec.LookPath("git"); err != nil {
+		c.Skip("git is not installed")
+	}
+	dir, err := ioutil.TempDir("", "discovery")
+	c.Assert(err, jc.ErrorIsNil)
+	defer os.RemoveAll(dir)
+
+	repoDir := filepath.Join(dir, "repo")
+	writeIndex(c, repoDir, map[string]string{"bots/lingo_bots.yaml": "owners: [a]\n"})
+	commit := func() {
+		for _, args := range [][]string{
+			{"add", "-A"},
+			{"-c", "user.name=lingo", "-c", "user.email=lingo@example.com", "commit", "-qm", "index"},
+		} {
+			c.Assert(git(repoDir, args...), jc.ErrorIsNil)
+		}
+	}
+	c.Assert(git("", "init", "-q", repoDir), jc.ErrorIsNil)
+	commit()
+
+	src, err := Open("git+file://"+filepath.ToSlash(repoDir), filepath.Join(dir, "cache"), false)
+	c.Assert(err, jc.ErrorIsNil)
+	data, err := src.Read("bots/lingo_bots.yaml")
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(string(data), gc.Equals, "owners: [a]\n")
+	_, err = src.Read("bots/a/lingo_owner.yaml")
+	c.Assert(errors.IsNotFound(err), jc.IsTrue)
+
+	// The clone is reused until it is refreshed.
+	writeIndex(c, repoDir, map[string]string{"bots/lingo_bots.yaml": "owners: [b]\n"})
+	commit()
+	src, err = Open("git+file://"+filepath.ToSlash(repoDir), filepath.Join(dir, "cache"), false)
+	c.Assert(err, jc.ErrorIsNil)
+	data, err = src.Read("bots/lingo_bots.yaml")
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(string(data), gc.Equals, "owners: [a]\n")
+
+	src, err = Open("git+file://"+filepath.ToSlash(repoDir), filepath.Join(dir, "cache"), true)
+	c.Assert(err, jc.ErrorIsNil)
+	data, err = src.Read("bots/lingo_bots.yaml")
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(string(data), gc.Equals, "owners: [b]\n")
+
+	// A stale clone is used when the repository is gone.
+	c.Assert(os.RemoveAll(repoDir), jc.ErrorIsNil)
+	src, err = Open("git+file://"+filepath.ToSlash(repoDir), filepath.Join(dir, "cache"), true)
+	c.Assert(err, jc.ErrorIsNil)
+	data, err = src.Read("bots/lingo_bots.yaml")
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(string(data), gc.Equals, "owners: [b]\n")
+}
+
+func (s *discoverySuite) TestMirror(c *gc.C) {
+	dir, err := ioutil.TempDir("", "discovery")
+	c.Assert(err, jc.ErrorIsNil)
+	defer os.RemoveAll(dir)
+
+	_, err = Load(&Mirror{Source: &files{files: testIndex}, Dir: dir})
+	c.Assert(err, jc.ErrorIsNil)
+
+	ix, err := Load(Dir(dir))
+	c.Assert(err, jc.ErrorIsNil)
+	want, err := Load(&files{files: testIndex})
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(ix.Search(&Query{}), gc.DeepEquals, want.Search(&Query{}))
+}
+
+func (s *discoverySuite) TestProject(c *gc.C) {
+	dir, err := ioutil.TempDir("", "discovery")
+	c.Assert(err, jc.ErrorIsNil)
+	defer os.RemoveAll(dir)
+
+	index := map[string]string{
+		"tenets/codelingo/go/defer-close-file/codelingo.yaml": `
+tenets:
+  - name: defer-close-file
+    actions:
+      codelingo/review:
+        comment: Close the file.
+    query: |
+      import codelingo/ast/go
+`,
+	}
+	for path, data := range testIndex {
+		if _, ok := index[path]; !ok {
+			index[path] = data
+		}
+	}
+	writeIndex(c, dir, map[string]string{
+		"codelingo.yaml": `
+tenets:
+  - import: codelingo/go
+  - name: local
+    doc: A project's own Tenet.
+    bots:
+      acme/notify:
+    query: |
+      import codelingo/ast/go
+      import acme/ast/php
+
+      go.file
+`,
+		"sub/codelingo.yaml": `
+tenets:
+  - import: codelingo/go/defer-close-file
+`,
+	})
+
+	root, sub := filepath.Join(dir, "codelingo.yaml"), filepath.Join(dir, "sub", "codelingo.yaml")
+	ix, err := Project([]string{root, sub}, &files{files: index})
+	c.Assert(err, jc.ErrorIsNil)
+	items := ix.Search(&Query{})
+	c.Assert(fullNames(items), gc.DeepEquals, []string{
+		"tenet codelingo/go/defer-close-file",
+		"tenet codelingo/go/empty-slice",
+		"tenet local",
+		"bundle codelingo/go",
+		"bot acme/notify",
+		"bot codelingo/review",
+		"lexicon acme/ast/php",
+		"lexicon codelingo/ast/go",
+	})
+	c.Assert(items[0].Files, gc.DeepEquals, []string{root, sub})
+	c.Assert(items[2].Description, gc.Equals, "A project's own Tenet.")
+	c.Assert(items[3].Description, gc.Equals, "Best practices for Go.")
+	c.Assert(items[5].Description, gc.Equals, "Comments on pull requests.")
+	c.Assert(items[7].Description, gc.Equals, "The Go AST.")
+
+	writeIndex(c, dir, map[string]string{"codelingo.yaml": "tenets:\n  - import: codelingo\n"})
+	_, err = Project([]string{root}, &files{files: index})
+	c.Assert(err, gc.ErrorMatches, `failed to import codelingo in .*: "codelingo" is not of the form owner/bundle or owner/bundle/tenet`)
+}
+
+func (s *discoverySuite) TestLookupImport(c *gc.C) {
+	src := &files{files: testIndex}
+	item, err := LookupImport(src, "codelingo/go")
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(item, gc.DeepEquals, &Item{
+		Kind:        KindBundle,
+		Owner:       "codelingo",
+		Name:        "go",
+		Description: "Best practices for Go.",
+		Tags:        []string{"go", "golang"},
+	})
+
+	item, err = LookupImport(src, "codelingo/go/empty-slice")
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(item.FullName(), gc.Equals, "codelingo/go/empty-slice")
+	c.Assert(item.Description, gc.Equals, "Declare empty slices as nil.")
+
+	_, err = LookupImport(src, "codelingo/php")
+	c.Assert(errors.IsNotFound(err), jc.IsTrue)
+	_, err = LookupImport(src, "codelingo/go/missing")
+	c.Assert(err, gc.ErrorMatches, "Tenet codelingo/go/missing not found")
+	_, err = LookupImport(src, "codelingo/../go")
+	c.Assert(err, gc.ErrorMatches, `"codelingo/../go" is not of the form owner/bundle or owner/bundle/tenet`)
+}
@@ -0,0 +1,210 @@
+package discovery
+
+import (
+	"io/ioutil"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/juju/errors"
+	"gopkg.in/yaml.v2"
+)
+
+// lexiconImport matches the Lexicons imported by a query.
+var lexiconImport = regexp.MustCompile(`(?m)^\s*import\s+([^/\s]+)/([^/\s]+)/([^/\s]+)\s*$`)
+
+// Project returns the items a project's codelingo.yaml files use: the
+// Tenets they define and import, the bundles they import, and the Bots and
+// Lexicons those Tenets use. Imported Tenets, and the descriptions of what
+// is used, are read from src.
+func Project(dotlingos []string, src Source) (*Index, error) {
+	p := &project{
+		crawler: crawler{src: src},
+		byName:  map[string]*Item{},
+	}
+	for _, path := range dotlingos {
+		data, err := ioutil.ReadFile(path)
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		var dl dotlingo
+		if err := yaml.Unmarshal(data, &dl); err != nil {
+			return nil, errors.Annotatef(err, "failed to parse %s", path)
+		}
+		for _, t := range dl.Tenets {
+			if t.Import == "" {
+				item := &Item{Kind: KindTenet, Name: t.Name}
+				describe(item, t)
+				if err := p.use(item, t, path); err != nil {
+					return nil, errors.Annotatef(err, "failed to read %s", path)
+				}
+				continue
+			}
+			if err := p.imported(t.Import, path); err != nil {
+				return nil, errors.Annotatef(err, "failed to import %s in %s", t.Import, path)
+			}
+		}
+	}
+	return &Index{Items: p.items}, nil
+}
+
+type project struct {
+	crawler
+	byName map[string]*Item
+}
+
+// add records that file uses item, returning false if it was already used.
+func (p *project) add(item *Item, file string) bool {
+	key := string(item.Kind) + " " + item.FullName()
+	if existing, ok := p.byName[key]; ok {
+		if !containsFold(existing.Files, file) {
+			existing.Files = append(existing.Files, file)
+		}
+		return false
+	}
+	item.Files = []string{file}
+	p.byName[key] = item
+	p.items = append(p.items, item)
+	return true
+}
+
+// use records that file uses a Tenet, and the Bots and Lexicons it uses.
+func (p *project) use(item *Item, t *dotlingoTenet, file string) error {
+	p.add(item, file)
+
+	var bots []string
+	for name := range t.Actions {
+		bots = append(bots, name)
+	}
+	for name := range t.Bots {
+		bots = append(bots, name)
+	}
+	sort.Strings(bots)
+	for _, bot := range bots {
+		parts := strings.Split(bot, "/")
+		if len(parts) != 2 {
+			continue
+		}
+		bot := &Item{Kind: KindBot, Owner: parts[0], Name: parts[1]}
+		if p.add(bot, file) {
+			if err := p.details(bot, "bots", bot.Owner, bot.Name, "lingo_bot.yaml"); err != nil {
+				return errors.Trace(err)
+			}
+		}
+	}
+
+	for _, match := range lexiconImport.FindAllStringSubmatch(t.Query, -1) {
+		lexicon := &Item{Kind: KindLexicon, Owner: match[1], Type: match[2], Name: match[3]}
+		if p.add(lexicon, file) {
+			if err := p.details(lexicon, "lexicons", lexicon.Type, lexicon.Owner, lexicon.Name, "lingo_lexicon.yaml"); err != nil {
+				return errors.Trace(err)
+			}
+		}
+	}
+	return nil
+}
+
+// LookupImport returns the bundle or Tenet imported by ref, of the form
+// owner/bundle or owner/bundle/tenet. It returns an error satisfying
+// errors.IsNotFound if the index has no such bundle or Tenet.
+func LookupImport(src Source, ref string) (*Item, error) {
+	owner, bundle, tenet, err := splitImport(ref)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	c := &crawler{src: src}
+	var d details
+	found, err := c.read(&d, "tenets", owner, bundle, "lingo_bundle.yaml")
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	if !found {
+		return nil, errors.NotFoundf("bundle %s/%s", owner, bundle)
+	}
+	if tenet == "" {
+		return &Item{Kind: KindBundle, Owner: owner, Name: bundle, Description: d.Description, Tags: d.Tags}, nil
+	}
+
+	listed := false
+	for _, name := range d.Tenets {
+		listed = listed || name == tenet
+	}
+	if !listed {
+		return nil, errors.NotFoundf("Tenet %s", ref)
+	}
+	item := &Item{Kind: KindTenet, Owner: owner, Bundle: bundle, Name: tenet}
+	var dl dotlingo
+	if _, err := c.read(&dl, "tenets", owner, bundle, tenet, "codelingo.yaml"); err != nil {
+		return nil, errors.Trace(err)
+	}
+	describe(item, dl.tenet(tenet))
+	return item, nil
+}
+
+// splitImport splits an import of the form owner/bundle[/tenet].
+func splitImport(ref string) (owner, bundle, tenet string, err error) {
+	parts := strings.Split(ref, "/")
+	for _, part := range parts {
+		if !validName(part) {
+			parts = nil
+		}
+	}
+	switch len(parts) {
+	case 2:
+		return parts[0], parts[1], "", nil
+	case 3:
+		return parts[0], parts[1], parts[2], nil
+	}
+	return "", "", "", errors.Errorf("%q is not of the form owner/bundle or owner/bundle/tenet", ref)
+}
+
+// imported records that file imports a bundle, or a single Tenet of one.
+func (p *project) imported(ref, file string) error {
+	owner, bundle, tenet, err := splitImport(ref)
+	if err != nil {
+		return errors.Trace(err)
+	}
+
+	var d details
+	if _, err := p.read(&d, "tenets", owner, bundle, "lingo_bundle.yaml"); err != nil {
+		return errors.Trace(err)
+	}
+	tenets := []string(d.Tenets)
+	if tenet != "" {
+		tenets = []string{tenet}
+	} else {
+		p.add(&Item{
+			Kind:        KindBundle,
+			Owner:       owner,
+			Name:        bundle,
+			Description: d.Description,
+			Tags:        d.Tags,
+		}, file)
+	}
+
+	for _, name := range tenets {
+		var dl dotlingo
+		if _, err := p.read(&dl, "tenets", owner, bundle, name, "codelingo.yaml"); err != nil {
+			return errors.Trace(err)
+		}
+		item := &Item{Kind: KindTenet, Owner: owner, Bundle: bundle, Name: name}
+		t := dl.tenet(name)
+		describe(item, t)
+		if err := p.use(item, t, file); err != nil {
+			return errors.Trace(err)
+		}
+	}
+	return nil
+}
+
+// details fills in item's description and tags from the index file at the
+// path joined from elems, if there is one.
+func (p *project) details(item *Item, elems ...string) error {
+	var d details
+	if _, err := p.read(&d, elems...); err != nil {
+		return errors.Trace(err)
+	}
+	item.Description = d.Description
+	item.Tags = d.Tags
+	return nil
+}
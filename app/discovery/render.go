@@ -0,0 +1,62 @@
+package discovery
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/juju/errors"
+)
+
+// The formats items can be rendered in.
+const (
+	FormatTable = "table"
+	FormatJSON  = "json"
+)
+
+// maxDescription is the longest description shown in a table.
+const maxDescription = 60
+
+// Render writes items to w as a table or as JSON.
+func Render(w io.Writer, items []*Item, format string) error {
+	switch format {
+	case FormatJSON:
+		if items == nil {
+			items = []*Item{}
+		}
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return errors.Trace(enc.Encode(items))
+	case FormatTable, "":
+		if len(items) == 0 {
+			_, err := fmt.Fprintln(w, "Nothing found.")
+			return errors.Trace(err)
+		}
+		tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+		fmt.Fprintln(tw, "KIND\tNAME\tDESCRIPTION\tTAGS")
+		for _, item := range items {
+			name := item.FullName()
+			if item.Version != "" {
+				name += "@" + item.Version
+			}
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", item.Kind, name,
+				shorten(item.Description), strings.Join(item.Tags, ","))
+		}
+		return errors.Trace(tw.Flush())
+	}
+	return errors.Errorf("unknown format %q, expected %s or %s", format, FormatTable, FormatJSON)
+}
+
+// shorten returns the first line of s, truncated to fit in a table.
+func shorten(s string) string {
+	s = strings.TrimSpace(s)
+	if i := strings.IndexByte(s, '\n'); i >= 0 {
+		s = s[:i]
+	}
+	if r := []rune(s); len(r) > maxDescription {
+		s = string(r[:maxDescription-3]) + "..."
+	}
+	return s
+}
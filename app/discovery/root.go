@@ -0,0 +1,190 @@
+package discovery
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/juju/errors"
+)
+
+// Open returns the Source for the index at root, which is one of:
+//
+//	https://example.com/index/       a web server
+//	git+https://example.com/index    a git repository, as is any URL ending
+//	git@example.com:org/index.git    in .git. A branch or tag may follow,
+//	                                 e.g. index.git#stable
+//	/srv/index or file:///srv/index  a local directory
+//
+// Files from web servers and clones of git repositories are kept in
+// cacheDir. If refresh is set, they are read again.
+func Open(root, cacheDir string, refresh bool) (Source, error) {
+	if root == "" {
+		root = DefaultRoot
+	}
+	key := sha256.Sum256([]byte(root))
+	cached := filepath.Join(cacheDir, hex.EncodeToString(key[:6]))
+
+	if url, ref, ok := gitRoot(root); ok {
+		return &Git{
+			URL:     url,
+			Ref:     ref,
+			Dir:     cached,
+			TTL:     DefaultCacheTTL,
+			Refresh: refresh,
+		}, nil
+	}
+	if strings.HasPrefix(root, "http://") || strings.HasPrefix(root, "https://") {
+		return &Cache{
+			Source:  &HTTP{BaseURL: root},
+			Dir:     cached,
+			TTL:     DefaultCacheTTL,
+			Refresh: refresh,
+		}, nil
+	}
+	if strings.Contains(root, "://") && !strings.HasPrefix(root, "file://") {
+		return nil, errors.Errorf("unsupported discovery root %q", root)
+	}
+
+	dir := filepath.FromSlash(strings.TrimPrefix(root, "file://"))
+	if info, err := os.Stat(dir); err != nil || !info.IsDir() {
+		return nil, errors.Errorf("discovery root %s is not a directory", dir)
+	}
+	return Dir(dir), nil
+}
+
+// gitRoot returns the URL and ref of root if it is a git repository.
+func gitRoot(root string) (url, ref string, ok bool) {
+	url = root
+	if i := strings.LastIndex(url, "#"); i >= 0 {
+		url, ref = url[:i], url[i+1:]
+	}
+	switch {
+	case strings.HasPrefix(url, "git+"):
+		return strings.TrimPrefix(url, "git+"), ref, true
+	case strings.HasSuffix(url, ".git"):
+		return url, ref, true
+	}
+	return "", "", false
+}
+
+// Dir is a Source which reads the index from a local directory.
+type Dir string
+
+func (d Dir) Read(path string) ([]byte, error) {
+	data, err := ioutil.ReadFile(filepath.Join(string(d), filepath.FromSlash(path)))
+	if os.IsNotExist(err) {
+		return nil, errors.NotFoundf("%s", path)
+	}
+	return data, errors.Trace(err)
+}
+
+// Git is a Source which reads the index from a shallow clone of a git
+// repository in Dir. The clone is updated when it is older than TTL, unless
+// the repository can't be reached, in which case it is used anyway.
+type Git struct {
+	URL string
+	// Ref is the branch or tag to read, or empty for the default branch.
+	Ref     string
+	Dir     string
+	TTL     time.Duration
+	Refresh bool
+
+	synced bool
+}
+
+// syncedFile records when the clone was last updated.
+const syncedFile = ".lingo-synced"
+
+func (g *Git) Read(path string) ([]byte, error) {
+	if !g.synced {
+		if err := g.sync(); err != nil {
+			return nil, errors.Trace(err)
+		}
+		g.synced = true
+	}
+	return Dir(g.Dir).Read(path)
+}
+
+func (g *Git) sync() error {
+	stamp := filepath.Join(g.Dir, ".git", syncedFile)
+	info, err := os.Stat(stamp)
+	if err == nil && !g.Refresh && time.Since(info.ModTime()) < g.TTL {
+		return nil
+	}
+
+	if _, statErr := os.Stat(filepath.Join(g.Dir, ".git")); statErr != nil {
+		if err := g.clone(); err != nil {
+			return errors.Trace(err)
+		}
+	} else if err := g.pull(); err != nil {
+		// The stale clone is better than nothing, e.g. when offline.
+		return nil
+	}
+	return errors.Trace(ioutil.WriteFile(stamp, nil, 0644))
+}
+
+func (g *Git) clone() error {
+	tmp := g.Dir + ".tmp"
+	os.RemoveAll(tmp)
+	if err := os.MkdirAll(filepath.Dir(tmp), 0755); err != nil {
+		return errors.Trace(err)
+	}
+	args := []string{"clone", "--quiet", "--depth", "1"}
+	if g.Ref != "" {
+		args = append(args, "--branch", g.Ref)
+	}
+	if err := git("", append(args, g.URL, tmp)...); err != nil {
+		os.RemoveAll(tmp)
+		return errors.Annotatef(err, "failed to clone %s", g.URL)
+	}
+	os.RemoveAll(g.Dir)
+	return errors.Trace(os.Rename(tmp, g.Dir))
+}
+
+func (g *Git) pull() error {
+	ref := g.Ref
+	if ref == "" {
+		ref = "HEAD"
+	}
+	if err := git(g.Dir, "fetch", "--quiet", "--depth", "1", "origin", ref); err != nil {
+		return errors.Annotatef(err, "failed to update %s", g.URL)
+	}
+	return errors.Trace(git(g.Dir, "reset", "--quiet", "--hard", "FETCH_HEAD"))
+}
+
+func git(dir string, args ...string) error {
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	// Never prompt for credentials, which would hang lingo.
+	cmd.Env = append(os.Environ(), "GIT_TERMINAL_PROMPT=0")
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return errors.Errorf("git %s: %s", strings.Join(args, " "), strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+// Mirror is a Source which writes every file it reads from Source under
+// Dir, so that loading an index through it leaves a copy of the index in
+// Dir.
+type Mirror struct {
+	Source Source
+	Dir    string
+}
+
+func (m *Mirror) Read(path string) ([]byte, error) {
+	data, err := m.Source.Read(path)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	dest := filepath.Join(m.Dir, filepath.FromSlash(path))
+	if err := os.MkdirAll(filepath.Dir(dest), 0755); err != nil {
+		return nil, errors.Trace(err)
+	}
+	return data, errors.Trace(ioutil.WriteFile(dest, data, 0644))
+}
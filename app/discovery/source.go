@@ -0,0 +1,128 @@
+package discovery
+
+import (
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/juju/errors"
+)
+
+// DefaultRoot is the root of the index published by CodeLingo.
+const DefaultRoot = "https://raw.githubusercontent.com/codelingo/codelingo/master/"
+
+// DefaultCacheTTL is how long files read from the index are reused before
+// they are read again.
+const DefaultCacheTTL = 24 * time.Hour
+
+// Source provides the files of the index.
+type Source interface {
+	// Read returns the file at path, which is slash separated and relative
+	// to the index's root. It returns an error satisfying
+	// errors.IsNotFound if the index has no such file.
+	Read(path string) ([]byte, error)
+}
+
+// HTTP is a Source which reads the index from a web server.
+type HTTP struct {
+	// BaseURL is the URL of the index's root.
+	BaseURL string
+}
+
+func (h *HTTP) Read(path string) ([]byte, error) {
+	url := strings.TrimSuffix(h.BaseURL, "/") + "/" + path
+	resp, err := http.Get(url)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotFound:
+		return nil, errors.NotFoundf("%s", url)
+	default:
+		return nil, errors.Errorf("failed to read %s: %s", url, resp.Status)
+	}
+	data, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, errors.Annotatef(err, "failed to read %s", url)
+	}
+	return data, nil
+}
+
+// Cache is a Source which keeps the files read from another Source in a
+// directory. Files older than TTL are read again, unless the Source can't
+// be read, in which case they are used anyway.
+type Cache struct {
+	Source Source
+	Dir    string
+	TTL    time.Duration
+	// Refresh reads every file from the Source again.
+	Refresh bool
+}
+
+// notFoundSuffix marks files the Source doesn't have.
+const notFoundSuffix = ".notfound"
+
+func (c *Cache) Read(path string) ([]byte, error) {
+	cached := filepath.Join(c.Dir, filepath.FromSlash(path))
+	data, fresh, err := c.cached(cached)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	if fresh && !c.Refresh {
+		if data == nil {
+			return nil, errors.NotFoundf("%s", path)
+		}
+		return data, nil
+	}
+
+	read, err := c.Source.Read(path)
+	switch {
+	case errors.IsNotFound(err):
+		return nil, c.store(cached+notFoundSuffix, nil, err)
+	case err != nil:
+		if data != nil {
+			// Stale files are better than none, e.g. when offline.
+			return data, nil
+		}
+		return nil, errors.Trace(err)
+	}
+	os.Remove(cached + notFoundSuffix)
+	return read, c.store(cached, read, nil)
+}
+
+// cached returns the cached file at path, or nil if the Source doesn't have
+// it, and whether it is fresh.
+func (c *Cache) cached(path string) ([]byte, bool, error) {
+	if info, err := os.Stat(path + notFoundSuffix); err == nil {
+		return nil, time.Since(info.ModTime()) < c.TTL, nil
+	}
+	info, err := os.Stat(path)
+	if os.IsNotExist(err) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, errors.Trace(err)
+	}
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, false, errors.Trace(err)
+	}
+	return data, time.Since(info.ModTime()) < c.TTL, nil
+}
+
+// store caches data at path, returning result.
+func (c *Cache) store(path string, data []byte, result error) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return errors.Trace(err)
+	}
+	if err := ioutil.WriteFile(path, data, 0644); err != nil {
+		return errors.Trace(err)
+	}
+	return result
+}
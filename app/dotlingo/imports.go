@@ -0,0 +1,227 @@
+// Package dotlingo finds codelingo.yaml files and edits them in place,
+// leaving their comments and formatting untouched.
+package dotlingo
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/juju/errors"
+	"gopkg.in/yaml.v2"
+)
+
+var (
+	// tenetsKeyRegexp matches the top level "tenets:" key.
+	tenetsKeyRegexp = regexp.MustCompile(`^tenets:\s*(\[\s*\])?\s*(#.*)?$`)
+	// itemRegexp matches the first line of an item of a block sequence.
+	itemRegexp = regexp.MustCompile(`^(\s*)-(\s+|$)`)
+	// importRegexp matches an item importing Tenets.
+	importRegexp = regexp.MustCompile(`^(\s*)-\s+import:\s*(?:"([^"]*)"|'([^']*)'|([^\s#]+))\s*(#.*)?$`)
+)
+
+// Imports returns the bundles and Tenets imported by a codelingo.yaml file.
+func Imports(src []byte) ([]string, error) {
+	var file struct {
+		Tenets []struct {
+			Import string `yaml:"import"`
+		} `yaml:"tenets"`
+	}
+	if err := yaml.Unmarshal(src, &file); err != nil {
+		return nil, errors.Trace(err)
+	}
+	var imports []string
+	for _, t := range file.Tenets {
+		if t.Import != "" {
+			imports = append(imports, t.Import)
+		}
+	}
+	return imports, nil
+}
+
+// Overlapping returns the one of imports which already imports ref, of the
+// form owner/bundle or owner/bundle/tenet, or imports part of it. It returns
+// an empty string if there is none.
+func Overlapping(imports []string, ref string) string {
+	for _, imp := range imports {
+		if imp == ref || strings.HasPrefix(ref, imp+"/") || strings.HasPrefix(imp, ref+"/") {
+			return imp
+		}
+	}
+	return ""
+}
+
+// AddImport returns src with an item importing ref appended to the imports
+// of its "tenets" list, creating the list if there is none.
+func AddImport(src []byte, ref string) ([]byte, error) {
+	imports, err := Imports(src)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	if imp := Overlapping(imports, ref); imp != "" {
+		return nil, errors.Errorf("%s overlaps the existing import of %s", ref, imp)
+	}
+
+	lines := splitLines(src)
+	key := tenetsKey(lines)
+	if key < 0 {
+		if len(lines) > 0 && lines[len(lines)-1] == "" {
+			lines = lines[:len(lines)-1]
+		}
+		lines = append(lines, "tenets:", "  - import: "+ref, "")
+	} else {
+		if m := tenetsKeyRegexp.FindStringSubmatch(lines[key]); m[1] != "" {
+			// Replace an empty flow sequence, "tenets: []", with a block.
+			lines[key] = strings.TrimSpace("tenets: " + m[2])
+		}
+		indent, at := importPosition(lines, key)
+		lines = insert(lines, at, indent+"- import: "+ref)
+	}
+
+	out := []byte(strings.Join(lines, "\n"))
+	return out, errors.Trace(check(out, append(imports, ref)))
+}
+
+// RemoveImport returns src without the item importing ref.
+func RemoveImport(src []byte, ref string) ([]byte, error) {
+	imports, err := Imports(src)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	var remaining []string
+	for _, imp := range imports {
+		if imp != ref {
+			remaining = append(remaining, imp)
+		}
+	}
+	if len(remaining) == len(imports) {
+		if imp := Overlapping(imports, ref); imp != "" {
+			return nil, errors.Errorf("%s is not imported itself, but %s is", ref, imp)
+		}
+		return nil, errors.Errorf("%s is not imported", ref)
+	}
+
+	lines := splitLines(src)
+	key := tenetsKey(lines)
+	if key < 0 {
+		return nil, errors.New("could not find the tenets list")
+	}
+	end := blockEnd(lines, key)
+	for i := end - 1; i > key; i-- {
+		m := importRegexp.FindStringSubmatch(lines[i])
+		if m == nil || m[2]+m[3]+m[4] != ref {
+			continue
+		}
+		lines = append(lines[:i], lines[itemEnd(lines, i, end):]...)
+	}
+
+	out := []byte(strings.Join(lines, "\n"))
+	return out, errors.Trace(check(out, remaining))
+}
+
+// importPosition returns the indentation of the items of the tenets list
+// starting on line key, and the line to insert an import at: after the last
+// import, or before the first Tenet and the comments above it.
+func importPosition(lines []string, key int) (string, int) {
+	end := blockEnd(lines, key)
+	indent, first, at := "  ", -1, -1
+	for i := key + 1; i < end; i++ {
+		m := itemRegexp.FindStringSubmatch(lines[i])
+		if m == nil {
+			continue
+		}
+		if first < 0 {
+			indent, first = m[1], i
+		}
+		// Skip the items of lists nested in Tenets.
+		if m[1] != indent {
+			continue
+		}
+		if importRegexp.MatchString(lines[i]) {
+			at = itemEnd(lines, i, end)
+		}
+	}
+
+	switch {
+	case at >= 0:
+		return indent, at
+	case first >= 0:
+		for first-1 > key && isComment(lines[first-1]) {
+			first--
+		}
+		return indent, first
+	}
+	return indent, key + 1
+}
+
+func splitLines(src []byte) []string {
+	if len(src) == 0 {
+		return nil
+	}
+	return strings.Split(string(src), "\n")
+}
+
+func insert(lines []string, at int, line string) []string {
+	lines = append(lines, "")
+	copy(lines[at+1:], lines[at:])
+	lines[at] = line
+	return lines
+}
+
+// tenetsKey returns the line of the top level "tenets:" key, or -1.
+func tenetsKey(lines []string) int {
+	for i, l := range lines {
+		if tenetsKeyRegexp.MatchString(l) {
+			return i
+		}
+	}
+	return -1
+}
+
+// blockEnd returns the line after the value of the top level key on line
+// key, which is the next line starting another top level key.
+func blockEnd(lines []string, key int) int {
+	for i := key + 1; i < len(lines); i++ {
+		l := lines[i]
+		if l == "" || isComment(l) || l[0] == ' ' || l[0] == '\t' || itemRegexp.MatchString(l) {
+			continue
+		}
+		return i
+	}
+	return len(lines)
+}
+
+// itemEnd returns the line after the item of a sequence starting on line
+// start, ignoring trailing blank lines and comments.
+func itemEnd(lines []string, start, end int) int {
+	indent := len(itemRegexp.FindStringSubmatch(lines[start])[1])
+	last := start
+	for i := start + 1; i < end; i++ {
+		l := lines[i]
+		if strings.TrimSpace(l) == "" || isComment(l) {
+			continue
+		}
+		ind := len(l) - len(strings.TrimLeft(l, " "))
+		if ind < indent || (ind == indent && itemRegexp.MatchString(l)) {
+			break
+		}
+		last = i
+	}
+	return last + 1
+}
+
+func isComment(l string) bool {
+	return strings.HasPrefix(strings.TrimSpace(l), "#")
+}
+
+// check verifies that an edited file imports exactly the expected bundles
+// and Tenets, in case it was laid out in a way the edit didn't expect.
+func check(src []byte, want []string) error {
+	got, err := Imports(src)
+	if err != nil {
+		return errors.Annotate(err, "could not edit the file without breaking it")
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		return errors.New("could not edit the file safely. Edit its imports by hand")
+	}
+	return nil
+}
@@ -0,0 +1,137 @@
+package dotlingo
+
+import (
+	"testing"
+
+	jc "github.com/juju/testing/checkers"
+	gc "gopkg.in/check.v1"
+)
+
+func Test(t *testing.T) {
+	gc.TestingT(t)
+}
+
+type dotlingoSuite struct{}
+
+var _ = gc.Suite(&dotlingoSuite{})
+
+func (s *dotlingoSuite) TestAddImport(c *gc.C) {
+	for _, t := range []struct {
+		about string
+		src   string
+		want  string
+	}{{
+		about: "no file",
+		src:   "",
+		want:  "tenets:\n  - import: codelingo/go\n",
+	}, {
+		about: "no tenets",
+		src:   "# My Tenets.\n",
+		want:  "# My Tenets.\ntenets:\n  - import: codelingo/go\n",
+	}, {
+		about: "empty flow sequence",
+		src:   "tenets: [] # none yet\n",
+		want:  "tenets: # none yet\n  - import: codelingo/go\n",
+	}, {
+		about: "after the last import",
+		src: `# Imported Tenets.
+tenets:
+    - import: codelingo/rust  # for the CLI
+    # Local Tenets.
+    - name: local
+      tags:
+        - import
+      query: |
+        go.file
+    - import: "codelingo/php"
+
+    - name: other
+`,
+		want: `# Imported Tenets.
+tenets:
+    - import: codelingo/rust  # for the CLI
+    # Local Tenets.
+    - name: local
+      tags:
+        - import
+      query: |
+        go.file
+    - import: "codelingo/php"
+    - import: codelingo/go
+
+    - name: other
+`,
+	}, {
+		about: "before the first Tenet and its comments",
+		src: `tenets:
+# Finds things.
+- name: local
+  query: |
+    go.file
+other: value
+`,
+		want: `tenets:
+- import: codelingo/go
+# Finds things.
+- name: local
+  query: |
+    go.file
+other: value
+`,
+	}, {
+		about: "an empty list followed by another key",
+		src:   "tenets:\nother: value\n",
+		want:  "tenets:\n  - import: codelingo/go\nother: value\n",
+	}} {
+		c.Logf("%s", t.about)
+		out, err := AddImport([]byte(t.src), "codelingo/go")
+		c.Assert(err, jc.ErrorIsNil)
+		c.Check(string(out), gc.Equals, t.want)
+	}
+}
+
+func (s *dotlingoSuite) TestAddImportDuplicate(c *gc.C) {
+	src := []byte("tenets:\n  - import: codelingo/go\n")
+	_, err := AddImport(src, "codelingo/go")
+	c.Assert(err, gc.ErrorMatches, "codelingo/go overlaps the existing import of codelingo/go")
+	_, err = AddImport(src, "codelingo/go/empty-slice")
+	c.Assert(err, gc.ErrorMatches, "codelingo/go/empty-slice overlaps the existing import of codelingo/go")
+	_, err = AddImport([]byte("tenets:\n  - import: codelingo/go/empty-slice\n"), "codelingo/go")
+	c.Assert(err, gc.ErrorMatches, "codelingo/go overlaps the existing import of codelingo/go/empty-slice")
+
+	_, err = AddImport([]byte("tenets: [{import: codelingo/rust}]\n"), "codelingo/go")
+	c.Assert(err, gc.ErrorMatches, "could not edit the file safely. Edit its imports by hand")
+	_, err = AddImport([]byte("tenets: {\n"), "codelingo/go")
+	c.Assert(err, gc.ErrorMatches, "yaml: .*")
+}
+
+func (s *dotlingoSuite) TestRemoveImport(c *gc.C) {
+	src := `tenets:
+  # The Go bundle.
+  - import: codelingo/go # comment
+  - import: 'codelingo/go/empty-slice'
+  - name: local
+    query: |
+      import codelingo/go
+`
+	out, err := RemoveImport([]byte(src), "codelingo/go")
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(string(out), gc.Equals, `tenets:
+  # The Go bundle.
+  - import: 'codelingo/go/empty-slice'
+  - name: local
+    query: |
+      import codelingo/go
+`)
+
+	out, err = RemoveImport(out, "codelingo/go/empty-slice")
+	c.Assert(err, jc.ErrorIsNil)
+	imports, err := Imports(out)
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(imports, gc.HasLen, 0)
+
+	_, err = RemoveImport([]byte(src), "codelingo/rust")
+	c.Assert(err, gc.ErrorMatches, "codelingo/rust is not imported")
+	_, err = RemoveImport([]byte("tenets:\n  - import: codelingo/go\n"), "codelingo/go/empty-slice")
+	c.Assert(err, gc.ErrorMatches, "codelingo/go/empty-slice is not imported itself, but codelingo/go is")
+}
@@ -0,0 +1,34 @@
+package dotlingo
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/codelingo/lingo/app/util/common"
+	"github.com/juju/errors"
+)
+
+// Nearest returns the codelingo.yaml file in dir or the closest of its
+// parents, looking no further than root if it is one of them. It returns a
+// NotFound error if there is none.
+func Nearest(dir, root string) (string, error) {
+	var names []string
+	for name := range common.LingoFilenames {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for {
+		for _, name := range names {
+			path := filepath.Join(dir, name)
+			if _, err := os.Stat(path); err == nil {
+				return path, nil
+			}
+		}
+		if dir == root || dir == filepath.Dir(dir) {
+			return "", errors.NotFoundf("codelingo.yaml file")
+		}
+		dir = filepath.Dir(dir)
+	}
+}
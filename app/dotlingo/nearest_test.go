@@ -0,0 +1,34 @@
+package dotlingo
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/juju/errors"
+	jc "github.com/juju/testing/checkers"
+	gc "gopkg.in/check.v1"
+)
+
+func (s *dotlingoSuite) TestNearest(c *gc.C) {
+	root := c.MkDir()
+	sub := filepath.Join(root, "a", "b")
+	c.Assert(os.MkdirAll(sub, 0755), jc.ErrorIsNil)
+	top := filepath.Join(root, "codelingo.yaml")
+	c.Assert(ioutil.WriteFile(top, nil, 0644), jc.ErrorIsNil)
+
+	path, err := Nearest(sub, "")
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(path, gc.Equals, top)
+
+	// The closest one wins, whichever of its names it has.
+	near := filepath.Join(root, "a", "codelingo.yml")
+	c.Assert(ioutil.WriteFile(near, nil, 0644), jc.ErrorIsNil)
+	path, err = Nearest(sub, "")
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(path, gc.Equals, near)
+
+	// Nothing above root is looked at.
+	_, err = Nearest(sub, sub)
+	c.Assert(errors.IsNotFound(err), jc.IsTrue)
+}
@@ -0,0 +1,101 @@
+package dotlingo
+
+import (
+	"strings"
+
+	"github.com/juju/errors"
+	"gopkg.in/yaml.v2"
+)
+
+// TenetNames returns the names of the Tenets written in a codelingo.yaml
+// file, leaving out its imports.
+func TenetNames(src []byte) ([]string, error) {
+	var file struct {
+		Tenets []struct {
+			Name string `yaml:"name"`
+		} `yaml:"tenets"`
+	}
+	if err := yaml.Unmarshal(src, &file); err != nil {
+		return nil, errors.Trace(err)
+	}
+	var names []string
+	for _, t := range file.Tenets {
+		if t.Name != "" {
+			names = append(names, t.Name)
+		}
+	}
+	return names, nil
+}
+
+// AddTenet returns src with tenet, an item of the "tenets" list indented by
+// two spaces, appended to the list, creating the list if there is none.
+func AddTenet(src []byte, tenet string) ([]byte, error) {
+	before, err := countTenets(src)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+
+	lines := splitLines(src)
+	item := strings.Split(strings.TrimSuffix(tenet, "\n"), "\n")
+	key := tenetsKey(lines)
+	if key < 0 {
+		if len(lines) > 0 && lines[len(lines)-1] == "" {
+			lines = lines[:len(lines)-1]
+		}
+		lines = append(append(append(lines, "tenets:"), item...), "")
+	} else {
+		if m := tenetsKeyRegexp.FindStringSubmatch(lines[key]); m[1] != "" {
+			// Replace an empty flow sequence, "tenets: []", with a block.
+			lines[key] = strings.TrimSpace("tenets: " + m[2])
+		}
+		indent, at := tenetPosition(lines, key)
+		for i := len(item) - 1; i >= 0; i-- {
+			l := item[i]
+			if l != "" {
+				l = indent + strings.TrimPrefix(l, "  ")
+			}
+			lines = insert(lines, at, l)
+		}
+	}
+
+	out := []byte(strings.Join(lines, "\n"))
+	after, err := countTenets(out)
+	if err != nil {
+		return nil, errors.Annotate(err, "could not edit the file without breaking it")
+	}
+	if after != before+1 {
+		return nil, errors.New("could not edit the file safely. Add the Tenet by hand")
+	}
+	return out, nil
+}
+
+// tenetPosition returns the indentation of the items of the tenets list
+// starting on line key, and the line after its last item.
+func tenetPosition(lines []string, key int) (string, int) {
+	end := blockEnd(lines, key)
+	indent, first, at := "  ", -1, key+1
+	for i := key + 1; i < end; i++ {
+		m := itemRegexp.FindStringSubmatch(lines[i])
+		if m == nil {
+			continue
+		}
+		if first < 0 {
+			indent, first = m[1], i
+		}
+		// Skip the items of lists nested in Tenets.
+		if m[1] == indent {
+			at = itemEnd(lines, i, end)
+		}
+	}
+	return indent, at
+}
+
+func countTenets(src []byte) (int, error) {
+	var file struct {
+		Tenets []interface{} `yaml:"tenets"`
+	}
+	if err := yaml.Unmarshal(src, &file); err != nil {
+		return 0, errors.Trace(err)
+	}
+	return len(file.Tenets), nil
+}
@@ -0,0 +1,68 @@
+package dotlingo
+
+import (
+	jc "github.com/juju/testing/checkers"
+	gc "gopkg.in/check.v1"
+)
+
+const testTenet = `  - name: new
+    query: |
+      go.file
+`
+
+func (s *dotlingoSuite) TestAddTenet(c *gc.C) {
+	for _, t := range []struct {
+		about string
+		src   string
+		want  string
+	}{{
+		about: "no tenets",
+		src:   "# My Tenets.\n",
+		want:  "# My Tenets.\ntenets:\n  - name: new\n    query: |\n      go.file\n",
+	}, {
+		about: "empty flow sequence",
+		src:   "tenets: []\n",
+		want:  "tenets:\n  - name: new\n    query: |\n      go.file\n",
+	}, {
+		about: "before another key, with the list's indentation",
+		src: `tenets:
+- import: codelingo/go
+- name: local
+  tags:
+  - find
+
+  query: |
+    go.file
+# The rest.
+other: value
+`,
+		want: `tenets:
+- import: codelingo/go
+- name: local
+  tags:
+  - find
+
+  query: |
+    go.file
+- name: new
+  query: |
+    go.file
+# The rest.
+other: value
+`,
+	}} {
+		c.Logf("%s", t.about)
+		out, err := AddTenet([]byte(t.src), testTenet)
+		c.Assert(err, jc.ErrorIsNil)
+		c.Check(string(out), gc.Equals, t.want)
+	}
+
+	_, err := AddTenet([]byte("tenets: [{import: codelingo/go}]\n"), testTenet)
+	c.Assert(err, gc.ErrorMatches, "could not edit the file .*")
+}
+
+func (s *dotlingoSuite) TestTenetNames(c *gc.C) {
+	names, err := TenetNames([]byte("tenets:\n  - import: codelingo/go\n  - name: a\n  - name: b\n"))
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(names, jc.DeepEquals, []string{"a", "b"})
+}
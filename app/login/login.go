@@ -0,0 +1,271 @@
+// Package login signs lingo in to CodeLingo with the OAuth 2.0 device
+// authorization grant (RFC 8628): lingo asks the website for a code, the
+// user approves it in their browser, and lingo polls until the website
+// hands over a token.
+package login
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/juju/errors"
+)
+
+// ClientID identifies lingo to the website.
+const ClientID = "lingo"
+
+// The endpoints of the flow, relative to the website's address.
+const (
+	DeviceCodePath = "/oauth/device/code"
+	TokenPath      = "/oauth/token"
+	RevokePath     = "/oauth/revoke"
+)
+
+const deviceCodeGrant = "urn:ietf:params:oauth:grant-type:device_code"
+
+var (
+	// ErrDenied is returned when the user declines the sign in.
+	ErrDenied = errors.New("the sign in was declined")
+	// ErrExpired is returned when the code, or a refresh token, expires
+	// before it is used.
+	ErrExpired = errors.New("the sign in expired. Please try again")
+	// ErrUnsupported is returned when the website doesn't offer the flow.
+	ErrUnsupported = errors.New("the website does not support signing in from the browser")
+)
+
+// Client runs the flow against a CodeLingo website.
+type Client struct {
+	// BaseURL is the website's address, e.g. https://www.codelingo.io.
+	BaseURL string
+
+	// HTTP makes the requests. It defaults to http.DefaultClient.
+	HTTP *http.Client
+
+	// After waits between polls. It defaults to time.After.
+	After func(time.Duration) <-chan time.Time
+}
+
+// DeviceCode is the code the user approves.
+type DeviceCode struct {
+	DeviceCode string `json:"device_code"`
+	// UserCode is the code the user is asked to confirm.
+	UserCode        string `json:"user_code"`
+	VerificationURI string `json:"verification_uri"`
+	// VerificationURIComplete includes the user code, so it needn't be
+	// typed.
+	VerificationURIComplete string `json:"verification_uri_complete"`
+	ExpiresIn               int    `json:"expires_in"`
+	// Interval is the number of seconds to wait between polls.
+	Interval int `json:"interval"`
+}
+
+// Token is the result of a successful sign in.
+type Token struct {
+	AccessToken  string `json:"access_token"`
+	RefreshToken string `json:"refresh_token"`
+	// ExpiresIn is the number of seconds the access token is valid for,
+	// or zero if it doesn't expire.
+	ExpiresIn int `json:"expires_in"`
+	// Username is the signed in user's CodeLingo username.
+	Username string `json:"username"`
+
+	// Expiry is when the access token expires, or zero if it doesn't.
+	Expiry time.Time `json:"-"`
+}
+
+// tokenError is the error response of the token endpoint.
+type tokenError struct {
+	Code        string `json:"error"`
+	Description string `json:"error_description"`
+}
+
+// RequestCode starts a sign in, returning the code for the user to
+// approve.
+func (c *Client) RequestCode(ctx context.Context) (*DeviceCode, error) {
+	var code DeviceCode
+	status, err := c.post(ctx, DeviceCodePath, url.Values{"client_id": {ClientID}}, &code, nil)
+	if err != nil {
+		if status == http.StatusNotFound {
+			return nil, ErrUnsupported
+		}
+		return nil, errors.Trace(err)
+	}
+	if code.DeviceCode == "" || code.VerificationURI == "" {
+		return nil, errors.New("the website returned an incomplete sign in code")
+	}
+	if code.Interval <= 0 {
+		code.Interval = 5
+	}
+	return &code, nil
+}
+
+// Poll waits until the user approves or declines code, or it expires.
+func (c *Client) Poll(ctx context.Context, code *DeviceCode) (*Token, error) {
+	interval := time.Duration(code.Interval) * time.Second
+	var expired <-chan time.Time
+	if code.ExpiresIn > 0 {
+		expired = c.after(time.Duration(code.ExpiresIn) * time.Second)
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil, errors.Trace(ctx.Err())
+		case <-expired:
+			return nil, ErrExpired
+		case <-c.after(interval):
+		}
+
+		token, tokenErr, err := c.token(ctx, url.Values{
+			"grant_type":  {deviceCodeGrant},
+			"device_code": {code.DeviceCode},
+			"client_id":   {ClientID},
+		})
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		if tokenErr == nil {
+			return token, nil
+		}
+		switch tokenErr.Code {
+		case "authorization_pending":
+		case "slow_down":
+			interval += 5 * time.Second
+		case "access_denied":
+			return nil, ErrDenied
+		case "expired_token":
+			return nil, ErrExpired
+		default:
+			return nil, tokenErr
+		}
+	}
+}
+
+// Refresh exchanges a refresh token for a new access token.
+func (c *Client) Refresh(ctx context.Context, refreshToken string) (*Token, error) {
+	token, tokenErr, err := c.token(ctx, url.Values{
+		"grant_type":    {"refresh_token"},
+		"refresh_token": {refreshToken},
+		"client_id":     {ClientID},
+	})
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	if tokenErr != nil {
+		if tokenErr.Code == "invalid_grant" || tokenErr.Code == "expired_token" {
+			return nil, ErrExpired
+		}
+		return nil, tokenErr
+	}
+	if token.RefreshToken == "" {
+		// The refresh token may be reused.
+		token.RefreshToken = refreshToken
+	}
+	return token, nil
+}
+
+// Revoke invalidates the token's access and refresh tokens on the website
+// (RFC 7009).
+func (c *Client) Revoke(ctx context.Context, token *Token) error {
+	for _, t := range []struct{ token, hint string }{
+		{token.RefreshToken, "refresh_token"},
+		{token.AccessToken, "access_token"},
+	} {
+		if t.token == "" {
+			continue
+		}
+		status, err := c.post(ctx, RevokePath, url.Values{
+			"token":           {t.token},
+			"token_type_hint": {t.hint},
+			"client_id":       {ClientID},
+		}, nil, nil)
+		if status == http.StatusNotFound {
+			return ErrUnsupported
+		}
+		if err != nil {
+			return errors.Trace(err)
+		}
+	}
+	return nil
+}
+
+// token requests a token, returning the error the endpoint responded with
+// if the request was refused.
+func (c *Client) token(ctx context.Context, form url.Values) (*Token, *tokenError, error) {
+	var token Token
+	var tokenErr tokenError
+	status, err := c.post(ctx, TokenPath, form, &token, &tokenErr)
+	if err != nil {
+		if tokenErr.Code != "" {
+			return nil, &tokenErr, nil
+		}
+		if status == http.StatusNotFound {
+			return nil, nil, ErrUnsupported
+		}
+		return nil, nil, errors.Trace(err)
+	}
+	if token.AccessToken == "" {
+		return nil, nil, errors.New("the website returned an empty token")
+	}
+	if token.ExpiresIn > 0 {
+		token.Expiry = time.Now().Add(time.Duration(token.ExpiresIn) * time.Second)
+	}
+	return &token, nil, nil
+}
+
+// post posts form to path, decoding a successful response into result and
+// an unsuccessful one into failure, if they aren't nil.
+func (c *Client) post(ctx context.Context, path string, form url.Values, result, failure interface{}) (int, error) {
+	u := strings.TrimSuffix(c.BaseURL, "/") + path
+	req, err := http.NewRequest(http.MethodPost, u, strings.NewReader(form.Encode()))
+	if err != nil {
+		return 0, errors.Trace(err)
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+
+	client := c.HTTP
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return 0, errors.Trace(err)
+	}
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return resp.StatusCode, errors.Trace(err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		if failure != nil {
+			json.Unmarshal(body, failure)
+		}
+		return resp.StatusCode, errors.Errorf("failed to post to %s: %s", u, resp.Status)
+	}
+	if result == nil {
+		return resp.StatusCode, nil
+	}
+	return resp.StatusCode, errors.Annotatef(json.Unmarshal(body, result), "failed to parse the response from %s", u)
+}
+
+func (c *Client) after(d time.Duration) <-chan time.Time {
+	if c.After != nil {
+		return c.After(d)
+	}
+	return time.After(d)
+}
+
+func (e *tokenError) Error() string {
+	if e.Description != "" {
+		return e.Code + ": " + e.Description
+	}
+	return e.Code
+}
@@ -0,0 +1,162 @@
+package login
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	jc "github.com/juju/testing/checkers"
+	gc "gopkg.in/check.v1"
+)
+
+func Test(t *testing.T) {
+	gc.TestingT(t)
+}
+
+type loginSuite struct{}
+
+var _ = gc.Suite(&loginSuite{})
+
+// fakeWebsite answers token requests with responses, in turn, recording
+// the forms it was sent.
+type fakeWebsite struct {
+	responses []interface{}
+	forms     []map[string][]string
+}
+
+func (f *fakeWebsite) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	r.ParseForm()
+	f.forms = append(f.forms, r.PostForm)
+	switch r.URL.Path {
+	case DeviceCodePath:
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"device_code":      "dev-code",
+			"user_code":        "ABCD-EFGH",
+			"verification_uri": "https://example.com/device",
+			"expires_in":       600,
+		})
+	case RevokePath:
+	case TokenPath:
+		resp := f.responses[0]
+		f.responses = f.responses[1:]
+		if e, ok := resp.(*tokenError); ok {
+			w.WriteHeader(http.StatusBadRequest)
+			resp = map[string]string{"error": e.Code}
+		}
+		json.NewEncoder(w).Encode(resp)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// client returns a client of website which doesn't wait between polls,
+// recording the intervals it was asked to wait.
+func client(c *gc.C, website http.Handler, waits *[]time.Duration) *Client {
+	srv := httptest.NewServer(website)
+	c.Assert(srv, gc.NotNil)
+	return &Client{
+		BaseURL: srv.URL + "/",
+		After: func(d time.Duration) <-chan time.Time {
+			ch := make(chan time.Time, 1)
+			// Only poll waits are recorded, not the code's expiry.
+			if d < time.Minute {
+				*waits = append(*waits, d)
+				ch <- time.Now()
+			}
+			return ch
+		},
+	}
+}
+
+func (s *loginSuite) TestSignIn(c *gc.C) {
+	website := &fakeWebsite{responses: []interface{}{
+		&tokenError{Code: "authorization_pending"},
+		&tokenError{Code: "slow_down"},
+		map[string]interface{}{
+			"access_token":  "access",
+			"refresh_token": "refresh",
+			"expires_in":    3600,
+			"username":      "bob",
+		},
+	}}
+	var waits []time.Duration
+	cl := client(c, website, &waits)
+
+	code, err := cl.RequestCode(context.Background())
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(code.UserCode, gc.Equals, "ABCD-EFGH")
+	c.Assert(code.Interval, gc.Equals, 5)
+
+	token, err := cl.Poll(context.Background(), code)
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(token.AccessToken, gc.Equals, "access")
+	c.Assert(token.RefreshToken, gc.Equals, "refresh")
+	c.Assert(token.Username, gc.Equals, "bob")
+	c.Assert(time.Until(token.Expiry) > 59*time.Minute, jc.IsTrue)
+
+	// The interval grows when the website asks lingo to slow down.
+	c.Assert(waits, jc.DeepEquals, []time.Duration{5 * time.Second, 5 * time.Second, 10 * time.Second})
+	c.Assert(website.forms[1]["device_code"], jc.DeepEquals, []string{"dev-code"})
+	c.Assert(website.forms[1]["grant_type"], jc.DeepEquals, []string{deviceCodeGrant})
+}
+
+func (s *loginSuite) TestSignInFails(c *gc.C) {
+	for _, t := range []struct {
+		code string
+		err  string
+	}{
+		{"access_denied", "the sign in was declined"},
+		{"expired_token", "the sign in expired. Please try again"},
+		{"invalid_client", "invalid_client"},
+	} {
+		var waits []time.Duration
+		cl := client(c, &fakeWebsite{responses: []interface{}{&tokenError{Code: t.code}}}, &waits)
+		_, err := cl.Poll(context.Background(), &DeviceCode{DeviceCode: "dev-code", Interval: 1})
+		c.Check(err, gc.ErrorMatches, t.err)
+	}
+}
+
+func (s *loginSuite) TestUnsupported(c *gc.C) {
+	var waits []time.Duration
+	cl := client(c, http.NotFoundHandler(), &waits)
+	_, err := cl.RequestCode(context.Background())
+	c.Assert(err, gc.Equals, ErrUnsupported)
+}
+
+func (s *loginSuite) TestRefresh(c *gc.C) {
+	website := &fakeWebsite{responses: []interface{}{
+		map[string]interface{}{"access_token": "new", "expires_in": 60},
+		&tokenError{Code: "invalid_grant"},
+	}}
+	var waits []time.Duration
+	cl := client(c, website, &waits)
+
+	token, err := cl.Refresh(context.Background(), "refresh")
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(token.AccessToken, gc.Equals, "new")
+	// The refresh token is kept when the website doesn't issue a new one.
+	c.Assert(token.RefreshToken, gc.Equals, "refresh")
+	c.Assert(website.forms[0]["refresh_token"], jc.DeepEquals, []string{"refresh"})
+
+	_, err = cl.Refresh(context.Background(), "refresh")
+	c.Assert(err, gc.Equals, ErrExpired)
+}
+
+func (s *loginSuite) TestRevoke(c *gc.C) {
+	website := &fakeWebsite{}
+	var waits []time.Duration
+	cl := client(c, website, &waits)
+
+	err := cl.Revoke(context.Background(), &Token{AccessToken: "access", RefreshToken: "refresh"})
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(website.forms, gc.HasLen, 2)
+	c.Assert(website.forms[0]["token"], jc.DeepEquals, []string{"refresh"})
+	c.Assert(website.forms[0]["token_type_hint"], jc.DeepEquals, []string{"refresh_token"})
+	c.Assert(website.forms[1]["token"], jc.DeepEquals, []string{"access"})
+
+	cl = client(c, http.NotFoundHandler(), &waits)
+	c.Assert(cl.Revoke(context.Background(), &Token{AccessToken: "access"}), gc.Equals, ErrUnsupported)
+}
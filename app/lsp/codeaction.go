@@ -0,0 +1,216 @@
+package lsp
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/codelingo/lingo/app/clql"
+	"github.com/codelingo/lingo/app/dotlingo"
+	rpc "github.com/codelingo/rpc/service"
+	"github.com/juju/errors"
+)
+
+// generatedTenetName is the name given to tenets generated from a selection.
+const generatedTenetName = "generated-tenet"
+
+func (s *Server) codeActions(params *CodeActionParams) []CodeAction {
+	actions := []CodeAction{}
+	if isDotlingoURI(params.TextDocument.URI) || params.Range.Start == params.Range.End {
+		return actions
+	}
+
+	return append(actions, CodeAction{
+		Title: "Generate a CodeLingo query from the selection",
+		Kind:  "refactor",
+		Command: &Command{
+			Title:     "Generate a CodeLingo query",
+			Command:   GenerateQueryCommand,
+			Arguments: []interface{}{params.TextDocument.URI, params.Range},
+		},
+	})
+}
+
+func (s *Server) executeCommand(ctx context.Context, params *ExecuteCommandParams) (interface{}, error) {
+	if params.Command != GenerateQueryCommand {
+		return nil, &responseError{Code: errInvalidParams, Message: "unknown command: " + params.Command}
+	}
+
+	var uri string
+	var rng Range
+	if len(params.Arguments) != 2 {
+		return nil, &responseError{Code: errInvalidParams, Message: GenerateQueryCommand + " expects a document URI and a range"}
+	}
+	if err := json.Unmarshal(params.Arguments[0], &uri); err != nil {
+		return nil, &responseError{Code: errInvalidParams, Message: err.Error()}
+	}
+	if err := json.Unmarshal(params.Arguments[1], &rng); err != nil {
+		return nil, &responseError{Code: errInvalidParams, Message: err.Error()}
+	}
+
+	query, err := s.generateQuery(ctx, uri, rng)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+
+	path, err := dotlingo.Nearest(filepath.Dir(uriToPath(uri)), "")
+	if errors.IsNotFound(err) {
+		return nil, errors.Trace(s.conn.notify("window/showMessage", &ShowMessageParams{
+			Type:    MessageTypeInfo,
+			Message: "No codelingo.yaml was found for this file. Generated query:\n" + query,
+		}))
+	}
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+
+	text, ok := s.document(pathToURI(path))
+	if !ok {
+		src, err := ioutil.ReadFile(path)
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		text = string(src)
+	}
+
+	// Add the tenet to the end of the tenets list, under a name no other
+	// tenet has.
+	names, err := dotlingo.TenetNames([]byte(text))
+	if err != nil {
+		return nil, errors.Annotate(err, "failed to parse codelingo.yaml")
+	}
+	tenet := clql.RenderTenet(uniqueName(generatedTenetName, names), "This code matches the generated query.", query)
+	out, err := dotlingo.AddTenet([]byte(text), tenet)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+
+	return nil, errors.Trace(s.conn.request("workspace/applyEdit", &ApplyWorkspaceEditParams{
+		Label: "Generate a CodeLingo query",
+		Edit: WorkspaceEdit{Changes: map[string][]TextEdit{
+			pathToURI(path): {textEdit(text, string(out))},
+		}},
+	}))
+}
+
+// uniqueName returns name, numbered if needed to differ from all of names.
+func uniqueName(name string, names []string) string {
+	taken := make(map[string]bool, len(names))
+	for _, n := range names {
+		taken[n] = true
+	}
+	unique := name
+	for i := 2; taken[unique]; i++ {
+		unique = fmt.Sprintf("%s-%d", name, i)
+	}
+	return unique
+}
+
+// textEdit returns the edit which changes text to newText, replacing only
+// the part between what they start and end with.
+func textEdit(text, newText string) TextEdit {
+	start := 0
+	for start < len(text) && start < len(newText) && text[start] == newText[start] {
+		start++
+	}
+	end := 0
+	for end < len(text)-start && end < len(newText)-start && text[len(text)-1-end] == newText[len(newText)-1-end] {
+		end++
+	}
+	// Keep both ends of the edit on character boundaries.
+	for start > 0 && !utf8.RuneStart(text[start]) {
+		start--
+	}
+	for end > 0 && !utf8.RuneStart(text[len(text)-end]) {
+		end--
+	}
+	return TextEdit{
+		Range:   Range{Start: position(text, start), End: position(text, len(text)-end)},
+		NewText: newText[start : len(newText)-end],
+	}
+}
+
+// position returns the position of a byte offset in text.
+func position(text string, offset int) Position {
+	before := text[:offset]
+	line := strings.Count(before, "\n")
+	return Position{Line: line, Character: utf16Len(before[strings.LastIndex(before, "\n")+1:])}
+}
+
+// generateQuery returns a CLQL query matching the given range of a document.
+func (s *Server) generateQuery(ctx context.Context, uri string, rng Range) (string, error) {
+	file := uriToPath(uri)
+	text, ok := s.document(uri)
+	if !ok {
+		src, err := ioutil.ReadFile(file)
+		if err != nil {
+			return "", errors.Trace(err)
+		}
+		text = string(src)
+	}
+
+	start, err := byteOffset(text, rng.Start)
+	if err != nil {
+		return "", errors.Trace(err)
+	}
+	end, err := byteOffset(text, rng.End)
+	if err != nil {
+		return "", errors.Trace(err)
+	}
+	if start > end {
+		start, end = end, start
+	}
+
+	lang, err := clql.LexiconForFile(file, "")
+	if err != nil {
+		return "", errors.Trace(err)
+	}
+
+	reply, err := s.platform.QueryFromOffset(ctx, &rpc.QueryFromOffsetRequest{
+		Lang:     lang,
+		Dir:      filepath.Base(filepath.Dir(file)),
+		Filename: filepath.Base(file),
+		Src:      text,
+		Start:    int64(start),
+		End:      int64(end),
+	})
+	if err != nil {
+		return "", errors.Trace(err)
+	}
+
+	facts, err := renderFacts(reply.Facts)
+	if err != nil {
+		return "", errors.Trace(err)
+	}
+	return clql.Render(lang, facts), nil
+}
+
+// renderFacts converts generated facts to CLQL facts, keeping only the
+// properties of the final facts.
+func renderFacts(genFacts []*rpc.GenFact) ([]*clql.Fact, error) {
+	facts := make([]*clql.Fact, len(genFacts))
+	for i, genFact := range genFacts {
+		children, err := renderFacts(genFact.Children)
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		fact := &clql.Fact{Name: genFact.FactName, Children: children}
+
+		if len(genFact.Children) == 0 {
+			fact.Properties = make(map[string]interface{})
+			for name, prop := range genFact.Properties {
+				val, err := clql.PropertyValue(prop)
+				if err != nil {
+					return nil, errors.Trace(err)
+				}
+				fact.Properties[name] = val
+			}
+		}
+		facts[i] = fact
+	}
+	return facts, nil
+}
@@ -0,0 +1,301 @@
+package lsp
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/codelingo/lingo/app/clql"
+	rpc "github.com/codelingo/rpc/service"
+	"github.com/juju/errors"
+)
+
+// Lexicon is a lexicon imported by a query, e.g. codelingo/ast/go.
+type Lexicon struct {
+	Owner string
+	Name  string
+}
+
+var (
+	importRegexp = regexp.MustCompile(`^import\s+([\w./-]+)`)
+	factRegexp   = regexp.MustCompile(`^@?([\w-]+)\.([\w-]+)`)
+)
+
+// Imports returns the lexicons imported by the given query.
+func Imports(query string) []Lexicon {
+	var paths []string
+	inGroup := false
+	for _, l := range strings.Split(query, "\n") {
+		l = strings.TrimSpace(l)
+		switch {
+		case inGroup && strings.HasPrefix(l, ")"):
+			inGroup = false
+		case inGroup:
+			if fields := strings.Fields(l); len(fields) > 0 && !strings.HasPrefix(l, "#") {
+				paths = append(paths, fields[0])
+			}
+		case strings.HasPrefix(l, "import") && strings.HasPrefix(strings.TrimSpace(strings.TrimPrefix(l, "import")), "("):
+			inGroup = true
+		default:
+			if m := importRegexp.FindStringSubmatch(l); m != nil {
+				paths = append(paths, m[1])
+			}
+		}
+	}
+
+	var lexicons []Lexicon
+	for _, path := range paths {
+		parts := strings.Split(path, "/")
+		if len(parts) < 2 {
+			continue
+		}
+		lexicons = append(lexicons, Lexicon{Owner: parts[0], Name: parts[len(parts)-1]})
+	}
+	return lexicons
+}
+
+func findLexicon(lexicons []Lexicon, name string) (Lexicon, bool) {
+	for _, lex := range lexicons {
+		if lex.Name == name {
+			return lex, true
+		}
+	}
+	return Lexicon{}, false
+}
+
+// blockAt returns the query block containing the given line.
+func blockAt(text string, line int) *clql.QueryBlock {
+	for _, block := range clql.QueryBlocks([]byte(text)) {
+		if line >= block.Start && line <= block.End {
+			return block
+		}
+	}
+	return nil
+}
+
+func (s *Server) completion(ctx context.Context, params *TextDocumentPositionParams) (*CompletionList, error) {
+	list := &CompletionList{Items: []CompletionItem{}}
+
+	text, ok := s.document(params.TextDocument.URI)
+	if !ok || !isDotlingoURI(params.TextDocument.URI) {
+		return list, nil
+	}
+	block := blockAt(text, params.Position.Line)
+	if block == nil {
+		return list, nil
+	}
+	lexicons := Imports(block.Src)
+
+	offset, err := byteOffset(text, params.Position)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	lineStart := strings.LastIndexByte(text[:offset], '\n') + 1
+	word := trailingWord(text[lineStart:offset])
+
+	if i := strings.Index(word, "."); i >= 0 {
+		lex, ok := findLexicon(lexicons, word[:i])
+		if !ok {
+			return list, nil
+		}
+		facts, err := s.lexiconFacts(ctx, lex)
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		for _, fact := range facts {
+			list.Items = append(list.Items, CompletionItem{
+				Label:  lex.Name + "." + fact,
+				Kind:   CompletionKindClass,
+				Detail: lex.Owner + "/" + lex.Name + " fact",
+			})
+		}
+		return list, nil
+	}
+
+	for _, lex := range lexicons {
+		list.Items = append(list.Items, CompletionItem{
+			Label:  lex.Name,
+			Kind:   CompletionKindModule,
+			Detail: lex.Owner + "/" + lex.Name + " lexicon",
+		})
+	}
+
+	lexName, fact := parentFact(text, block, params.Position.Line)
+	lex, ok := findLexicon(lexicons, lexName)
+	if !ok {
+		return list, nil
+	}
+	desc, err := s.describeFact(ctx, lex, fact)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	for _, prop := range desc.Properties {
+		item := CompletionItem{
+			Label:  prop.Name,
+			Kind:   CompletionKindProperty,
+			Detail: lex.Name + "." + fact + " property",
+		}
+		if prop.Description != "" {
+			item.Documentation = &MarkupContent{Kind: "markdown", Value: prop.Description}
+		}
+		list.Items = append(list.Items, item)
+	}
+	return list, nil
+}
+
+func (s *Server) hover(ctx context.Context, params *TextDocumentPositionParams) (*Hover, error) {
+	text, ok := s.document(params.TextDocument.URI)
+	if !ok || !isDotlingoURI(params.TextDocument.URI) {
+		return nil, nil
+	}
+	block := blockAt(text, params.Position.Line)
+	if block == nil {
+		return nil, nil
+	}
+
+	offset, err := byteOffset(text, params.Position)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	lineStart := strings.LastIndexByte(text[:offset], '\n') + 1
+	lineEnd := len(text)
+	if i := strings.IndexByte(text[offset:], '\n'); i >= 0 {
+		lineEnd = offset + i
+	}
+	word := trailingWord(text[lineStart:offset]) + leadingWord(text[offset:lineEnd])
+
+	m := factRegexp.FindStringSubmatch(word)
+	if m == nil {
+		return nil, nil
+	}
+	lex, ok := findLexicon(Imports(block.Src), m[1])
+	if !ok {
+		return nil, nil
+	}
+	desc, err := s.describeFact(ctx, lex, m[2])
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+
+	return &Hover{Contents: MarkupContent{Kind: "markdown", Value: formatHover(lex.Name+"."+m[2], desc)}}, nil
+}
+
+func formatHover(name string, desc *rpc.DescribeFactReply) string {
+	var buf strings.Builder
+	fmt.Fprintf(&buf, "**%s**\n\n%s\n", name, desc.Description)
+	if desc.Examples != "" {
+		fmt.Fprintf(&buf, "\n**Examples**\n\n```\n%s\n```\n", strings.TrimRight(desc.Examples, "\n"))
+	}
+	if len(desc.Properties) > 0 {
+		buf.WriteString("\n**Properties**\n\n")
+		for _, prop := range desc.Properties {
+			fmt.Fprintf(&buf, "- `%s`: %s\n", prop.Name, prop.Description)
+		}
+	}
+	return buf.String()
+}
+
+// parentFact returns the lexicon and name of the fact enclosing the given
+// line of a query block.
+func parentFact(text string, block *clql.QueryBlock, line int) (string, string) {
+	cur := lineAt(text, line)
+	indent := len(cur) - len(strings.TrimLeft(cur, " "))
+	if strings.TrimSpace(cur) == "" {
+		indent = len(cur)
+	}
+
+	for i := line - 1; i >= block.Start; i-- {
+		l := lineAt(text, i)
+		trimmed := strings.TrimSpace(l)
+		if trimmed == "" || strings.HasPrefix(trimmed, "#") {
+			continue
+		}
+		if len(l)-len(strings.TrimLeft(l, " ")) >= indent {
+			continue
+		}
+		if m := factRegexp.FindStringSubmatch(trimmed); m != nil && !strings.HasPrefix(trimmed, "@") {
+			return m[1], m[2]
+		}
+		return "", ""
+	}
+	return "", ""
+}
+
+func isWordChar(c byte) bool {
+	return c == '_' || c == '.' || c == '-' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9'
+}
+
+func trailingWord(s string) string {
+	i := len(s)
+	for i > 0 && isWordChar(s[i-1]) {
+		i--
+	}
+	return s[i:]
+}
+
+func leadingWord(s string) string {
+	i := 0
+	for i < len(s) && isWordChar(s[i]) {
+		i++
+	}
+	return s[:i]
+}
+
+// lexiconFacts returns the names of the facts in the given lexicon, without
+// the lexicon prefix.
+func (s *Server) lexiconFacts(ctx context.Context, lex Lexicon) ([]string, error) {
+	key := lex.Owner + "/" + lex.Name
+	s.mu.Lock()
+	factMap, ok := s.facts[key]
+	s.mu.Unlock()
+
+	if !ok {
+		var err error
+		factMap, err = s.platform.ListFacts(ctx, lex.Owner, lex.Name, "")
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		s.mu.Lock()
+		s.facts[key] = factMap
+		s.mu.Unlock()
+	}
+
+	seen := make(map[string]bool)
+	var facts []string
+	add := func(fact string) {
+		fact = strings.TrimPrefix(fact, lex.Name+".")
+		if fact != "" && !seen[fact] {
+			seen[fact] = true
+			facts = append(facts, fact)
+		}
+	}
+	for parent, children := range factMap {
+		add(parent)
+		for _, child := range children {
+			add(child)
+		}
+	}
+	sort.Strings(facts)
+	return facts, nil
+}
+
+func (s *Server) describeFact(ctx context.Context, lex Lexicon, fact string) (*rpc.DescribeFactReply, error) {
+	key := lex.Owner + "/" + lex.Name + "/" + fact
+	s.mu.Lock()
+	desc, ok := s.descriptions[key]
+	s.mu.Unlock()
+	if ok {
+		return desc, nil
+	}
+
+	desc, err := s.platform.DescribeFact(ctx, lex.Owner, lex.Name, "", fact)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	s.mu.Lock()
+	s.descriptions[key] = desc
+	s.mu.Unlock()
+	return desc, nil
+}
@@ -0,0 +1,108 @@
+package lsp
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/textproto"
+	"strconv"
+	"sync"
+
+	"github.com/juju/errors"
+)
+
+// conn reads and writes JSON-RPC messages framed with Content-Length
+// headers, as used by the Language Server Protocol.
+type conn struct {
+	r *bufio.Reader
+
+	mu     sync.Mutex
+	w      io.Writer
+	nextID int
+}
+
+func newConn(r io.Reader, w io.Writer) *conn {
+	return &conn{
+		r: bufio.NewReader(r),
+		w: w,
+	}
+}
+
+// read returns the next message. It returns io.EOF when the input is closed.
+func (c *conn) read() (*message, error) {
+	header, err := textproto.NewReader(c.r).ReadMIMEHeader()
+	if err != nil {
+		if err == io.EOF || errors.Cause(err) == io.ErrUnexpectedEOF {
+			return nil, io.EOF
+		}
+		return nil, errors.Trace(err)
+	}
+
+	length, err := strconv.Atoi(header.Get("Content-Length"))
+	if err != nil {
+		return nil, errors.Errorf("invalid Content-Length header %q", header.Get("Content-Length"))
+	}
+
+	body := make([]byte, length)
+	if _, err := io.ReadFull(c.r, body); err != nil {
+		return nil, errors.Trace(err)
+	}
+
+	msg := &message{}
+	if err := json.Unmarshal(body, msg); err != nil {
+		return nil, errors.Trace(err)
+	}
+	return msg, nil
+}
+
+func (c *conn) write(msg *message) error {
+	msg.JSONRPC = "2.0"
+	body, err := json.Marshal(msg)
+	if err != nil {
+		return errors.Trace(err)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, err := fmt.Fprintf(c.w, "Content-Length: %d\r\n\r\n", len(body)); err != nil {
+		return errors.Trace(err)
+	}
+	_, err = c.w.Write(body)
+	return errors.Trace(err)
+}
+
+func (c *conn) reply(id *json.RawMessage, result interface{}) error {
+	if result == nil {
+		// A null result must still be sent.
+		result = json.RawMessage("null")
+	}
+	return c.write(&message{ID: id, Result: result})
+}
+
+func (c *conn) replyError(id *json.RawMessage, code int, err error) error {
+	return c.write(&message{ID: id, Error: &responseError{Code: code, Message: err.Error()}})
+}
+
+func (c *conn) notify(method string, params interface{}) error {
+	raw, err := json.Marshal(params)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	return c.write(&message{Method: method, Params: raw})
+}
+
+// request sends a request to the client. The client's response is ignored.
+func (c *conn) request(method string, params interface{}) error {
+	raw, err := json.Marshal(params)
+	if err != nil {
+		return errors.Trace(err)
+	}
+
+	c.mu.Lock()
+	c.nextID++
+	id := json.RawMessage(strconv.Itoa(c.nextID))
+	c.mu.Unlock()
+
+	return c.write(&message{ID: &id, Method: method, Params: raw})
+}
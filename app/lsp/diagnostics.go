@@ -0,0 +1,95 @@
+package lsp
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+
+	"github.com/codelingo/lingo/app/clql"
+	"github.com/juju/errors"
+	"gopkg.in/yaml.v2"
+)
+
+var yamlLineRegexp = regexp.MustCompile(`^yaml: line (\d+): (.*)$`)
+
+// Diagnostics returns the YAML and CLQL errors found in the given
+// codelingo.yaml source.
+func Diagnostics(text string) []Diagnostic {
+	diags := []Diagnostic{}
+
+	var check interface{}
+	if err := yaml.Unmarshal([]byte(text), &check); err != nil {
+		line, msg := 0, err.Error()
+		if m := yamlLineRegexp.FindStringSubmatch(msg); m != nil {
+			n, _ := strconv.Atoi(m[1])
+			line, msg = n-1, m[2]
+		}
+		diags = append(diags, lineDiagnostic(text, line, SeverityError, msg))
+		// The query blocks can't be trusted if the YAML is invalid.
+		return diags
+	}
+
+	for _, block := range clql.QueryBlocks([]byte(text)) {
+		if strings.TrimSpace(block.Src) == "" {
+			diags = append(diags, lineDiagnostic(text, block.KeyLine, SeverityError, "query is empty"))
+			continue
+		}
+
+		if _, err := clql.Format(block.Src); err != nil {
+			if sErr, ok := errors.Cause(err).(*clql.SyntaxError); ok {
+				diags = append(diags, lineDiagnostic(text, block.Start+sErr.Line-1, SeverityError, sErr.Msg))
+				continue
+			}
+			diags = append(diags, lineDiagnostic(text, block.KeyLine, SeverityError, err.Error()))
+			continue
+		}
+
+		if len(Imports(block.Src)) == 0 {
+			diags = append(diags, lineDiagnostic(text, block.KeyLine, SeverityWarning, "query does not import a lexicon"))
+		}
+	}
+	return diags
+}
+
+// lineDiagnostic returns a diagnostic covering the non-whitespace text of
+// the given line.
+func lineDiagnostic(text string, line int, severity int, msg string) Diagnostic {
+	l := lineAt(text, line)
+	start := len(l) - len(strings.TrimLeft(l, " \t"))
+	return Diagnostic{
+		Range: Range{
+			Start: Position{Line: line, Character: utf16Len(l[:start])},
+			End:   Position{Line: line, Character: utf16Len(l)},
+		},
+		Severity: severity,
+		Source:   "lingo",
+		Message:  msg,
+	}
+}
+
+func utf16Len(s string) int {
+	return len(utf16.Encode([]rune(s)))
+}
+
+// byteOffset converts an LSP position, whose character is counted in UTF-16
+// code units, to a byte offset in text.
+func byteOffset(text string, pos Position) (int, error) {
+	offset := 0
+	for l := 0; l < pos.Line; l++ {
+		i := strings.IndexByte(text[offset:], '\n')
+		if i < 0 {
+			return 0, errors.Errorf("line %d is past the end of the document", pos.Line)
+		}
+		offset += i + 1
+	}
+
+	units := 0
+	for i, r := range text[offset:] {
+		if units >= pos.Character || r == '\n' {
+			return offset + i, nil
+		}
+		units += len(utf16.Encode([]rune{r}))
+	}
+	return len(text), nil
+}
@@ -0,0 +1,238 @@
+// Package lsp implements a Language Server Protocol server for codelingo.yaml
+// files.
+package lsp
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/codelingo/lingo/app/util"
+	"github.com/codelingo/lingo/app/util/common"
+	rpc "github.com/codelingo/rpc/service"
+	"github.com/juju/errors"
+)
+
+// GenerateQueryCommand is the command run by the code action which generates
+// a query from the selected source code.
+const GenerateQueryCommand = "lingo.generateQuery"
+
+// Platform is the part of the CodeLingo platform used by the server.
+type Platform interface {
+	ListFacts(ctx context.Context, owner, name, version string) (map[string][]string, error)
+	DescribeFact(ctx context.Context, owner, name, version, fact string) (*rpc.DescribeFactReply, error)
+	QueryFromOffset(ctx context.Context, req *rpc.QueryFromOffsetRequest) (*rpc.QueryFromOffsetReply, error)
+}
+
+// Server is a language server for codelingo.yaml files.
+type Server struct {
+	platform Platform
+	conn     *conn
+
+	mu   sync.Mutex
+	docs map[string]string
+
+	facts        map[string]map[string][]string
+	descriptions map[string]*rpc.DescribeFactReply
+}
+
+// NewServer returns a server which uses the given platform to look up facts
+// and generate queries.
+func NewServer(platform Platform) *Server {
+	return &Server{
+		platform:     platform,
+		docs:         make(map[string]string),
+		facts:        make(map[string]map[string][]string),
+		descriptions: make(map[string]*rpc.DescribeFactReply),
+	}
+}
+
+// Serve handles messages read from r, writing responses to w, until the
+// client sends the exit notification or r is closed.
+func (s *Server) Serve(ctx context.Context, r io.Reader, w io.Writer) error {
+	s.conn = newConn(r, w)
+	for {
+		msg, err := s.conn.read()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return errors.Trace(err)
+		}
+
+		if msg.Method == "" {
+			// A response to a request sent to the client.
+			continue
+		}
+		if msg.Method == "exit" {
+			return nil
+		}
+
+		result, err := s.handle(ctx, msg)
+		if msg.ID == nil {
+			if err != nil {
+				util.Logger.Debugf("%s: %s", msg.Method, errors.ErrorStack(err))
+			}
+			continue
+		}
+
+		if err != nil {
+			code := errInternal
+			if rErr, ok := errors.Cause(err).(*responseError); ok {
+				code = rErr.Code
+			}
+			err = s.conn.replyError(msg.ID, code, err)
+		} else {
+			err = s.conn.reply(msg.ID, result)
+		}
+		if err != nil {
+			return errors.Trace(err)
+		}
+	}
+}
+
+func (e *responseError) Error() string {
+	return e.Message
+}
+
+func (s *Server) handle(ctx context.Context, msg *message) (interface{}, error) {
+	switch msg.Method {
+	case "initialize":
+		return map[string]interface{}{
+			"capabilities": map[string]interface{}{
+				// Documents are synced in full on every change.
+				"textDocumentSync": 1,
+				"completionProvider": map[string]interface{}{
+					"triggerCharacters": []string{"."},
+				},
+				"hoverProvider":      true,
+				"codeActionProvider": true,
+				"executeCommandProvider": map[string]interface{}{
+					"commands": []string{GenerateQueryCommand},
+				},
+			},
+			"serverInfo": map[string]string{
+				"name":    "lingo",
+				"version": common.ClientVersion,
+			},
+		}, nil
+	case "initialized", "shutdown", "$/cancelRequest", "textDocument/didSave":
+		return nil, nil
+	case "textDocument/didOpen":
+		params := &DidOpenTextDocumentParams{}
+		if err := unmarshalParams(msg, params); err != nil {
+			return nil, errors.Trace(err)
+		}
+		return nil, errors.Trace(s.update(params.TextDocument.URI, params.TextDocument.Text))
+	case "textDocument/didChange":
+		params := &DidChangeTextDocumentParams{}
+		if err := unmarshalParams(msg, params); err != nil {
+			return nil, errors.Trace(err)
+		}
+		if len(params.ContentChanges) == 0 {
+			return nil, nil
+		}
+		text := params.ContentChanges[len(params.ContentChanges)-1].Text
+		return nil, errors.Trace(s.update(params.TextDocument.URI, text))
+	case "textDocument/didClose":
+		params := &DidCloseTextDocumentParams{}
+		if err := unmarshalParams(msg, params); err != nil {
+			return nil, errors.Trace(err)
+		}
+		s.mu.Lock()
+		delete(s.docs, params.TextDocument.URI)
+		s.mu.Unlock()
+		if !isDotlingoURI(params.TextDocument.URI) {
+			return nil, nil
+		}
+		return nil, errors.Trace(s.conn.notify("textDocument/publishDiagnostics", &PublishDiagnosticsParams{
+			URI:         params.TextDocument.URI,
+			Diagnostics: []Diagnostic{},
+		}))
+	case "textDocument/completion":
+		params := &TextDocumentPositionParams{}
+		if err := unmarshalParams(msg, params); err != nil {
+			return nil, errors.Trace(err)
+		}
+		return s.completion(ctx, params)
+	case "textDocument/hover":
+		params := &TextDocumentPositionParams{}
+		if err := unmarshalParams(msg, params); err != nil {
+			return nil, errors.Trace(err)
+		}
+		return s.hover(ctx, params)
+	case "textDocument/codeAction":
+		params := &CodeActionParams{}
+		if err := unmarshalParams(msg, params); err != nil {
+			return nil, errors.Trace(err)
+		}
+		return s.codeActions(params), nil
+	case "workspace/executeCommand":
+		params := &ExecuteCommandParams{}
+		if err := unmarshalParams(msg, params); err != nil {
+			return nil, errors.Trace(err)
+		}
+		return s.executeCommand(ctx, params)
+	}
+
+	return nil, &responseError{Code: errMethodNotFound, Message: "method not found: " + msg.Method}
+}
+
+func unmarshalParams(msg *message, params interface{}) error {
+	if err := json.Unmarshal(msg.Params, params); err != nil {
+		return &responseError{Code: errInvalidParams, Message: err.Error()}
+	}
+	return nil
+}
+
+// update stores the latest text of a document and publishes its diagnostics.
+func (s *Server) update(uri, text string) error {
+	s.mu.Lock()
+	s.docs[uri] = text
+	s.mu.Unlock()
+
+	if !isDotlingoURI(uri) {
+		return nil
+	}
+	return errors.Trace(s.conn.notify("textDocument/publishDiagnostics", &PublishDiagnosticsParams{
+		URI:         uri,
+		Diagnostics: Diagnostics(text),
+	}))
+}
+
+// document returns the text of an open document.
+func (s *Server) document(uri string) (string, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	text, ok := s.docs[uri]
+	return text, ok
+}
+
+func isDotlingoURI(uri string) bool {
+	return common.IsDotlingoFile(uriToPath(uri))
+}
+
+func uriToPath(uri string) string {
+	u, err := url.Parse(uri)
+	if err != nil || u.Scheme != "file" {
+		return uri
+	}
+	return filepath.FromSlash(u.Path)
+}
+
+func pathToURI(path string) string {
+	return (&url.URL{Scheme: "file", Path: filepath.ToSlash(path)}).String()
+}
+
+// lineAt returns the given line of text.
+func lineAt(text string, line int) string {
+	lines := strings.Split(text, "\n")
+	if line < 0 || line >= len(lines) {
+		return ""
+	}
+	return strings.TrimSuffix(lines[line], "\r")
+}
@@ -0,0 +1,199 @@
+package lsp
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	rpc "github.com/codelingo/rpc/service"
+	jc "github.com/juju/testing/checkers"
+	gc "gopkg.in/check.v1"
+)
+
+func Test(t *testing.T) {
+	gc.TestingT(t)
+}
+
+type lspSuite struct{}
+
+var _ = gc.Suite(&lspSuite{})
+
+type fakePlatform struct{}
+
+func (fakePlatform) ListFacts(ctx context.Context, owner, name, version string) (map[string][]string, error) {
+	return map[string][]string{
+		"go.file":      {"go.func_decl"},
+		"go.func_decl": {"go.ident"},
+	}, nil
+}
+
+func (fakePlatform) DescribeFact(ctx context.Context, owner, name, version, fact string) (*rpc.DescribeFactReply, error) {
+	return &rpc.DescribeFactReply{
+		Description: "A " + fact + " fact.",
+		Properties:  []*rpc.Property{{Name: "name", Description: "The name."}},
+	}, nil
+}
+
+func (fakePlatform) QueryFromOffset(ctx context.Context, req *rpc.QueryFromOffsetRequest) (*rpc.QueryFromOffsetReply, error) {
+	return &rpc.QueryFromOffsetReply{}, nil
+}
+
+const testURI = "file:///repo/codelingo.yaml"
+
+const testDotlingo = `tenets:
+  - name: find-funcs
+    flows:
+      codelingo/review:
+        comment: Found a func.
+    query: |
+      import codelingo/ast/go
+
+      go.func_decl(depth = any):
+        na
+`
+
+// serve runs the server over the given messages and returns the messages it
+// wrote.
+func serve(c *gc.C, msgs ...string) []map[string]interface{} {
+	in := &bytes.Buffer{}
+	for _, msg := range msgs {
+		fmt.Fprintf(in, "Content-Length: %d\r\n\r\n%s", len(msg), msg)
+	}
+	out := &bytes.Buffer{}
+	c.Assert(NewServer(fakePlatform{}).Serve(context.Background(), in, out), jc.ErrorIsNil)
+
+	var replies []map[string]interface{}
+	for out.Len() > 0 {
+		var length int
+		_, err := fmt.Fscanf(out, "Content-Length: %d\r\n\r\n", &length)
+		c.Assert(err, jc.ErrorIsNil)
+		reply := make(map[string]interface{})
+		c.Assert(json.Unmarshal(out.Next(length), &reply), jc.ErrorIsNil)
+		replies = append(replies, reply)
+	}
+	return replies
+}
+
+func request(id int, method string, params interface{}) string {
+	raw, _ := json.Marshal(map[string]interface{}{"jsonrpc": "2.0", "id": id, "method": method, "params": params})
+	return string(raw)
+}
+
+func notification(method string, params interface{}) string {
+	raw, _ := json.Marshal(map[string]interface{}{"jsonrpc": "2.0", "method": method, "params": params})
+	return string(raw)
+}
+
+func didOpen(text string) string {
+	return notification("textDocument/didOpen", &DidOpenTextDocumentParams{
+		TextDocument: TextDocumentItem{URI: testURI, LanguageID: "yaml", Text: text},
+	})
+}
+
+func (s *lspSuite) TestInitialize(c *gc.C) {
+	replies := serve(c, request(1, "initialize", map[string]interface{}{}), request(2, "unknown", nil))
+	c.Assert(replies, gc.HasLen, 2)
+	c.Assert(replies[0]["result"], gc.NotNil)
+	caps := replies[0]["result"].(map[string]interface{})["capabilities"].(map[string]interface{})
+	c.Assert(caps["hoverProvider"], gc.Equals, true)
+	c.Assert(replies[1]["error"].(map[string]interface{})["code"], gc.Equals, float64(errMethodNotFound))
+}
+
+func (s *lspSuite) TestDiagnostics(c *gc.C) {
+	c.Assert(Diagnostics(testDotlingo), gc.HasLen, 0)
+
+	diags := Diagnostics("tenets:\n  - name: x\n    query: |\n      import codelingo/ast/go\n      go.file(:\n")
+	c.Assert(diags, gc.HasLen, 1)
+	c.Assert(diags[0].Range.Start.Line, gc.Equals, 4)
+	c.Assert(diags[0].Message, gc.Equals, "unbalanced parentheses")
+
+	diags = Diagnostics("tenets:\n  - name: x\n    query: |\n      go.file\n")
+	c.Assert(diags, gc.HasLen, 1)
+	c.Assert(diags[0].Severity, gc.Equals, SeverityWarning)
+
+	diags = Diagnostics("tenets:\n\t- name: x\n")
+	c.Assert(diags, gc.HasLen, 1)
+	c.Assert(diags[0].Severity, gc.Equals, SeverityError)
+}
+
+func (s *lspSuite) TestPublishDiagnostics(c *gc.C) {
+	replies := serve(c, didOpen("tenets:\n  - name: x\n    query: |\n      go.file(\n"))
+	c.Assert(replies, gc.HasLen, 1)
+	c.Assert(replies[0]["method"], gc.Equals, "textDocument/publishDiagnostics")
+	diags := replies[0]["params"].(map[string]interface{})["diagnostics"].([]interface{})
+	c.Assert(diags, gc.HasLen, 1)
+}
+
+func completionLabels(c *gc.C, reply map[string]interface{}) []string {
+	var labels []string
+	for _, item := range reply["result"].(map[string]interface{})["items"].([]interface{}) {
+		labels = append(labels, item.(map[string]interface{})["label"].(string))
+	}
+	return labels
+}
+
+func (s *lspSuite) TestCompletion(c *gc.C) {
+	text := testDotlingo + "        go.\n"
+	replies := serve(c,
+		didOpen(text),
+		request(1, "textDocument/completion", &TextDocumentPositionParams{
+			TextDocument: TextDocumentIdentifier{URI: testURI},
+			Position:     Position{Line: 10, Character: 11},
+		}),
+		request(2, "textDocument/completion", &TextDocumentPositionParams{
+			TextDocument: TextDocumentIdentifier{URI: testURI},
+			Position:     Position{Line: 9, Character: 10},
+		}),
+	)
+	c.Assert(replies, gc.HasLen, 3)
+	c.Assert(completionLabels(c, replies[1]), jc.DeepEquals, []string{"go.file", "go.func_decl", "go.ident"})
+	c.Assert(completionLabels(c, replies[2]), jc.DeepEquals, []string{"go", "name"})
+}
+
+func (s *lspSuite) TestHover(c *gc.C) {
+	replies := serve(c,
+		didOpen(testDotlingo),
+		request(1, "textDocument/hover", &TextDocumentPositionParams{
+			TextDocument: TextDocumentIdentifier{URI: testURI},
+			Position:     Position{Line: 8, Character: 10},
+		}),
+	)
+	c.Assert(replies, gc.HasLen, 2)
+	contents := replies[1]["result"].(map[string]interface{})["contents"].(map[string]interface{})
+	c.Assert(contents["value"], gc.Equals, "**go.func_decl**\n\nA func_decl fact.\n\n**Properties**\n\n- `name`: The name.\n")
+}
+
+func (s *lspSuite) TestCodeActions(c *gc.C) {
+	srv := NewServer(fakePlatform{})
+	rng := Range{Start: Position{Line: 1}, End: Position{Line: 2}}
+	c.Assert(srv.codeActions(&CodeActionParams{TextDocument: TextDocumentIdentifier{URI: testURI}, Range: rng}), gc.HasLen, 0)
+
+	actions := srv.codeActions(&CodeActionParams{TextDocument: TextDocumentIdentifier{URI: "file:///repo/main.go"}, Range: rng})
+	c.Assert(actions, gc.HasLen, 1)
+	c.Assert(actions[0].Command.Command, gc.Equals, GenerateQueryCommand)
+}
+
+func (s *lspSuite) TestByteOffset(c *gc.C) {
+	text := "a\n😀b\n"
+	offset, err := byteOffset(text, Position{Line: 1, Character: 2})
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(text[offset:], gc.Equals, "b\n")
+
+	_, err = byteOffset(text, Position{Line: 5})
+	c.Assert(err, gc.ErrorMatches, "line 5 is past the end of the document")
+}
+
+func (s *lspSuite) TestTextEdit(c *gc.C) {
+	text := "tenets:\n  - name: é\nother: 1\n"
+	newText := "tenets:\n  - name: é\n  - name: new\nother: 1\n"
+	edit := textEdit(text, newText)
+	c.Assert(edit, jc.DeepEquals, TextEdit{
+		Range:   Range{Start: Position{Line: 2}, End: Position{Line: 2}},
+		NewText: "  - name: new\n",
+	})
+
+	c.Assert(uniqueName("t", nil), gc.Equals, "t")
+	c.Assert(uniqueName("t", []string{"t", "t-2", "u"}), gc.Equals, "t-3")
+}
@@ -0,0 +1,93 @@
+// Package sandbox runs commands isolated from the rest of the system. It is
+// used to run untrusted Actions, and is only supported on Linux, where it
+// relies on unprivileged user namespaces.
+//
+// A sandboxed command sees the paths in Config.ReadOnly read-only, has a
+// private, empty /tmp, has no network unless Config.Network is set, is
+// limited in CPU time and memory, and is given only the environment in
+// Config.Env.
+//
+// The sandbox is set up by lingo itself, which is re-executed in the new
+// namespaces as the command's parent before exec'ing it. Programs using this
+// package must call Init first thing in main.
+package sandbox
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/juju/errors"
+)
+
+const (
+	// initArg is the name lingo is re-executed with to set up a sandbox.
+	initArg = "lingo-sandbox-init"
+	// configEnv holds the JSON encoded Config of the sandbox to set up.
+	configEnv = "LINGO_SANDBOX_CONFIG"
+	// ExitSetupFailed is the exit code of a sandbox which could not be
+	// set up.
+	ExitSetupFailed = 125
+)
+
+// Config describes a sandboxed command.
+type Config struct {
+	Path string   `json:"path"`
+	Args []string `json:"args"`
+	Env  []string `json:"env"`
+	Dir  string   `json:"dir"`
+	// ReadOnly are paths the command may read but not write.
+	ReadOnly []string `json:"read_only"`
+	// Shared are directories under /tmp which stay visible, and writable,
+	// inside the private /tmp.
+	Shared []string `json:"shared"`
+	// Network allows the command to use the network.
+	Network bool `json:"network"`
+	// CPU is the limit on CPU time in seconds.
+	CPU int `json:"cpu"`
+	// Memory is the limit on the address space in MiB.
+	Memory int `json:"memory"`
+}
+
+// defaultEnv are the environment variables every sandboxed command is
+// given, if they are set.
+var defaultEnv = []string{"PATH", "HOME", "USER", "LANG", "TERM"}
+
+// ScrubEnv returns the variables in environ named in allow or defaultEnv,
+// or prefixed with LC_.
+func ScrubEnv(environ, allow []string) []string {
+	allowed := make(map[string]bool)
+	for _, name := range append(defaultEnv, allow...) {
+		allowed[name] = true
+	}
+
+	var env []string
+	for _, v := range environ {
+		name := strings.SplitN(v, "=", 2)[0]
+		if allowed[name] || strings.HasPrefix(name, "LC_") {
+			env = append(env, v)
+		}
+	}
+	return env
+}
+
+// Describe summarises the restrictions of the sandbox, for users whose
+// command failed in it.
+func (c *Config) Describe() string {
+	network := "no network access"
+	if c.Network {
+		network = "network access"
+	}
+	return fmt.Sprintf("%s, read-only access to %s, a private /tmp, %d seconds of CPU time and %d MiB of memory",
+		network, strings.Join(c.ReadOnly, ", "), c.CPU, c.Memory)
+}
+
+// setupError reports a sandbox which could not be set up and exits.
+func setupError(err error) {
+	fmt.Fprintf(os.Stderr, "lingo: failed to set up the sandbox: %v\n", err)
+	os.Exit(ExitSetupFailed)
+}
+
+// ErrSetupFailed is returned for commands whose sandbox could not be set
+// up.
+var ErrSetupFailed = errors.New("the sandbox could not be set up. Check that unprivileged user namespaces are enabled, e.g. with `sysctl kernel.unprivileged_userns_clone=1`")
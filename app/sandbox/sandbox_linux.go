@@ -0,0 +1,292 @@
+//go:build linux
+// +build linux
+
+package sandbox
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"runtime"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/juju/errors"
+)
+
+// prctl options and secure bits missing from the syscall package.
+const (
+	prSetNoNewPrivs    = 38
+	secbitNoRoot       = 1 << 0
+	secbitNoRootLocked = 1 << 1
+)
+
+// Command returns a command which runs cfg.Path in a new sandbox.
+func Command(cfg *Config) (*exec.Cmd, error) {
+	data, err := json.Marshal(cfg)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+
+	flags := syscall.CLONE_NEWUSER | syscall.CLONE_NEWNS
+	if !cfg.Network {
+		// A new network namespace has only a loopback device, which is down.
+		flags |= syscall.CLONE_NEWNET
+	}
+
+	cmd := exec.Command("/proc/self/exe")
+	cmd.Args = []string{initArg}
+	// lingo is initialised before the sandbox is set up, and needs the
+	// command's environment, e.g. HOME, to do so.
+	cmd.Env = append(append([]string{}, cfg.Env...), configEnv+"="+string(data))
+	cmd.SysProcAttr = &syscall.SysProcAttr{
+		Cloneflags: uintptr(flags),
+		// The sandbox is set up as root in the new user namespace, which is
+		// the user running lingo outside of it.
+		UidMappings:                []syscall.SysProcIDMap{{ContainerID: 0, HostID: os.Getuid(), Size: 1}},
+		GidMappings:                []syscall.SysProcIDMap{{ContainerID: 0, HostID: os.Getgid(), Size: 1}},
+		GidMappingsEnableSetgroups: false,
+	}
+	return cmd, nil
+}
+
+// Init sets up the sandbox and execs the sandboxed command, if this process
+// was started by Command. Otherwise it returns immediately.
+func Init() {
+	if len(os.Args) == 0 || os.Args[0] != initArg {
+		return
+	}
+	// Capabilities are per thread, so they must be dropped on the thread
+	// which execs the command.
+	runtime.LockOSThread()
+
+	cfg := &Config{}
+	if err := json.Unmarshal([]byte(os.Getenv(configEnv)), cfg); err != nil {
+		setupError(errors.Annotate(err, "invalid config"))
+	}
+	if err := setup(cfg); err != nil {
+		setupError(err)
+	}
+
+	env := append(cfg.Env, "TMPDIR=/tmp")
+	err := syscall.Exec(cfg.Path, append([]string{cfg.Path}, cfg.Args...), env)
+	setupError(errors.Annotatef(err, "failed to run %s", cfg.Path))
+}
+
+// Violation returns an error if a sandboxed command which exited with state
+// was stopped by the sandbox.
+func Violation(cfg *Config, state *os.ProcessState) error {
+	status, ok := state.Sys().(syscall.WaitStatus)
+	if !ok {
+		return nil
+	}
+	switch {
+	case status.Exited() && status.ExitStatus() == ExitSetupFailed:
+		return ErrSetupFailed
+	case status.Signaled() && status.Signal() == syscall.SIGXCPU,
+		// The hard limit is enforced with SIGKILL, which the command may
+		// also have been sent for another reason, such as running out of
+		// memory.
+		status.Signaled() && status.Signal() == syscall.SIGKILL && cfg != nil && cfg.CPU > 0 &&
+			state.UserTime()+state.SystemTime() >= time.Duration(cfg.CPU)*time.Second:
+		return errors.Errorf("it was stopped after exceeding its limit of %d seconds of CPU time", cfg.CPU)
+	}
+	return nil
+}
+
+func setup(cfg *Config) error {
+	// Keep the mounts below from propagating out of the sandbox.
+	if err := syscall.Mount("", "/", "", syscall.MS_REC|syscall.MS_PRIVATE, ""); err != nil {
+		return errors.Annotate(err, "failed to make mounts private")
+	}
+
+	// Open every path that is mounted into the sandbox before /tmp is
+	// replaced, as the private /tmp hides any of them under it.
+	type mountPoint struct {
+		path     string
+		dir      *os.File
+		readOnly bool
+	}
+	var points []mountPoint
+	open := func(path string, readOnly bool) error {
+		dir, err := os.Open(path)
+		if err != nil {
+			return errors.Trace(err)
+		}
+		points = append(points, mountPoint{path: path, dir: dir, readOnly: readOnly})
+		return nil
+	}
+	defer func() {
+		for _, point := range points {
+			point.dir.Close()
+		}
+	}()
+	for _, path := range cfg.Shared {
+		if err := open(path, false); err != nil {
+			return errors.Trace(err)
+		}
+	}
+	for _, path := range cfg.ReadOnly {
+		if err := open(path, true); err != nil {
+			return errors.Trace(err)
+		}
+	}
+
+	// Nothing outside the sandbox's own mounts may be written to, or the
+	// command could change the user's shell startup files, config or other
+	// Actions to escape it.
+	if err := remountAllReadOnly(); err != nil {
+		return errors.Trace(err)
+	}
+	if err := syscall.Mount("tmpfs", "/tmp", "tmpfs", syscall.MS_NOSUID|syscall.MS_NODEV, "mode=1777"); err != nil {
+		return errors.Annotate(err, "failed to mount a private /tmp")
+	}
+
+	for _, point := range points {
+		if err := os.MkdirAll(point.path, 0700); err != nil {
+			return errors.Trace(err)
+		}
+		source := fmt.Sprintf("/proc/self/fd/%d", point.dir.Fd())
+		if err := syscall.Mount(source, point.path, "", syscall.MS_BIND|syscall.MS_REC, ""); err != nil {
+			return errors.Annotatef(err, "failed to mount %s", point.path)
+		}
+		// The bind mount has the flags of the mount it was bound from, which
+		// was made read-only above.
+		if err := remount(point.path, point.readOnly); err != nil {
+			return errors.Trace(err)
+		}
+	}
+
+	if err := os.Chdir(cfg.Dir); err != nil {
+		return errors.Trace(err)
+	}
+	if err := setLimits(cfg); err != nil {
+		return errors.Trace(err)
+	}
+	return errors.Trace(dropPrivileges())
+}
+
+// remountAllReadOnly makes every mount in the sandbox's mount namespace
+// read-only.
+func remountAllReadOnly() error {
+	data, err := ioutil.ReadFile("/proc/self/mountinfo")
+	if err != nil {
+		return errors.Trace(err)
+	}
+	for _, line := range strings.Split(strings.TrimSpace(string(data)), "\n") {
+		// The fields are described in proc(5).
+		fields := strings.Fields(line)
+		if len(fields) < 6 {
+			continue
+		}
+		path, options := unescapeMountPath(fields[4]), strings.Split(fields[5], ",")
+		if options[0] == "ro" {
+			continue
+		}
+		if err := remount(path, true); err != nil {
+			// Mounts hidden under another mount can't be reached, so
+			// needn't be made read-only.
+			if os.IsNotExist(errors.Cause(err)) || os.IsPermission(errors.Cause(err)) {
+				continue
+			}
+			return errors.Trace(err)
+		}
+	}
+	return nil
+}
+
+// unescapeMountPath decodes the octal escapes, such as \040 for a space,
+// of a path in /proc/self/mountinfo.
+func unescapeMountPath(path string) string {
+	var b strings.Builder
+	for i := 0; i < len(path); i++ {
+		if path[i] == '\\' && i+3 < len(path) {
+			if n, err := strconv.ParseUint(path[i+1:i+4], 8, 8); err == nil {
+				b.WriteByte(byte(n))
+				i += 3
+				continue
+			}
+		}
+		b.WriteByte(path[i])
+	}
+	return b.String()
+}
+
+// remount makes the mount at path read-only or writable. The other flags of
+// a mount are locked in a user namespace, so they must be kept.
+func remount(path string, readOnly bool) error {
+	var stat syscall.Statfs_t
+	if err := syscall.Statfs(path, &stat); err != nil {
+		return errors.Trace(err)
+	}
+	flags := uintptr(syscall.MS_BIND | syscall.MS_REMOUNT)
+	if readOnly {
+		flags |= syscall.MS_RDONLY
+	}
+	for st, ms := range map[int64]uintptr{
+		0x2:    syscall.MS_NOSUID,
+		0x4:    syscall.MS_NODEV,
+		0x8:    syscall.MS_NOEXEC,
+		0x400:  syscall.MS_NOATIME,
+		0x800:  syscall.MS_NODIRATIME,
+		0x1000: syscall.MS_RELATIME,
+	} {
+		if int64(stat.Flags)&st != 0 {
+			flags |= ms
+		}
+	}
+	mode := "writable"
+	if readOnly {
+		mode = "read-only"
+	}
+	return errors.Annotatef(syscall.Mount("", path, "", flags, ""), "failed to make %s %s", path, mode)
+}
+
+func setLimits(cfg *Config) error {
+	// The command is sent SIGXCPU at the soft limit, and SIGKILL a second
+	// later if it hasn't exited.
+	cpu := &syscall.Rlimit{Cur: uint64(cfg.CPU), Max: uint64(cfg.CPU) + 1}
+	if err := syscall.Setrlimit(syscall.RLIMIT_CPU, cpu); err != nil {
+		return errors.Annotate(err, "failed to limit CPU time")
+	}
+	memory := uint64(cfg.Memory) << 20
+	if err := syscall.Setrlimit(syscall.RLIMIT_AS, &syscall.Rlimit{Cur: memory, Max: memory}); err != nil {
+		return errors.Annotate(err, "failed to limit memory")
+	}
+	return nil
+}
+
+// dropPrivileges keeps the command, which runs as root in the sandbox's
+// user namespace, from gaining any capabilities, so it can't undo the
+// sandbox's mounts.
+func dropPrivileges() error {
+	for c := 0; c <= lastCap(); c++ {
+		if _, _, errno := syscall.RawSyscall(syscall.SYS_PRCTL, syscall.PR_CAPBSET_DROP, uintptr(c), 0); errno != 0 {
+			return errors.Annotatef(errno, "failed to drop capability %d", c)
+		}
+	}
+	if _, _, errno := syscall.RawSyscall(syscall.SYS_PRCTL, syscall.PR_SET_SECUREBITS, secbitNoRoot|secbitNoRootLocked, 0); errno != 0 {
+		return errors.Annotate(errno, "failed to set secure bits")
+	}
+	if _, _, errno := syscall.RawSyscall(syscall.SYS_PRCTL, prSetNoNewPrivs, 1, 0); errno != 0 {
+		return errors.Annotate(errno, "failed to set no_new_privs")
+	}
+	return nil
+}
+
+// lastCap returns the highest capability the kernel supports.
+func lastCap() int {
+	data, err := ioutil.ReadFile("/proc/sys/kernel/cap_last_cap")
+	if err != nil {
+		return 31
+	}
+	last, err := strconv.Atoi(strings.TrimSpace(string(data)))
+	if err != nil {
+		return 31
+	}
+	return last
+}
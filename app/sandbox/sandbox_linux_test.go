@@ -0,0 +1,121 @@
+package sandbox
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestMain(m *testing.M) {
+	// The test binary sets up the sandboxes of the commands it runs.
+	Init()
+	os.Exit(m.Run())
+}
+
+func TestSandbox(t *testing.T) {
+	repo, err := ioutil.TempDir("", "sandbox-repo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(repo)
+	shared, err := ioutil.TempDir("", "sandbox-shared")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(shared)
+	if err := ioutil.WriteFile(filepath.Join(repo, "main.go"), []byte("package main\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// The sandbox's /tmp is private, so probe a directory outside it.
+	home, err := os.UserHomeDir()
+	if err != nil {
+		t.Fatal(err)
+	}
+	probe := filepath.Join(home, ".lingo-sandbox-probe")
+	defer os.Remove(probe)
+
+	script := `
+cat main.go
+touch main.go && echo "repo writable"
+touch "$HOME/.lingo-sandbox-probe" && echo "home writable"
+mount -o remount,bind,rw . 2>/dev/null && echo "repo remounted"
+echo result > ` + shared + `/results.json
+ls /tmp | grep -v sandbox- && echo "tmp shared"
+echo "interfaces: $(grep -c : /proc/net/dev)"
+echo "secret: $SECRET"
+echo "allowed: $ALLOWED"
+`
+	cmd, err := Command(&Config{
+		Path:     "/bin/sh",
+		Args:     []string{"-c", script},
+		Env:      ScrubEnv([]string{"PATH=/bin:/usr/bin", "HOME=" + home, "SECRET=x", "ALLOWED=y"}, []string{"ALLOWED"}),
+		Dir:      repo,
+		ReadOnly: []string{repo},
+		Shared:   []string{shared},
+		CPU:      10,
+		Memory:   512,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout, cmd.Stderr = &stdout, &stderr
+	if err := cmd.Run(); err != nil {
+		if Violation(nil, cmd.ProcessState) == ErrSetupFailed {
+			t.Skipf("sandboxes aren't supported here: %s", stderr.String())
+		}
+		t.Fatalf("%v: %s", err, stderr.String())
+	}
+
+	out := stdout.String()
+	for _, expected := range []string{"package main\n", "interfaces: 1\n", "secret: \n", "allowed: y\n"} {
+		if !strings.Contains(out, expected) {
+			t.Errorf("expected %q in the sandbox's output:\n%s", expected, out)
+		}
+	}
+	for _, unexpected := range []string{"repo writable", "home writable", "repo remounted", "tmp shared"} {
+		if strings.Contains(out, unexpected) {
+			t.Errorf("unexpected %q in the sandbox's output:\n%s", unexpected, out)
+		}
+	}
+	if _, err := os.Stat(probe); err == nil {
+		t.Errorf("the sandbox wrote to %s", home)
+	}
+	if data, err := ioutil.ReadFile(filepath.Join(shared, "results.json")); err != nil || string(data) != "result\n" {
+		t.Errorf("the shared directory wasn't written to: %q, %v", data, err)
+	}
+}
+
+func TestViolation(t *testing.T) {
+	cfg := &Config{CPU: 10}
+	for _, c := range []struct {
+		script    string
+		violation bool
+	}{
+		{script: "exit 1"},
+		{script: "kill -XCPU $$", violation: true},
+		// Only a command which used up its CPU time was killed for it.
+		{script: "kill -KILL $$"},
+	} {
+		cmd := exec.Command("/bin/sh", "-c", c.script)
+		if err := cmd.Run(); err == nil {
+			t.Fatalf("%s: expected the command to fail", c.script)
+		}
+		err := Violation(cfg, cmd.ProcessState)
+		if c.violation != (err != nil) {
+			t.Errorf("%s: unexpected violation %v", c.script, err)
+		}
+	}
+}
+
+func TestScrubEnv(t *testing.T) {
+	env := ScrubEnv([]string{"PATH=/bin", "LC_ALL=C", "AWS_SECRET_ACCESS_KEY=x", "GOPATH=/go"}, []string{"GOPATH"})
+	if strings.Join(env, " ") != "PATH=/bin LC_ALL=C GOPATH=/go" {
+		t.Errorf("ScrubEnv = %v", env)
+	}
+}
@@ -0,0 +1,24 @@
+//go:build !linux
+// +build !linux
+
+package sandbox
+
+import (
+	"os"
+	"os/exec"
+
+	"github.com/juju/errors"
+)
+
+// Command returns an error, as sandboxes are only supported on Linux.
+func Command(cfg *Config) (*exec.Cmd, error) {
+	return nil, errors.New("Actions can only be sandboxed on Linux")
+}
+
+// Init does nothing, as sandboxes are only supported on Linux.
+func Init() {}
+
+// Violation always returns nil, as sandboxes are only supported on Linux.
+func Violation(cfg *Config, state *os.ProcessState) error {
+	return nil
+}
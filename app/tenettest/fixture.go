@@ -0,0 +1,141 @@
+// Package tenettest runs the Tenets in a codelingo.yaml against source
+// fixtures and compares the matches with the ones the fixtures expect.
+//
+// Fixtures live in a testdata directory next to the codelingo.yaml. A source
+// line is expected to be matched by a Tenet when it is annotated with a
+// marker comment naming the Tenet:
+//
+//	fmt.Println("hi") // lingo:expect no-println
+//
+// A marker on a line of its own applies to the next line. Several Tenets can
+// be listed, separated by commas.
+package tenettest
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/juju/errors"
+)
+
+// FixtureDir is the name of the directory, next to a codelingo.yaml, which
+// holds the fixtures for its Tenets.
+const FixtureDir = "testdata"
+
+var markerRegexp = regexp.MustCompile(`lingo:expect\s+([\w-]+(?:\s*,\s*[\w-]+)*)`)
+
+// commentLeaders are stripped from a marker's line to decide whether the
+// marker is on a line of its own.
+var commentLeaders = []string{"//", "/*", "*/", "#", "--", ";", "<!--", "-->", "*"}
+
+// Match is a source line matched by a Tenet. File is relative to the fixture
+// directory and Line starts at 1.
+type Match struct {
+	Tenet string `json:"tenet"`
+	File  string `json:"file"`
+	Line  int    `json:"line"`
+}
+
+func (m Match) String() string {
+	return fmt.Sprintf("%s:%d", m.File, m.Line)
+}
+
+// Expectations returns the matches expected by the marker comments in the
+// fixtures under dir.
+func Expectations(dir string) ([]Match, error) {
+	var matches []Match
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return errors.Trace(err)
+		}
+		if info.IsDir() {
+			return nil
+		}
+
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return errors.Trace(err)
+		}
+		fileMatches, err := fileExpectations(path, filepath.ToSlash(rel))
+		if err != nil {
+			return errors.Trace(err)
+		}
+		matches = append(matches, fileMatches...)
+		return nil
+	})
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+
+	sortMatches(matches)
+	return matches, nil
+}
+
+func fileExpectations(path, rel string) ([]Match, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	defer f.Close()
+
+	var matches []Match
+	var pending []string
+	scanner := bufio.NewScanner(f)
+	for line := 1; scanner.Scan(); line++ {
+		text := scanner.Text()
+		for _, tenet := range pending {
+			matches = append(matches, Match{Tenet: tenet, File: rel, Line: line})
+		}
+		pending = nil
+
+		loc := markerRegexp.FindStringSubmatchIndex(text)
+		if loc == nil {
+			continue
+		}
+		var tenets []string
+		for _, tenet := range strings.Split(text[loc[2]:loc[3]], ",") {
+			tenets = append(tenets, strings.TrimSpace(tenet))
+		}
+
+		if isBlankComment(text[:loc[0]]) {
+			pending = tenets
+			continue
+		}
+		for _, tenet := range tenets {
+			matches = append(matches, Match{Tenet: tenet, File: rel, Line: line})
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, errors.Annotatef(err, "reading %s", path)
+	}
+	if len(pending) > 0 {
+		return nil, errors.Errorf("%s: marker on the last line has no line to apply to", path)
+	}
+	return matches, nil
+}
+
+func isBlankComment(s string) bool {
+	s = strings.TrimSpace(s)
+	for _, leader := range commentLeaders {
+		s = strings.TrimSpace(strings.Replace(s, leader, "", -1))
+	}
+	return s == ""
+}
+
+func sortMatches(matches []Match) {
+	sort.Slice(matches, func(i, j int) bool {
+		a, b := matches[i], matches[j]
+		if a.File != b.File {
+			return a.File < b.File
+		}
+		if a.Line != b.Line {
+			return a.Line < b.Line
+		}
+		return a.Tenet < b.Tenet
+	})
+}
@@ -0,0 +1,82 @@
+package tenettest
+
+import (
+	"fmt"
+	"io"
+)
+
+// Result is the outcome of testing one Tenet.
+type Result struct {
+	Tenet string
+	// Missing are the expected matches the Tenet did not make.
+	Missing []Match
+	// Unexpected are the matches the Tenet made which were not expected.
+	Unexpected []Match
+}
+
+// Passed reports whether the Tenet made exactly the expected matches.
+func (r *Result) Passed() bool {
+	return len(r.Missing) == 0 && len(r.Unexpected) == 0
+}
+
+// Compare returns the result of each named Tenet given its expected and
+// actual matches.
+func Compare(tenets []string, expected, actual []Match) []*Result {
+	results := make([]*Result, len(tenets))
+	byTenet := make(map[string]*Result)
+	for i, tenet := range tenets {
+		results[i] = &Result{Tenet: tenet}
+		byTenet[tenet] = results[i]
+	}
+
+	want := matchSet(expected)
+	got := matchSet(actual)
+	sortMatches(expected)
+	sortMatches(actual)
+
+	for _, m := range expected {
+		if !got[m] {
+			if r, ok := byTenet[m.Tenet]; ok {
+				r.Missing = append(r.Missing, m)
+			}
+		}
+	}
+	for _, m := range actual {
+		if !want[m] {
+			if r, ok := byTenet[m.Tenet]; ok {
+				r.Unexpected = append(r.Unexpected, m)
+			}
+		}
+	}
+	return results
+}
+
+func matchSet(matches []Match) map[Match]bool {
+	set := make(map[Match]bool)
+	for _, m := range matches {
+		set[m] = true
+	}
+	return set
+}
+
+// Report writes a pass/fail line for each result, followed by the difference
+// between the expected and actual matches of each failed Tenet. It returns
+// the number of failed Tenets.
+func Report(w io.Writer, results []*Result) int {
+	failed := 0
+	for _, r := range results {
+		if r.Passed() {
+			fmt.Fprintf(w, "PASS %s\n", r.Tenet)
+			continue
+		}
+		failed++
+		fmt.Fprintf(w, "FAIL %s\n", r.Tenet)
+		for _, m := range r.Missing {
+			fmt.Fprintf(w, "    - %s (expected, not matched)\n", m)
+		}
+		for _, m := range r.Unexpected {
+			fmt.Fprintf(w, "    + %s (matched, not expected)\n", m)
+		}
+	}
+	return failed
+}
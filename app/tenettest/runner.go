@@ -0,0 +1,51 @@
+package tenettest
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/juju/errors"
+)
+
+// Runner runs the Tenets of a codelingo.yaml against the fixtures in a
+// directory.
+type Runner interface {
+	Run(ctx context.Context, dotlingoPath, fixtureDir string) ([]Match, error)
+}
+
+// CommandRunner is a local stand-in for the platform. It runs a command with
+// the codelingo.yaml path and the fixture directory appended to its
+// arguments. The command must write a JSON array of matches to stdout, with
+// each file relative to the fixture directory.
+type CommandRunner struct {
+	Command string
+}
+
+func (r *CommandRunner) Run(ctx context.Context, dotlingoPath, fixtureDir string) ([]Match, error) {
+	args := strings.Fields(r.Command)
+	if len(args) == 0 {
+		return nil, errors.New("no runner command given")
+	}
+	args = append(args, dotlingoPath, fixtureDir)
+
+	stdout, stderr := &bytes.Buffer{}, &bytes.Buffer{}
+	cmd := exec.CommandContext(ctx, args[0], args[1:]...)
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
+	if err := cmd.Run(); err != nil {
+		return nil, errors.Annotatef(err, "running %q: %s", r.Command, strings.TrimSpace(stderr.String()))
+	}
+
+	var matches []Match
+	if err := json.Unmarshal(stdout.Bytes(), &matches); err != nil {
+		return nil, errors.Annotatef(err, "reading the output of %q", r.Command)
+	}
+	for i := range matches {
+		matches[i].File = filepath.ToSlash(filepath.Clean(matches[i].File))
+	}
+	return matches, nil
+}
@@ -0,0 +1,87 @@
+package tenettest
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	jc "github.com/juju/testing/checkers"
+	gc "gopkg.in/check.v1"
+)
+
+func Test(t *testing.T) {
+	gc.TestingT(t)
+}
+
+type tenettestSuite struct{}
+
+var _ = gc.Suite(&tenettestSuite{})
+
+func writeFile(c *gc.C, path, content string) {
+	c.Assert(os.MkdirAll(filepath.Dir(path), 0755), jc.ErrorIsNil)
+	c.Assert(ioutil.WriteFile(path, []byte(content), 0644), jc.ErrorIsNil)
+}
+
+func (s *tenettestSuite) TestExpectations(c *gc.C) {
+	dir := c.MkDir()
+	writeFile(c, filepath.Join(dir, "main.go"), `package main
+
+func main() {
+	fmt.Println("hi") // lingo:expect no-println
+	// lingo:expect no-println, no-literals
+	fmt.Println("bye")
+}
+`)
+	writeFile(c, filepath.Join(dir, "sub", "x.py"), "print(1)  # lingo:expect no-print\n")
+
+	matches, err := Expectations(dir)
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(matches, jc.DeepEquals, []Match{
+		{Tenet: "no-println", File: "main.go", Line: 4},
+		{Tenet: "no-literals", File: "main.go", Line: 6},
+		{Tenet: "no-println", File: "main.go", Line: 6},
+		{Tenet: "no-print", File: "sub/x.py", Line: 1},
+	})
+
+	writeFile(c, filepath.Join(dir, "last.go"), "// lingo:expect no-println")
+	_, err = Expectations(dir)
+	c.Assert(err, gc.ErrorMatches, ".*last.go: marker on the last line has no line to apply to")
+}
+
+func (s *tenettestSuite) TestCompareAndReport(c *gc.C) {
+	expected := []Match{
+		{Tenet: "a", File: "main.go", Line: 4},
+		{Tenet: "b", File: "main.go", Line: 6},
+	}
+	actual := []Match{
+		{Tenet: "b", File: "main.go", Line: 6},
+		{Tenet: "a", File: "main.go", Line: 5},
+	}
+
+	results := Compare([]string{"a", "b"}, expected, actual)
+	c.Assert(results, gc.HasLen, 2)
+	c.Assert(results[0].Passed(), jc.IsFalse)
+	c.Assert(results[1].Passed(), jc.IsTrue)
+
+	buf := &bytes.Buffer{}
+	c.Assert(Report(buf, results), gc.Equals, 1)
+	c.Assert(buf.String(), gc.Equals, `FAIL a
+    - main.go:4 (expected, not matched)
+    + main.go:5 (matched, not expected)
+PASS b
+`)
+}
+
+func (s *tenettestSuite) TestCommandRunner(c *gc.C) {
+	dir := c.MkDir()
+	script := filepath.Join(dir, "runner.sh")
+	writeFile(c, script, "#!/bin/sh\necho '[{\"tenet\": \"a\", \"file\": \"./main.go\", \"line\": 4}]'\n")
+	c.Assert(os.Chmod(script, 0755), jc.ErrorIsNil)
+
+	matches, err := (&CommandRunner{Command: script}).Run(context.Background(), "codelingo.yaml", "testdata")
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(matches, jc.DeepEquals, []Match{{Tenet: "a", File: "main.go", Line: 4}})
+}
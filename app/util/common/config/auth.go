@@ -1,14 +1,18 @@
 package config
 
 import (
+	"crypto/md5"
+	"encoding/hex"
 	"io/ioutil"
 	"os"
 	"path/filepath"
+	"strings"
 
+	"github.com/codelingo/lingo/app/credentials"
+	"github.com/codelingo/lingo/app/login"
 	"github.com/codelingo/lingo/app/util"
 	"github.com/codelingo/lingo/service/config"
 	"github.com/juju/errors"
-	"strings"
 )
 
 const (
@@ -19,16 +23,29 @@ const (
 	p4Password            = "p4server.user.password"
 )
 
+// usernameEnvVar overrides the username in auth.yaml, for CI where there is
+// no config.
+var usernameEnvVar = credentials.EnvVar("username")
+
+// authConfig holds the user's username in auth.yaml, and their token in the
+// credentials store.
 type authConfig struct {
 	*config.FileConfig
+	path    string
+	profile string
+	store   credentials.Store
 }
 
 func AuthInDir(dir string) (*authConfig, error) {
-	configHome, err := util.ConfigHome()
+	profileHome, err := util.ProfileHome()
 	if err != nil {
 		return nil, errors.Trace(err)
 	}
-	envFile := filepath.Join(configHome, EnvCfgFile)
+	return authInDir(dir, profileHome)
+}
+
+func authInDir(dir, profileHome string) (*authConfig, error) {
+	envFile := filepath.Join(profileHome, EnvCfgFile)
 	cfg := config.New(envFile)
 
 	aCfgPath := filepath.Join(dir, AuthCfgFile)
@@ -38,22 +55,41 @@ func AuthInDir(dir string) (*authConfig, error) {
 	}
 
 	return &authConfig{
-		aCfg,
+		FileConfig: aCfg,
+		path:       aCfgPath,
 	}, nil
 }
 
 func Auth() (*authConfig, error) {
-	configHome, err := util.ConfigHome()
+	profile, err := util.Profile()
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	if _, err := util.ProfileHome(); err != nil {
+		return nil, errors.Trace(err)
+	}
+	return AuthForProfile(profile)
+}
+
+// AuthForProfile returns the auth config of the named profile, which needn't
+// be the one in use.
+func AuthForProfile(profile string) (*authConfig, error) {
+	dir, err := util.ProfileDir(profile)
 	if err != nil {
 		return nil, errors.Trace(err)
 	}
-	return AuthInDir(configHome)
+	a, err := authInDir(dir, dir)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	a.profile = profile
+	return a, nil
 }
 
 func CreateAuthFileInDir(dir string, overwrite bool) error {
 	aCfgFilePath := filepath.Join(dir, AuthCfgFile)
 	if _, err := os.Stat(aCfgFilePath); os.IsNotExist(err) || overwrite {
-		err := ioutil.WriteFile(aCfgFilePath, []byte(AuthTmpl), 0644)
+		err := ioutil.WriteFile(aCfgFilePath, []byte(AuthTmpl), 0600)
 		if err != nil {
 			return errors.Annotate(err, "verifyConfig: Could not create auth config")
 		}
@@ -62,20 +98,19 @@ func CreateAuthFileInDir(dir string, overwrite bool) error {
 }
 
 func CreateAuthFile() error {
-	configHome, err := util.ConfigHome()
+	profileHome, err := util.ProfileHome()
 	if err != nil {
 		return errors.Trace(err)
 	}
-	return CreateAuthFileInDir(configHome, false)
+	return CreateAuthFileInDir(profileHome, false)
 }
 
 func (a *authConfig) Dump() (map[string]interface{}, error) {
 	keyMap := make(map[string]interface{})
 
 	var authDumpConsts = []string{
-		gitCredentialFilename,
 		gitUserName,
-		gitPassword,
+		p4UserName,
 	}
 
 	for _, aCon := range authDumpConsts {
@@ -91,31 +126,10 @@ func (a *authConfig) Dump() (map[string]interface{}, error) {
 	return keyMap, nil
 }
 
-func (a *authConfig) GetGitCredentialsFilename() (string, error) {
-	env, err := a.GetEnv()
-	if err != nil {
-		return "", errors.Trace(err)
-	}
-	val, err := a.GetForEnv(env, gitCredentialFilename)
-	if err != nil {
-		if strings.Contains(err.Error(), "Could not find value") {
-			credName := "git-credentials-" + env
-			err = a.SetForEnv(env, gitCredentialFilename, credName)
-			if err != nil {
-				return "", errors.Trace(err)
-			}
-			return credName, nil
-		}
-		return "", errors.Trace(err)
-	}
-	str, ok := val.(string)
-	if !ok {
-		return "", errors.Errorf("unexpected value type %T", val)
-	}
-	return str, nil
-}
-
 func (a *authConfig) GetGitUserName() (string, error) {
+	if username := os.Getenv(usernameEnvVar); username != "" {
+		return username, nil
+	}
 	return a.GetValue(gitUserName)
 }
 
@@ -123,15 +137,38 @@ func (a *authConfig) SetGitUserName(userName string) error {
 	return a.Set(gitUserName, userName)
 }
 
+// GetGitUserPassword returns the user's token from the credentials store,
+// refreshing it first if it has expired.
+// TODO(waigani) change "password" to "token"
 func (a *authConfig) GetGitUserPassword() (string, error) {
-	return a.GetValue(gitPassword)
+	env, err := a.GetEnv()
+	if err != nil {
+		return "", errors.Trace(err)
+	}
+	store, err := a.Store()
+	if err != nil {
+		return "", errors.Trace(err)
+	}
+	token, err := store.Get(a.secretName(env, credentials.TokenName))
+	if errors.IsNotFound(err) {
+		return "", errors.Errorf("Could not find value for config %q. Please run `lingo config setup`.", gitPassword)
+	}
+	if err != nil {
+		return "", errors.Trace(err)
+	}
+	return a.refreshedToken(store, env, token)
 }
 
+// SetGitUserPassword keeps a token, which doesn't expire, in the
+// credentials store.
 func (a *authConfig) SetGitUserPassword(userPassword string) error {
-	return a.Set(gitPassword, userPassword)
+	return errors.Trace(a.SaveLogin(&login.Token{AccessToken: userPassword}))
 }
 
 func (a *authConfig) GetP4UserName() (string, error) {
+	if username := os.Getenv(usernameEnvVar); username != "" {
+		return username, nil
+	}
 	return a.GetValue(p4UserName)
 }
 
@@ -139,31 +176,23 @@ func (a *authConfig) SetP4UserName(userName string) error {
 	return a.Set(p4UserName, userName)
 }
 
+// GetP4UserPassword returns the user's Perforce password, which the
+// Perforce server expects to be the upper case MD5 of their token. It is
+// derived when needed rather than stored.
 func (a *authConfig) GetP4UserPassword() (string, error) {
-	return a.GetValue(p4Password)
-}
-
-func (a *authConfig) SetP4UserPassword(userPassword string) error {
-	return a.Set(p4Password, userPassword)
+	token, err := a.GetGitUserPassword()
+	if err != nil {
+		return "", errors.Trace(err)
+	}
+	hash := md5.Sum([]byte(token))
+	return strings.ToUpper(hex.EncodeToString(hash[:])), nil
 }
 
+// AuthTmpl is the initial auth.yaml. Tokens are kept in the credentials
+// store, not here.
 var AuthTmpl = `
 paas:
   gitserver:
-    credentials_filename: git-credentials
     user:
-      password: ""
       username: ""
-dev:
-  gitserver:
-    credentials_filename: git-credentials-dev
-onprem:
-  gitserver:
-    credentials_filename: git-credentials-onprem
-test:
-  gitserver:
-    credentials_filename: git-credentials-test
-staging:
-  gitserver:
-    credentials_filename: git-credentials-staging
 `[1:]
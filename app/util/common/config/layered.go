@@ -0,0 +1,440 @@
+package config
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/juju/errors"
+	"gopkg.in/yaml.v2"
+)
+
+// The layers a setting can come from, from lowest to highest precedence. A
+// setting in one layer overrides the same setting in the layers before it.
+const (
+	OriginDefault = "default"
+	OriginUser    = "user"
+	OriginProject = "project"
+	OriginEnv     = "env"
+	OriginFlag    = "flag"
+)
+
+// ProjectCfgFile is the project's config file, relative to the root of its
+// repository.
+var ProjectCfgFile = filepath.Join(".codelingo", "config.yaml")
+
+// Keys are the settings lingo reads, which can also be set with environment
+// variables.
+var Keys = []string{
+	websiteHTTPAddr,
+	platformGRPCAddr,
+	flowGRPCAddr,
+	platformTLS,
+	flowTLS,
+	gitServerAddr,
+	gitServerRemote,
+	p4RemoteName,
+	p4RemoteDepotName,
+	p4ServerHost,
+	p4ServerPort,
+	p4ServerProtocol,
+	actionsPublicKey,
+	actionsRegistry,
+	actionsOwners,
+	actionsSandbox,
+	actionsTimeout,
+	discoveryRoot,
+	credentialsBackend,
+	defaultOwner,
+}
+
+// ProjectKeys are the only settings a project's config file may set. A
+// cloned repository could use the others, such as the platform's addresses
+// or the key Actions are verified with, to steal the user's credentials or
+// run its own code, so they are ignored.
+var ProjectKeys = []string{
+	gitServerRemote,
+	p4RemoteName,
+	p4RemoteDepotName,
+	actionsOwners,
+	actionsTimeout,
+	discoveryRoot,
+	defaultOwner,
+}
+
+var keyRegexp = regexp.MustCompile(`^[a-z0-9_-]+(\.[a-z0-9_-]+)*$`)
+
+// flagValues are the settings given with --config on the command line.
+var flagValues = map[string]string{}
+
+// Setting is the value of a key and where it was set.
+type Setting struct {
+	Key    string
+	Value  string
+	Origin string
+	// Source is the file, environment variable or flag the value was read
+	// from. It is empty for defaults.
+	Source string
+}
+
+// String returns where the setting was set, as origin:source.
+func (s *Setting) String() string {
+	if s.Source == "" {
+		return s.Origin
+	}
+	return s.Origin + ":" + s.Source
+}
+
+// Settings are the values of every key set in any layer.
+type Settings struct {
+	byKey map[string][]*Setting
+}
+
+// ValidKey returns an error if key is not a dot separated path of lower
+// case names.
+func ValidKey(key string) error {
+	if !keyRegexp.MatchString(key) {
+		return errors.Errorf("invalid key %q, expected a dot separated path such as discovery.root", key)
+	}
+	return nil
+}
+
+// IsProjectKey returns true if key may be set in a project's config file.
+func IsProjectKey(key string) bool {
+	for _, k := range ProjectKeys {
+		if k == key {
+			return true
+		}
+	}
+	return false
+}
+
+// EnvVar returns the environment variable that sets key.
+func EnvVar(key string) string {
+	return "LINGO_" + strings.ToUpper(strings.NewReplacer(".", "_", "-", "_").Replace(key))
+}
+
+// SetFlagValues sets the values given with --config, each of the form
+// key=value. They override every other layer.
+func SetFlagValues(values []string) error {
+	flags := map[string]string{}
+	for _, value := range values {
+		parts := strings.SplitN(value, "=", 2)
+		if len(parts) != 2 {
+			return errors.Errorf("invalid --config %q, expected key=value", value)
+		}
+		if err := ValidKey(parts[0]); err != nil {
+			return errors.Trace(err)
+		}
+		flags[parts[0]] = parts[1]
+	}
+	flagValues = flags
+	return nil
+}
+
+// Resolve reads the settings of env from every layer, where userFile is the
+// user's platform.yaml.
+func Resolve(env, userFile string) (*Settings, error) {
+	s := &Settings{byKey: map[string][]*Setting{}}
+
+	defaults, err := parseNested([]byte(defaultConfig))
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	s.addEnvSections(defaults, "paas", OriginDefault, "")
+
+	user, err := readNested(userFile)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	s.addEnvSections(user, env, OriginUser, userFile)
+
+	projectFile, err := ProjectFile()
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	if projectFile != "" {
+		project, err := readNested(projectFile)
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		for key, value := range flatten(project) {
+			if IsProjectKey(key) {
+				s.set(key, value, OriginProject, projectFile)
+			}
+		}
+	}
+
+	keys := map[string]bool{}
+	for _, key := range Keys {
+		keys[key] = true
+	}
+	for key := range s.byKey {
+		keys[key] = true
+	}
+	for key := range flagValues {
+		keys[key] = true
+	}
+	for key := range keys {
+		if value, ok := os.LookupEnv(EnvVar(key)); ok {
+			s.set(key, value, OriginEnv, EnvVar(key))
+		}
+	}
+
+	s.add(flagValues, OriginFlag, "--config")
+	return s, nil
+}
+
+// Get returns the setting of key with the highest precedence, or nil if it
+// isn't set.
+func (s *Settings) Get(key string) *Setting {
+	settings := s.byKey[key]
+	if len(settings) == 0 {
+		return nil
+	}
+	return settings[len(settings)-1]
+}
+
+// List returns the setting of every key with the highest precedence, sorted
+// by key.
+func (s *Settings) List() []*Setting {
+	var keys []string
+	for key := range s.byKey {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var list []*Setting
+	for _, key := range keys {
+		list = append(list, s.Get(key))
+	}
+	return list
+}
+
+// addEnvSections adds the settings of a file split into environments,
+// where those of env override those of paas.
+func (s *Settings) addEnvSections(data map[string]interface{}, env, origin, source string) {
+	sections := []string{"paas"}
+	if env != "paas" {
+		sections = append(sections, env)
+	}
+	for _, section := range sections {
+		if m, ok := data[section].(map[interface{}]interface{}); ok {
+			s.add(flatten(convertMap(m)), origin, source)
+		}
+	}
+}
+
+func (s *Settings) add(values map[string]string, origin, source string) {
+	for key, value := range values {
+		s.set(key, value, origin, source)
+	}
+}
+
+func (s *Settings) set(key, value, origin, source string) {
+	settings := s.byKey[key]
+	// A layer may set a key twice, as addEnvSections does.
+	if n := len(settings); n > 0 && settings[n-1].Origin == origin {
+		settings = settings[:n-1]
+	}
+	s.byKey[key] = append(settings, &Setting{Key: key, Value: value, Origin: origin, Source: source})
+}
+
+// ProjectFile returns the project's config file in the root of the
+// repository containing the current directory, or an empty string if there
+// is no repository or it has no config file.
+func ProjectFile() (string, error) {
+	root, err := ProjectRoot()
+	if err != nil || root == "" {
+		return "", errors.Trace(err)
+	}
+	path := filepath.Join(root, ProjectCfgFile)
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		return "", nil
+	} else if err != nil {
+		return "", errors.Trace(err)
+	}
+	return path, nil
+}
+
+// ProjectRoot returns the root of the git repository containing the current
+// directory, or an empty string if it isn't in one.
+func ProjectRoot() (string, error) {
+	cwd, err := os.Getwd()
+	if err != nil {
+		return "", errors.Trace(err)
+	}
+	for dir := cwd; ; dir = filepath.Dir(dir) {
+		if _, err := os.Stat(filepath.Join(dir, ".git")); err == nil {
+			return dir, nil
+		}
+		if dir == filepath.Dir(dir) {
+			return "", nil
+		}
+	}
+}
+
+// SetProject sets key in the project's config file, creating it if needed,
+// and returns the file's path.
+func SetProject(key, value string) (string, error) {
+	if !IsProjectKey(key) {
+		return "", errors.Errorf("%s cannot be set for a project, only for you. Project settings are limited to %s", key, strings.Join(ProjectKeys, ", "))
+	}
+	path, data, err := editProject()
+	if err != nil {
+		return "", errors.Trace(err)
+	}
+	cfg := toTree(data)
+	if err := setNested(cfg, strings.Split(key, "."), value); err != nil {
+		return "", errors.Annotatef(err, "failed to set %s in %s", key, path)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return "", errors.Trace(err)
+	}
+	return path, errors.Trace(writeNested(path, cfg))
+}
+
+// UnsetProject removes key from the project's config file, returning false
+// if it wasn't set there.
+func UnsetProject(key string) (bool, error) {
+	path, data, err := editProject()
+	if err != nil {
+		return false, errors.Trace(err)
+	}
+	cfg := toTree(data)
+	if !unsetNested(cfg, strings.Split(key, ".")) {
+		return false, nil
+	}
+	if len(cfg) == 0 {
+		return true, errors.Trace(os.Remove(path))
+	}
+	return true, errors.Trace(writeNested(path, cfg))
+}
+
+// editProject returns the path and contents of the project's config file,
+// which may not exist yet.
+func editProject() (string, map[string]interface{}, error) {
+	root, err := ProjectRoot()
+	if err != nil {
+		return "", nil, errors.Trace(err)
+	}
+	if root == "" {
+		return "", nil, errors.New("not in a git repository. The project config is kept in .codelingo/config.yaml at the root of one")
+	}
+	path := filepath.Join(root, ProjectCfgFile)
+	data, err := readNested(path)
+	return path, data, errors.Trace(err)
+}
+
+func toTree(data map[string]interface{}) map[interface{}]interface{} {
+	tree := map[interface{}]interface{}{}
+	for k, v := range data {
+		tree[k] = v
+	}
+	return tree
+}
+
+// setNested sets the value at the path of keys, creating maps along it.
+func setNested(m map[interface{}]interface{}, keys []string, value string) error {
+	if len(keys) == 1 {
+		m[keys[0]] = value
+		return nil
+	}
+	if m[keys[0]] == nil {
+		m[keys[0]] = map[interface{}]interface{}{}
+	}
+	child, ok := m[keys[0]].(map[interface{}]interface{})
+	if !ok {
+		return errors.Errorf("%s is already set to a value", keys[0])
+	}
+	return setNested(child, keys[1:], value)
+}
+
+// unsetNested removes the value at the path of keys, and any maps left
+// empty, returning false if there was no value.
+func unsetNested(m map[interface{}]interface{}, keys []string) bool {
+	if len(keys) == 1 {
+		if _, ok := m[keys[0]]; !ok {
+			return false
+		}
+		delete(m, keys[0])
+		return true
+	}
+	child, ok := m[keys[0]].(map[interface{}]interface{})
+	if !ok || !unsetNested(child, keys[1:]) {
+		return false
+	}
+	if len(child) == 0 {
+		delete(m, keys[0])
+	}
+	return true
+}
+
+func writeNested(path string, m map[interface{}]interface{}) error {
+	data, err := yaml.Marshal(m)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	return errors.Trace(ioutil.WriteFile(path, data, 0644))
+}
+
+// readNested reads a yaml file, returning an empty map if it doesn't exist.
+func readNested(path string) (map[string]interface{}, error) {
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return map[string]interface{}{}, nil
+	}
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	m, err := parseNested(data)
+	return m, errors.Annotatef(err, "problem unmarshalling %s", path)
+}
+
+func parseNested(data []byte) (map[string]interface{}, error) {
+	m := map[string]interface{}{}
+	if err := yaml.Unmarshal(data, &m); err != nil {
+		return nil, errors.Trace(err)
+	}
+	return m, nil
+}
+
+// flatten returns the scalar values of a nested map keyed by their dot
+// separated paths. Lists are joined with commas.
+func flatten(m map[string]interface{}) map[string]string {
+	values := map[string]string{}
+	var walk func(prefix string, value interface{})
+	walk = func(prefix string, value interface{}) {
+		switch v := value.(type) {
+		case nil:
+		case map[interface{}]interface{}:
+			for k, child := range v {
+				walk(prefix+"."+fmt.Sprint(k), child)
+			}
+		case []interface{}:
+			var items []string
+			for _, item := range v {
+				items = append(items, fmt.Sprint(item))
+			}
+			values[prefix] = strings.Join(items, ",")
+		default:
+			values[prefix] = fmt.Sprint(v)
+		}
+	}
+	for k, v := range m {
+		walk(k, v)
+	}
+	return values
+}
+
+func convertMap(m map[interface{}]interface{}) map[string]interface{} {
+	converted := map[string]interface{}{}
+	for k, v := range m {
+		converted[fmt.Sprint(k)] = v
+	}
+	return converted
+}
@@ -0,0 +1,164 @@
+package config
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	jc "github.com/juju/testing/checkers"
+	gc "gopkg.in/check.v1"
+)
+
+func Test(t *testing.T) {
+	gc.TestingT(t)
+}
+
+type layeredSuite struct {
+	cwd string
+}
+
+var _ = gc.Suite(&layeredSuite{})
+
+func (s *layeredSuite) SetUpTest(c *gc.C) {
+	var err error
+	s.cwd, err = os.Getwd()
+	c.Assert(err, jc.ErrorIsNil)
+}
+
+func (s *layeredSuite) TearDownTest(c *gc.C) {
+	c.Assert(os.Chdir(s.cwd), jc.ErrorIsNil)
+	c.Assert(SetFlagValues(nil), jc.ErrorIsNil)
+	os.Unsetenv("LINGO_ACTIONS_TIMEOUT")
+}
+
+func write(c *gc.C, path, content string) {
+	c.Assert(os.MkdirAll(filepath.Dir(path), 0755), jc.ErrorIsNil)
+	c.Assert(ioutil.WriteFile(path, []byte(content), 0644), jc.ErrorIsNil)
+}
+
+func (s *layeredSuite) TestResolve(c *gc.C) {
+	repo := c.MkDir()
+	c.Assert(os.Mkdir(filepath.Join(repo, ".git"), 0755), jc.ErrorIsNil)
+	c.Assert(os.Mkdir(filepath.Join(repo, "sub"), 0755), jc.ErrorIsNil)
+	c.Assert(os.Chdir(filepath.Join(repo, "sub")), jc.ErrorIsNil)
+
+	userFile := filepath.Join(c.MkDir(), PlatformCfgFile)
+	write(c, userFile, `
+paas:
+  website: https://paas.example
+  discovery:
+    root: /user
+  actions:
+    timeout: 1m
+    sandbox: true
+dev:
+  website: https://dev.example
+`)
+	projectFile := filepath.Join(repo, ProjectCfgFile)
+	write(c, projectFile, `
+discovery:
+  root: /project
+actions:
+  timeout: 2m
+  owners: [codelingo, acme]
+`)
+	os.Setenv("LINGO_ACTIONS_TIMEOUT", "3m")
+	c.Assert(SetFlagValues([]string{"discovery.root=/flag"}), jc.ErrorIsNil)
+
+	settings, err := Resolve("dev", userFile)
+	c.Assert(err, jc.ErrorIsNil)
+
+	for key, want := range map[string]Setting{
+		"flow":            {Value: "grpc-flow.codelingo.io:443", Origin: OriginDefault},
+		"website":         {Value: "https://dev.example", Origin: OriginUser, Source: userFile},
+		"actions.sandbox": {Value: "true", Origin: OriginUser, Source: userFile},
+		"actions.owners":  {Value: "codelingo,acme", Origin: OriginProject, Source: projectFile},
+		"actions.timeout": {Value: "3m", Origin: OriginEnv, Source: "LINGO_ACTIONS_TIMEOUT"},
+		"discovery.root":  {Value: "/flag", Origin: OriginFlag, Source: "--config"},
+	} {
+		want.Key = key
+		c.Check(settings.Get(key), jc.DeepEquals, &want, gc.Commentf(key))
+	}
+	c.Assert(settings.Get("actions.publickey"), gc.IsNil)
+	c.Assert(settings.List(), gc.HasLen, 9)
+}
+
+func (s *layeredSuite) TestResolveUnsafeProjectKeys(c *gc.C) {
+	repo := c.MkDir()
+	c.Assert(os.Mkdir(filepath.Join(repo, ".git"), 0755), jc.ErrorIsNil)
+	c.Assert(os.Chdir(repo), jc.ErrorIsNil)
+	userFile := filepath.Join(c.MkDir(), PlatformCfgFile)
+	write(c, userFile, "paas:\n  actions:\n    sandbox: true\n")
+	projectFile := filepath.Join(repo, ProjectCfgFile)
+	write(c, projectFile, `
+website: https://attacker.example
+platform: attacker.example:443
+flow: attacker.example:443
+gitserver:
+  addr: https://attacker.example
+tls:
+  platform: false
+actions:
+  publickey: attacker
+  registry: https://attacker.example
+  sandbox: false
+credentials:
+  backend: file
+owner: acme
+`)
+
+	settings, err := Resolve("paas", userFile)
+	c.Assert(err, jc.ErrorIsNil)
+
+	// A cloned repository can't redirect the user's credentials, or change
+	// how Actions are verified and run.
+	for _, key := range []string{"website", "platform", "flow", "gitserver.addr", "tls.platform", "actions.publickey", "actions.registry", "actions.sandbox", "credentials.backend"} {
+		if setting := settings.Get(key); setting != nil {
+			c.Check(setting.Origin, gc.Not(gc.Equals), OriginProject, gc.Commentf(key))
+		}
+	}
+	c.Assert(settings.Get("actions.sandbox").Value, gc.Equals, "true")
+	c.Assert(settings.Get("owner"), jc.DeepEquals, &Setting{Key: "owner", Value: "acme", Origin: OriginProject, Source: projectFile})
+}
+
+func (s *layeredSuite) TestSetFlagValues(c *gc.C) {
+	c.Assert(SetFlagValues([]string{"website=a=b"}), jc.ErrorIsNil)
+	c.Assert(flagValues, jc.DeepEquals, map[string]string{"website": "a=b"})
+	c.Assert(SetFlagValues([]string{"website"}), gc.ErrorMatches, `invalid --config "website", expected key=value`)
+	c.Assert(SetFlagValues([]string{"Web Site=x"}), gc.ErrorMatches, `invalid key "Web Site", .*`)
+}
+
+func (s *layeredSuite) TestSetProject(c *gc.C) {
+	c.Assert(os.Chdir(c.MkDir()), jc.ErrorIsNil)
+	_, err := SetProject("discovery.root", "/x")
+	c.Assert(err, gc.ErrorMatches, "not in a git repository.*")
+
+	repo := c.MkDir()
+	c.Assert(os.Mkdir(filepath.Join(repo, ".git"), 0755), jc.ErrorIsNil)
+	c.Assert(os.Chdir(repo), jc.ErrorIsNil)
+	path, err := SetProject("discovery.root", "/x")
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(path, gc.Equals, filepath.Join(repo, ProjectCfgFile))
+	_, err = SetProject("actions.timeout", "1m")
+	c.Assert(err, jc.ErrorIsNil)
+	_, err = SetProject("discovery.root.nested", "1m")
+	c.Assert(err, gc.ErrorMatches, "discovery.root.nested cannot be set for a project, only for you.*")
+	_, err = SetProject("website", "https://example.com")
+	c.Assert(err, gc.ErrorMatches, "website cannot be set for a project, only for you.*")
+
+	data, err := ioutil.ReadFile(path)
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(string(data), gc.Equals, "actions:\n  timeout: 1m\ndiscovery:\n  root: /x\n")
+
+	for _, key := range []string{"discovery.root", "actions.timeout"} {
+		unset, err := UnsetProject(key)
+		c.Assert(err, jc.ErrorIsNil)
+		c.Assert(unset, jc.IsTrue)
+	}
+	unset, err := UnsetProject("actions.timeout")
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(unset, jc.IsFalse)
+	_, err = os.Stat(path)
+	c.Assert(os.IsNotExist(err), jc.IsTrue)
+}
@@ -0,0 +1,166 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/codelingo/lingo/app/credentials"
+	"github.com/codelingo/lingo/app/login"
+	"github.com/juju/errors"
+)
+
+// lingoHelpers matches the git credential helpers set by lingo: its own,
+// and the plaintext credentials files of earlier versions.
+const lingoHelpers = `config git-credential$|^store --file .*git-credentials`
+
+// LoggedInEnvs returns the environments which may have been set up with
+// `lingo config setup`: those with a section in auth.yaml or platform.yaml,
+// and the current environment.
+func (a *authConfig) LoggedInEnvs() ([]string, error) {
+	envs := map[string]bool{}
+	current, err := a.GetEnv()
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	envs[current] = true
+	for _, path := range []string{a.path, a.platformPath()} {
+		data, err := readNested(path)
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		for env := range data {
+			envs[env] = true
+		}
+	}
+
+	var sorted []string
+	for env := range envs {
+		sorted = append(sorted, env)
+	}
+	sort.Strings(sorted)
+	return sorted, nil
+}
+
+// Logout removes everything `lingo config setup` saved for env: the
+// usernames in auth.yaml, the token in the credentials store and git's
+// credential helper for the env's git server. It returns the removed token,
+// so it can be revoked, or nil if env wasn't set up.
+func (a *authConfig) Logout(env string) (*login.Token, error) {
+	token, err := a.removeLogin(env)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	addr, err := a.platformValue(env, gitServerAddr)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	if addr != "" {
+		if err := UnsetGitCredentialHelper(addr); err != nil {
+			return nil, errors.Trace(err)
+		}
+	}
+	return token, nil
+}
+
+// removeLogin removes what `lingo config setup` saved for env in the
+// profile's own files and credentials, leaving git's credential helper,
+// which is shared by every profile using the same git server.
+func (a *authConfig) removeLogin(env string) (*login.Token, error) {
+	var token *login.Token
+	for _, key := range []string{gitUserName, p4UserName} {
+		if value, err := a.GetForEnv(env, key); err == nil && value != "" {
+			token = &login.Token{}
+		}
+	}
+
+	store, err := a.Store()
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	for _, name := range []string{credentials.TokenName, refreshTokenName} {
+		secret, err := store.Get(a.secretName(env, name))
+		if errors.IsNotFound(err) {
+			continue
+		}
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		if token == nil {
+			token = &login.Token{}
+		}
+		if name == credentials.TokenName {
+			token.AccessToken = secret
+		} else {
+			token.RefreshToken = secret
+		}
+	}
+
+	// Remove the plaintext credentials file of an earlier version, in case
+	// it wasn't migrated.
+	if credFile, err := a.GetForEnv(env, gitCredentialFilename); err == nil {
+		if name, ok := credFile.(string); ok && name != "" {
+			err := os.Remove(filepath.Join(filepath.Dir(a.path), name))
+			if err != nil && !os.IsNotExist(err) {
+				return nil, errors.Trace(err)
+			}
+		}
+	}
+	for _, key := range []string{gitUserName, gitPassword, p4UserName, p4Password, gitCredentialFilename} {
+		if _, err := a.UnsetForEnv(env, key); err != nil {
+			return nil, errors.Trace(err)
+		}
+	}
+
+	// Nothing is stored in the environment, so there is nothing to delete.
+	if store.Backend() != credentials.BackendEnv {
+		for _, name := range []string{credentials.TokenName, refreshTokenName, tokenExpiryName} {
+			if err := store.Delete(a.secretName(env, name)); err != nil {
+				return nil, errors.Trace(err)
+			}
+		}
+	}
+	return token, nil
+}
+
+// WebSiteAddressForEnv returns the address of the website of env.
+func (a *authConfig) WebSiteAddressForEnv(env string) (string, error) {
+	addr, err := a.platformValue(env, websiteHTTPAddr)
+	if err != nil {
+		return "", errors.Trace(err)
+	}
+	if addr == "" {
+		return "", errors.Errorf("Could not find value for config %q", websiteHTTPAddr)
+	}
+	return addr, nil
+}
+
+// platformValue returns the value of key for env, or "" if it isn't set.
+func (a *authConfig) platformValue(env, key string) (string, error) {
+	settings, err := Resolve(env, a.platformPath())
+	if err != nil {
+		return "", errors.Trace(err)
+	}
+	if s := settings.Get(key); s != nil {
+		return s.Value, nil
+	}
+	return "", nil
+}
+
+func (a *authConfig) platformPath() string {
+	return filepath.Join(filepath.Dir(a.path), PlatformCfgFile)
+}
+
+// UnsetGitCredentialHelper removes the credential helpers lingo set for the
+// git server at addr, leaving any others.
+func UnsetGitCredentialHelper(addr string) error {
+	key := fmt.Sprintf("credential.%s.helper", addr)
+	_, err := git("config", "--global", "--unset-all", key, lingoHelpers)
+	// git exits with 5 if there was nothing to unset.
+	if err != nil && !strings.Contains(err.Error(), "exit status 5") {
+		return errors.Annotatef(err, "failed to remove the git credential helper for %s", addr)
+	}
+	return nil
+}
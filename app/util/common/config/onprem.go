@@ -0,0 +1,306 @@
+package config
+
+import (
+	"crypto/tls"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/codelingo/lingo/app/credentials"
+	"github.com/codelingo/lingo/app/util"
+	"github.com/codelingo/lingo/service/config"
+	"github.com/juju/errors"
+	"gopkg.in/yaml.v2"
+)
+
+// The services of an on-prem platform, in the order they are configured.
+const (
+	ServiceWebsite   = "website"
+	ServicePlatform  = "platform"
+	ServiceFlow      = "flow"
+	ServiceGitServer = "gitserver"
+	ServiceP4Server  = "p4server"
+)
+
+// OnPremServices lists every on-prem service. All but the Perforce server are
+// required.
+var OnPremServices = []string{ServiceWebsite, ServicePlatform, ServiceFlow, ServiceGitServer, ServiceP4Server}
+
+// defaultPorts are the ports of each service with and without TLS.
+var defaultPorts = map[string][2]int{
+	ServiceWebsite:   {443, 80},
+	ServicePlatform:  {443, 443},
+	ServiceFlow:      {443, 443},
+	ServiceGitServer: {443, 80},
+	ServiceP4Server:  {1666, 1666},
+}
+
+// dialTimeout limits how long validating an address may take.
+const dialTimeout = 5 * time.Second
+
+// OnPremService is the address of an on-prem service.
+type OnPremService struct {
+	Host string `yaml:"host"`
+	Port int    `yaml:"port,omitempty"`
+	// TLS defaults to true.
+	TLS *bool `yaml:"tls,omitempty"`
+}
+
+// OnPrem is the addresses of the services of an on-prem platform, keyed by
+// service. It is also the layout of the config bundles platform admins hand
+// out.
+type OnPrem struct {
+	Version  int                       `yaml:"version"`
+	Services map[string]*OnPremService `yaml:"services"`
+}
+
+// ReadOnPremBundle reads a config bundle.
+func ReadOnPremBundle(path string) (*OnPrem, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	var o OnPrem
+	if err := yaml.UnmarshalStrict(data, &o); err != nil {
+		return nil, errors.Annotatef(err, "failed to parse %s", path)
+	}
+	if o.Version != 1 {
+		return nil, errors.Errorf("%s has unknown version %d, expected 1", path, o.Version)
+	}
+	for name := range o.Services {
+		if _, ok := defaultPorts[name]; !ok {
+			return nil, errors.Errorf("%s configures unknown service %q, expected one of %s", path, name, strings.Join(OnPremServices, ", "))
+		}
+	}
+	return &o, nil
+}
+
+// ParseService parses the address of a service, [scheme://]host[:port].
+// Schemes https and ssl, or none, use TLS, and http and tcp don't. The port
+// defaults to the service's usual one.
+func ParseService(name, addr string) (*OnPremService, error) {
+	s := &OnPremService{}
+	if i := strings.Index(addr, "://"); i >= 0 {
+		switch scheme := addr[:i]; scheme {
+		case "https", "ssl":
+		case "http", "tcp":
+			s.TLS = new(bool)
+		default:
+			return nil, errors.Errorf("invalid %s address %q: unknown scheme %q, expected https, ssl, http or tcp", name, addr, scheme)
+		}
+		addr = strings.TrimSuffix(addr[i+3:], "/")
+	}
+
+	s.Host = addr
+	if host, port, err := net.SplitHostPort(addr); err == nil {
+		if s.Port, err = strconv.Atoi(port); err != nil {
+			return nil, errors.Errorf("invalid %s address %q: bad port %q", name, addr, port)
+		}
+		s.Host = host
+	}
+	return s, errors.Trace(s.validate(name))
+}
+
+// UsesTLS reports whether the service is reached over TLS.
+func (s *OnPremService) UsesTLS() bool {
+	return s.TLS == nil || *s.TLS
+}
+
+// Addr returns the service's host:port.
+func (s *OnPremService) Addr() string {
+	return net.JoinHostPort(s.Host, strconv.Itoa(s.Port))
+}
+
+// String returns the service's address as accepted by ParseService.
+func (s *OnPremService) String() string {
+	if s.UsesTLS() {
+		return s.Addr()
+	}
+	return "tcp://" + s.Addr()
+}
+
+// setDefaults fills in the port of a service without one.
+func (s *OnPremService) setDefaults(name string) {
+	if s.Port == 0 {
+		ports := defaultPorts[name]
+		s.Port = ports[0]
+		if !s.UsesTLS() {
+			s.Port = ports[1]
+		}
+	}
+}
+
+func (s *OnPremService) validate(name string) error {
+	if s.Host == "" || strings.ContainsAny(s.Host, "/ ") {
+		return errors.Errorf("invalid %s host %q", name, s.Host)
+	}
+	if s.Port < 0 || s.Port > 65535 {
+		return errors.Errorf("invalid %s port %d", name, s.Port)
+	}
+	return nil
+}
+
+// Dial checks that the service can be reached, and that its certificate is
+// valid if it uses TLS.
+func (s *OnPremService) Dial() error {
+	dialer := &net.Dialer{Timeout: dialTimeout}
+	if !s.UsesTLS() {
+		conn, err := dialer.Dial("tcp", s.Addr())
+		if err != nil {
+			return errors.Trace(err)
+		}
+		return errors.Trace(conn.Close())
+	}
+	conn, err := tls.DialWithDialer(dialer, "tcp", s.Addr(), &tls.Config{ServerName: s.Host})
+	if err != nil {
+		return errors.Trace(err)
+	}
+	return errors.Trace(conn.Close())
+}
+
+// Check fills in the default ports, and returns an error if a required
+// service is missing or an address is invalid.
+func (o *OnPrem) Check() error {
+	for _, name := range OnPremServices {
+		s := o.Services[name]
+		if s == nil {
+			if name == ServiceP4Server {
+				continue
+			}
+			return errors.Errorf("no address given for the %s service", name)
+		}
+		s.setDefaults(name)
+		if err := s.validate(name); err != nil {
+			return errors.Trace(err)
+		}
+	}
+	return nil
+}
+
+// Dial checks that every service can be reached, returning an error naming
+// each that can't.
+func (o *OnPrem) Dial() error {
+	var failed []string
+	for _, name := range OnPremServices {
+		if s := o.Services[name]; s != nil {
+			if err := s.Dial(); err != nil {
+				failed = append(failed, fmt.Sprintf("  %s (%s): %v", name, s, err))
+			}
+		}
+	}
+	if len(failed) > 0 {
+		return errors.Errorf("could not reach:\n%s", strings.Join(failed, "\n"))
+	}
+	return nil
+}
+
+// settings returns the platform.yaml values of the services.
+func (o *OnPrem) settings() map[string]string {
+	scheme := func(s *OnPremService) string {
+		if s.UsesTLS() {
+			return "https://"
+		}
+		return "http://"
+	}
+	values := map[string]string{}
+	if s := o.Services[ServiceWebsite]; s != nil {
+		values[websiteHTTPAddr] = scheme(s) + s.Addr()
+	}
+	if s := o.Services[ServicePlatform]; s != nil {
+		values[platformGRPCAddr] = s.Addr()
+		values[platformTLS] = strconv.FormatBool(s.UsesTLS())
+	}
+	if s := o.Services[ServiceFlow]; s != nil {
+		values[flowGRPCAddr] = s.Addr()
+		values[flowTLS] = strconv.FormatBool(s.UsesTLS())
+	}
+	if s := o.Services[ServiceGitServer]; s != nil {
+		values[gitServerAddr] = scheme(s) + s.Addr()
+	}
+	if s := o.Services[ServiceP4Server]; s != nil {
+		values[p4ServerHost] = s.Host
+		values[p4ServerPort] = strconv.Itoa(s.Port)
+		values[p4ServerProtocol] = "tcp"
+		if s.UsesTLS() {
+			values[p4ServerProtocol] = "ssl"
+		}
+	}
+	return values
+}
+
+// CurrentOnPrem returns the services configured for env in the profile in
+// use, so they can be offered as defaults. Services which aren't set are
+// missing.
+func CurrentOnPrem(env string) (*OnPrem, error) {
+	profileHome, err := util.ProfileHome()
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	data, err := readNested(filepath.Join(profileHome, PlatformCfgFile))
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	section, _ := data[env].(map[interface{}]interface{})
+	values := flatten(convertMap(section))
+
+	o := &OnPrem{Version: 1, Services: map[string]*OnPremService{}}
+	add := func(name, addr string, useTLS bool) {
+		if addr == "" {
+			return
+		}
+		if !useTLS && !strings.Contains(addr, "://") {
+			addr = "tcp://" + addr
+		}
+		if s, err := ParseService(name, addr); err == nil {
+			o.Services[name] = s
+		}
+	}
+	add(ServiceWebsite, values[websiteHTTPAddr], true)
+	add(ServicePlatform, values[platformGRPCAddr], values[platformTLS] != "false")
+	add(ServiceFlow, values[flowGRPCAddr], values[flowTLS] != "false")
+	add(ServiceGitServer, values[gitServerAddr], true)
+	if host := values[p4ServerHost]; host != "" {
+		add(ServiceP4Server, net.JoinHostPort(host, values[p4ServerPort]), values[p4ServerProtocol] == "ssl")
+	}
+	return o, nil
+}
+
+// WriteOnPrem sets the addresses of the services for env in the profile in
+// use, and makes env current. The platform config is replaced in a single
+// write, so it is never left half configured.
+func WriteOnPrem(env string, o *OnPrem) error {
+	profileHome, err := util.ProfileHome()
+	if err != nil {
+		return errors.Trace(err)
+	}
+	path := filepath.Join(profileHome, PlatformCfgFile)
+	data, err := readNested(path)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	tree := toTree(data)
+
+	// Replace the values of earlier versions, which nested the addresses
+	// under keys lingo doesn't read.
+	for _, key := range []string{websiteHTTPAddr, platformGRPCAddr, flowGRPCAddr, "messagequeue", "gitserver.remote.host"} {
+		unsetNested(tree, append([]string{env}, strings.Split(key, ".")...))
+	}
+	for key, value := range o.settings() {
+		if err := setNested(tree, append([]string{env}, strings.Split(key, ".")...), value); err != nil {
+			return errors.Annotatef(err, "failed to set %s", key)
+		}
+	}
+
+	out, err := yaml.Marshal(tree)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	if err := credentials.WriteFile(path, out); err != nil {
+		return errors.Trace(err)
+	}
+	return errors.Trace(config.New(filepath.Join(profileHome, EnvCfgFile)).SetEnv(env))
+}
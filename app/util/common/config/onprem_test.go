@@ -0,0 +1,139 @@
+package config
+
+import (
+	"net"
+	"path/filepath"
+
+	"github.com/codelingo/lingo/app/util"
+	jc "github.com/juju/testing/checkers"
+	gc "gopkg.in/check.v1"
+)
+
+type onpremSuite struct {
+	profileSuite
+}
+
+var _ = gc.Suite(&onpremSuite{})
+
+func (s *onpremSuite) TestParseService(c *gc.C) {
+	no := false
+	for _, t := range []struct {
+		addr    string
+		service *OnPremService
+	}{
+		{"lingo.example.com", &OnPremService{Host: "lingo.example.com"}},
+		{"https://lingo.example.com:8443/", &OnPremService{Host: "lingo.example.com", Port: 8443}},
+		{"http://10.0.0.1", &OnPremService{Host: "10.0.0.1", TLS: &no}},
+		{"tcp://[::1]:8001", &OnPremService{Host: "::1", Port: 8001, TLS: &no}},
+	} {
+		service, err := ParseService(ServiceWebsite, t.addr)
+		c.Assert(err, jc.ErrorIsNil)
+		c.Check(service, jc.DeepEquals, t.service, gc.Commentf(t.addr))
+	}
+
+	_, err := ParseService(ServiceFlow, "ftp://x")
+	c.Assert(err, gc.ErrorMatches, `invalid flow address "ftp://x": unknown scheme "ftp".*`)
+	_, err = ParseService(ServiceFlow, "x:port")
+	c.Assert(err, gc.ErrorMatches, `invalid flow address "x:port": bad port "port"`)
+	_, err = ParseService(ServiceFlow, "https://")
+	c.Assert(err, gc.ErrorMatches, `invalid flow host ""`)
+}
+
+func (s *onpremSuite) TestCheck(c *gc.C) {
+	o := &OnPrem{Services: map[string]*OnPremService{}}
+	for _, name := range []string{ServiceWebsite, ServicePlatform, ServiceFlow} {
+		service, err := ParseService(name, "tcp://lingo.example.com")
+		c.Assert(err, jc.ErrorIsNil)
+		o.Services[name] = service
+	}
+	c.Assert(o.Check(), gc.ErrorMatches, "no address given for the gitserver service")
+
+	o.Services[ServiceGitServer], _ = ParseService(ServiceGitServer, "git.example.com")
+	c.Assert(o.Check(), jc.ErrorIsNil)
+	c.Assert(o.Services[ServiceWebsite].Port, gc.Equals, 80)
+	c.Assert(o.Services[ServicePlatform].Port, gc.Equals, 443)
+	c.Assert(o.Services[ServiceGitServer].Port, gc.Equals, 443)
+}
+
+func (s *onpremSuite) TestReadOnPremBundle(c *gc.C) {
+	path := filepath.Join(c.MkDir(), "onprem.yaml")
+	write(c, path, `version: 1
+services:
+  website: {host: lingo.example.com}
+  flow: {host: grpc.example.com, port: 8002, tls: false}
+`)
+	o, err := ReadOnPremBundle(path)
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(o.Services, gc.HasLen, 2)
+	c.Assert(o.Services[ServiceFlow].Addr(), gc.Equals, "grpc.example.com:8002")
+	c.Assert(o.Services[ServiceFlow].UsesTLS(), jc.IsFalse)
+
+	write(c, path, "version: 2\n")
+	_, err = ReadOnPremBundle(path)
+	c.Assert(err, gc.ErrorMatches, ".* has unknown version 2, expected 1")
+
+	write(c, path, "version: 1\nservices:\n  queue: {host: x}\n")
+	_, err = ReadOnPremBundle(path)
+	c.Assert(err, gc.ErrorMatches, `.* configures unknown service "queue".*`)
+
+	write(c, path, "version: 1\nservices:\n  website: {hostname: x}\n")
+	_, err = ReadOnPremBundle(path)
+	c.Assert(err, gc.ErrorMatches, `(?s)failed to parse .*field hostname not found.*`)
+}
+
+func (s *onpremSuite) TestWriteOnPrem(c *gc.C) {
+	o := &OnPrem{Version: 1, Services: map[string]*OnPremService{}}
+	for name, addr := range map[string]string{
+		ServiceWebsite:   "https://lingo.example.com",
+		ServicePlatform:  "grpc.example.com:8001",
+		ServiceFlow:      "tcp://grpc.example.com:8002",
+		ServiceGitServer: "http://git.example.com",
+		ServiceP4Server:  "ssl://p4.example.com",
+	} {
+		service, err := ParseService(name, addr)
+		c.Assert(err, jc.ErrorIsNil)
+		o.Services[name] = service
+	}
+	c.Assert(o.Check(), jc.ErrorIsNil)
+	c.Assert(WriteOnPrem("onprem", o), jc.ErrorIsNil)
+
+	env, err := util.GetEnv()
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(env, gc.Equals, "onprem")
+	p, err := Platform()
+	c.Assert(err, jc.ErrorIsNil)
+	addr, err := p.WebSiteAddress()
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(addr, gc.Equals, "https://lingo.example.com:443")
+	addr, err = p.FlowAddress()
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(addr, gc.Equals, "grpc.example.com:8002")
+	flowTLS, err := p.FlowTLS()
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(flowTLS, jc.IsFalse)
+	platformTLS, err := p.PlatformTLS()
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(platformTLS, jc.IsTrue)
+	addr, err = p.P4ServerAddr()
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(addr, gc.Equals, "ssl:p4.example.com:1666")
+
+	// What was written is offered back as the current config.
+	current, err := CurrentOnPrem("onprem")
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(current, jc.DeepEquals, o)
+}
+
+func (s *onpremSuite) TestDial(c *gc.C) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	c.Assert(err, jc.ErrorIsNil)
+	addr := l.Addr().String()
+	service, err := ParseService(ServiceFlow, "tcp://"+addr)
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(service.Dial(), jc.ErrorIsNil)
+
+	// Nothing listens once it's closed.
+	c.Assert(l.Close(), jc.ErrorIsNil)
+	o := &OnPrem{Services: map[string]*OnPremService{ServiceFlow: service}}
+	c.Assert(o.Dial(), gc.ErrorMatches, `could not reach:\n  flow \(tcp://`+addr+`\): .*`)
+}
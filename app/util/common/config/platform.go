@@ -4,6 +4,9 @@ import (
 	"io/ioutil"
 	"os"
 	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/codelingo/lingo/app/util"
 	"github.com/codelingo/lingo/service/config"
@@ -15,6 +18,9 @@ const (
 	platformGRPCAddr = "platform"
 	flowGRPCAddr     = "flow"
 
+	platformTLS = "tls.platform"
+	flowTLS     = "tls.flow"
+
 	gitServerRemote = "gitserver.remote"
 	gitServerAddr   = "gitserver.addr"
 
@@ -23,6 +29,18 @@ const (
 	p4ServerHost      = "p4server.remote.host"
 	p4ServerPort      = "p4server.remote.port"
 	p4ServerProtocol  = "p4server.remote.protocol"
+
+	actionsPublicKey = "actions.publickey"
+	actionsRegistry  = "actions.registry"
+	actionsOwners    = "actions.owners"
+	actionsSandbox   = "actions.sandbox"
+	actionsTimeout   = "actions.timeout"
+
+	discoveryRoot = "discovery.root"
+
+	defaultOwner = "owner"
+
+	credentialsBackend = "credentials.backend"
 )
 
 // defaultConfig is the config that is written when an existing config can't be found.
@@ -37,14 +55,16 @@ const defaultConfig = `paas:
 
 type platformConfig struct {
 	*config.FileConfig
+	path     string
+	settings *Settings
 }
 
 func PlatformInDir(dir string) (*platformConfig, error) {
-	configHome, err := util.ConfigHome()
+	profileHome, err := util.ProfileHome()
 	if err != nil {
 		return nil, errors.Trace(err)
 	}
-	envFile := filepath.Join(configHome, EnvCfgFile)
+	envFile := filepath.Join(profileHome, EnvCfgFile)
 	cfg := config.New(envFile)
 
 	pCfgPath := filepath.Join(dir, PlatformCfgFile)
@@ -54,16 +74,17 @@ func PlatformInDir(dir string) (*platformConfig, error) {
 	}
 
 	return &platformConfig{
-		pCfg,
+		FileConfig: pCfg,
+		path:       pCfgPath,
 	}, nil
 }
 
 func Platform() (*platformConfig, error) {
-	configHome, err := util.ConfigHome()
+	profileHome, err := util.ProfileHome()
 	if err != nil {
 		return nil, errors.Trace(err)
 	}
-	return PlatformInDir(configHome)
+	return PlatformInDir(profileHome)
 }
 
 func CreatePlatformFileInDir(dir string, overwrite bool) error {
@@ -78,11 +99,11 @@ func CreatePlatformFileInDir(dir string, overwrite bool) error {
 }
 
 func CreatePlatformFile() error {
-	configHome, err := util.ConfigHome()
+	profileHome, err := util.ProfileHome()
 	if err != nil {
 		return errors.Trace(err)
 	}
-	return CreatePlatformFileInDir(configHome, false)
+	return CreatePlatformFileInDir(profileHome, false)
 }
 
 func (p *platformConfig) Dump() (map[string]interface{}, error) {
@@ -154,9 +175,29 @@ func (p *platformConfig) P4ServerAddr() (string, error) {
 	if err != nil {
 		return "", errors.Trace(err)
 	}
+	protocol, err := p.optionalValue(p4ServerProtocol)
+	if err != nil {
+		return "", errors.Trace(err)
+	}
+	if protocol == "ssl" {
+		// Perforce expects the protocol as a prefix of the address.
+		return "ssl:" + addr + ":" + port, nil
+	}
 	return addr + ":" + port, nil
 }
 
+// PlatformTLS reports whether the platform is dialed over TLS, which it is
+// unless tls.platform is false.
+func (p *platformConfig) PlatformTLS() (bool, error) {
+	return p.optionalBool(platformTLS, true)
+}
+
+// FlowTLS reports whether the flow server is dialed over TLS, which it is
+// unless tls.flow is false.
+func (p *platformConfig) FlowTLS() (bool, error) {
+	return p.optionalBool(flowTLS, true)
+}
+
 func (p *platformConfig) P4RemoteName() (string, error) {
 	remoteName, err := p.GetValue(p4RemoteName)
 	if err != nil {
@@ -172,3 +213,145 @@ func (p *platformConfig) P4RemoteDepotName() (string, error) {
 	}
 	return remoteName, nil
 }
+
+// ActionsPublicKey returns the base64 encoded ed25519 key used to verify
+// installed Actions, or an empty string if signatures aren't checked.
+func (p *platformConfig) ActionsPublicKey() (string, error) {
+	return p.optionalValue(actionsPublicKey)
+}
+
+// ActionsRegistry returns the URL of the registry Actions are installed
+// from, or an empty string to use the default registry.
+func (p *platformConfig) ActionsRegistry() (string, error) {
+	return p.optionalValue(actionsRegistry)
+}
+
+// ActionsOwners returns the owners to prefer, in order, when an Action's
+// name is given without an owner and more than one owner provides it. It is
+// set as a comma separated list.
+func (p *platformConfig) ActionsOwners() ([]string, error) {
+	value, err := p.optionalValue(actionsOwners)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	var owners []string
+	for _, owner := range strings.Split(value, ",") {
+		if owner = strings.TrimSpace(owner); owner != "" {
+			owners = append(owners, owner)
+		}
+	}
+	return owners, nil
+}
+
+// DefaultOwner returns the owner of Actions named without one, or an empty
+// string if it isn't set.
+func (p *platformConfig) DefaultOwner() (string, error) {
+	return p.optionalValue(defaultOwner)
+}
+
+// ActionsSandbox reports whether Actions are always run in a sandbox.
+func (p *platformConfig) ActionsSandbox() (bool, error) {
+	value, err := p.optionalValue(actionsSandbox)
+	if err != nil {
+		return false, errors.Trace(err)
+	}
+	return value == "true", nil
+}
+
+// ActionsTimeout returns how long Actions may run for, or zero if they
+// aren't limited.
+func (p *platformConfig) ActionsTimeout() (time.Duration, error) {
+	value, err := p.optionalValue(actionsTimeout)
+	if err != nil || value == "" {
+		return 0, errors.Trace(err)
+	}
+	timeout, err := time.ParseDuration(value)
+	if err != nil {
+		return 0, errors.Annotatef(err, "invalid %s", actionsTimeout)
+	}
+	return timeout, nil
+}
+
+// DiscoveryRoot returns the root of the discovery index: a URL, git
+// repository or local directory. It is empty to use the default index.
+func (p *platformConfig) DiscoveryRoot() (string, error) {
+	return p.optionalValue(discoveryRoot)
+}
+
+// Settings returns the settings of the current environment from every
+// layer, of which this platform.yaml is the user's.
+func (p *platformConfig) Settings() (*Settings, error) {
+	if p.settings == nil {
+		env, err := p.GetEnv()
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		if p.settings, err = Resolve(env, p.path); err != nil {
+			return nil, errors.Trace(err)
+		}
+	}
+	return p.settings, nil
+}
+
+// GetValue returns the value of a key from the layer with the highest
+// precedence that sets it.
+func (p *platformConfig) GetValue(key string) (string, error) {
+	settings, err := p.Settings()
+	if err != nil {
+		return "", errors.Trace(err)
+	}
+	setting := settings.Get(key)
+	if setting == nil {
+		return "", errors.Errorf("Could not find value for config %q", key)
+	}
+	return setting.Value, nil
+}
+
+// Set sets a key in this platform.yaml for the current environment.
+func (p *platformConfig) Set(key string, value interface{}) error {
+	p.settings = nil
+	return errors.Trace(p.FileConfig.Set(key, value))
+}
+
+// Unset removes a key from this platform.yaml for the current environment,
+// returning false if it wasn't set there.
+func (p *platformConfig) Unset(key string) (bool, error) {
+	env, err := p.GetEnv()
+	if err != nil {
+		return false, errors.Trace(err)
+	}
+	p.settings = nil
+	unset, err := p.UnsetForEnv(env, key)
+	return unset, errors.Trace(err)
+}
+
+// Path returns the path of this platform.yaml.
+func (p *platformConfig) Path() string {
+	return p.path
+}
+
+// optionalBool returns the boolean value of a key, or def if it is not set.
+func (p *platformConfig) optionalBool(key string, def bool) (bool, error) {
+	value, err := p.optionalValue(key)
+	if err != nil || value == "" {
+		return def, errors.Trace(err)
+	}
+	b, err := strconv.ParseBool(value)
+	if err != nil {
+		return false, errors.Errorf("invalid value %q for config %q, expected true or false", value, key)
+	}
+	return b, nil
+}
+
+// optionalValue returns the value of a key, or an empty string if it is not
+// set.
+func (p *platformConfig) optionalValue(key string) (string, error) {
+	settings, err := p.Settings()
+	if err != nil {
+		return "", errors.Trace(err)
+	}
+	if setting := settings.Get(key); setting != nil {
+		return setting.Value, nil
+	}
+	return "", nil
+}
@@ -0,0 +1,227 @@
+package config
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/codelingo/lingo/app/util"
+	"github.com/codelingo/lingo/service/config"
+	"github.com/juju/errors"
+)
+
+// Profiles returns the names of every profile, starting with the default.
+func Profiles() ([]string, error) {
+	dir, err := util.ProfileDir(util.DefaultProfile)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	infos, err := ioutil.ReadDir(filepath.Join(dir, "profiles"))
+	if err != nil && !os.IsNotExist(err) {
+		return nil, errors.Trace(err)
+	}
+	var profiles []string
+	for _, info := range infos {
+		if info.IsDir() {
+			profiles = append(profiles, info.Name())
+		}
+	}
+	sort.Strings(profiles)
+	return append([]string{util.DefaultProfile}, profiles...), nil
+}
+
+// CreateProfile creates a profile using env, with the default platform
+// addresses and no credentials. If owner isn't empty, it is set as the
+// profile's default owner.
+func CreateProfile(name, env, owner string) error {
+	dir, err := newProfileDir(name)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	if err := CreatePlatformFileInDir(dir, false); err != nil {
+		return errors.Trace(err)
+	}
+	if err := CreateAuthFileInDir(dir, false); err != nil {
+		return errors.Trace(err)
+	}
+	envFile := filepath.Join(dir, EnvCfgFile)
+	if err := config.New(envFile).SetEnv(env); err != nil {
+		return errors.Trace(err)
+	}
+	if owner == "" {
+		return nil
+	}
+	pCfg, err := config.New(envFile).New(filepath.Join(dir, PlatformCfgFile))
+	if err != nil {
+		return errors.Trace(err)
+	}
+	return errors.Trace(pCfg.SetForEnv(env, defaultOwner, owner))
+}
+
+// CopyProfile creates a profile with the platform config and environment of
+// another. Credentials aren't copied, so the new profile has to be set up.
+func CopyProfile(from, to string) error {
+	fromDir, err := profileDir(from)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	toDir, err := newProfileDir(to)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	for _, file := range []string{PlatformCfgFile, EnvCfgFile} {
+		data, err := ioutil.ReadFile(filepath.Join(fromDir, file))
+		if err != nil {
+			return errors.Trace(err)
+		}
+		if err := ioutil.WriteFile(filepath.Join(toDir, file), data, 0600); err != nil {
+			return errors.Trace(err)
+		}
+	}
+	return errors.Trace(CreateAuthFileInDir(toDir, false))
+}
+
+// UseProfile makes name the profile lingo uses.
+func UseProfile(name string) error {
+	if _, err := profileDir(name); err != nil {
+		return errors.Trace(err)
+	}
+	configHome, err := util.ConfigHome()
+	if err != nil {
+		return errors.Trace(err)
+	}
+	path := filepath.Join(configHome, util.ProfileCfgFile)
+	if name == util.DefaultProfile {
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return errors.Trace(err)
+		}
+		return nil
+	}
+	return errors.Trace(ioutil.WriteFile(path, []byte(name), 0644))
+}
+
+// DeleteProfile logs out of every environment of a profile, removing its
+// credentials, and deletes it. git's credential helper for a git server is
+// only removed if no other profile uses the server. The default profile and
+// the one in use can't be deleted.
+func DeleteProfile(name string) error {
+	if name == util.DefaultProfile {
+		return errors.New("the default profile cannot be deleted")
+	}
+	dir, err := profileDir(name)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	current, err := util.Profile()
+	if err != nil {
+		return errors.Trace(err)
+	}
+	if current == name {
+		return errors.Errorf("profile %q is in use. Switch to another with `lingo config profile use` first", name)
+	}
+
+	a, err := AuthForProfile(name)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	addrs, err := a.gitServerAddrs()
+	if err != nil {
+		return errors.Trace(err)
+	}
+	envs, err := a.LoggedInEnvs()
+	if err != nil {
+		return errors.Trace(err)
+	}
+	for _, env := range envs {
+		if _, err := a.removeLogin(env); err != nil {
+			return errors.Annotatef(err, "failed to log out of %s", env)
+		}
+	}
+	if err := os.RemoveAll(dir); err != nil {
+		return errors.Trace(err)
+	}
+
+	// git's credential helpers are set per git server, not per profile.
+	inUse, err := profileGitServerAddrs()
+	if err != nil {
+		return errors.Trace(err)
+	}
+	for addr := range addrs {
+		if inUse[addr] {
+			continue
+		}
+		if err := UnsetGitCredentialHelper(addr); err != nil {
+			return errors.Trace(err)
+		}
+	}
+	return nil
+}
+
+// gitServerAddrs returns the addresses of the git servers of the
+// environments of a's profile.
+func (a *authConfig) gitServerAddrs() (map[string]bool, error) {
+	envs, err := a.LoggedInEnvs()
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	addrs := map[string]bool{}
+	for _, env := range envs {
+		addr, err := a.platformValue(env, gitServerAddr)
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		if addr != "" {
+			addrs[addr] = true
+		}
+	}
+	return addrs, nil
+}
+
+// profileGitServerAddrs returns the addresses of the git servers of every
+// profile's environments.
+func profileGitServerAddrs() (map[string]bool, error) {
+	profiles, err := Profiles()
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	addrs := map[string]bool{}
+	for _, profile := range profiles {
+		a, err := AuthForProfile(profile)
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		profileAddrs, err := a.gitServerAddrs()
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		for addr := range profileAddrs {
+			addrs[addr] = true
+		}
+	}
+	return addrs, nil
+}
+
+// profileDir returns the directory of an existing profile.
+func profileDir(name string) (string, error) {
+	dir, err := util.ProfileDir(name)
+	if err != nil {
+		return "", errors.Trace(err)
+	}
+	if _, err := os.Stat(dir); os.IsNotExist(err) {
+		return "", errors.NotFoundf("profile %q", name)
+	}
+	return dir, nil
+}
+
+// newProfileDir creates the directory of a new profile.
+func newProfileDir(name string) (string, error) {
+	dir, err := util.ProfileDir(name)
+	if err != nil {
+		return "", errors.Trace(err)
+	}
+	if _, err := os.Stat(dir); name == util.DefaultProfile || err == nil {
+		return "", errors.AlreadyExistsf("profile %q", name)
+	}
+	return dir, errors.Trace(os.MkdirAll(dir, 0700))
+}
@@ -0,0 +1,127 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+
+	"github.com/codelingo/lingo/app/util"
+	"github.com/juju/errors"
+	jc "github.com/juju/testing/checkers"
+	gc "gopkg.in/check.v1"
+)
+
+type profileSuite struct {
+	home string
+	env  map[string]string
+}
+
+var _ = gc.Suite(&profileSuite{})
+
+func (s *profileSuite) SetUpTest(c *gc.C) {
+	s.env = map[string]string{}
+	for _, key := range []string{"HOME", "LINGO_HOME", util.ProfileEnvVar, "LINGO_TOKEN", "DBUS_SESSION_BUS_ADDRESS"} {
+		s.env[key] = os.Getenv(key)
+		os.Unsetenv(key)
+	}
+	// git's global config is kept in $HOME.
+	os.Setenv("HOME", c.MkDir())
+	s.home = c.MkDir()
+	os.Setenv("LINGO_HOME", s.home)
+	write(c, filepath.Join(s.home, EnvCfgFile), "paas")
+	c.Assert(CreatePlatformFileInDir(s.home, false), jc.ErrorIsNil)
+	c.Assert(CreateAuthFileInDir(s.home, false), jc.ErrorIsNil)
+}
+
+func (s *profileSuite) TearDownTest(c *gc.C) {
+	for key, value := range s.env {
+		if value == "" {
+			os.Unsetenv(key)
+		} else {
+			os.Setenv(key, value)
+		}
+	}
+}
+
+func (s *profileSuite) TestProfiles(c *gc.C) {
+	c.Assert(CreateProfile("onprem", "onprem", "acme"), jc.ErrorIsNil)
+	c.Assert(CreateProfile("onprem", "paas", ""), gc.ErrorMatches, `profile "onprem" already exists`)
+	c.Assert(CreateProfile(util.DefaultProfile, "paas", ""), gc.ErrorMatches, `profile "default" already exists`)
+	c.Assert(CreateProfile("../x", "paas", ""), gc.ErrorMatches, `invalid profile name "../x"`)
+	c.Assert(CopyProfile("onprem", "copy"), jc.ErrorIsNil)
+
+	profiles, err := Profiles()
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(profiles, jc.DeepEquals, []string{"default", "copy", "onprem"})
+
+	profile, err := util.Profile()
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(profile, gc.Equals, util.DefaultProfile)
+
+	c.Assert(UseProfile("copy"), jc.ErrorIsNil)
+	profile, err = util.Profile()
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(profile, gc.Equals, "copy")
+	dir, err := util.ProfileHome()
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(dir, gc.Equals, filepath.Join(s.home, "profiles", "copy"))
+
+	// The copy has the environment and default owner of the original.
+	env, err := util.GetEnv()
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(env, gc.Equals, "onprem")
+	p, err := Platform()
+	c.Assert(err, jc.ErrorIsNil)
+	owner, err := p.DefaultOwner()
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(owner, gc.Equals, "acme")
+
+	// $LINGO_PROFILE overrides the profile in use.
+	os.Setenv(util.ProfileEnvVar, "missing")
+	_, err = util.ProfileHome()
+	c.Assert(err, gc.ErrorMatches, `profile "missing" does not exist.*`)
+	os.Unsetenv(util.ProfileEnvVar)
+
+	c.Assert(UseProfile("missing"), jc.Satisfies, errors.IsNotFound)
+	c.Assert(DeleteProfile("copy"), gc.ErrorMatches, `profile "copy" is in use.*`)
+	c.Assert(DeleteProfile(util.DefaultProfile), gc.ErrorMatches, "the default profile cannot be deleted")
+	c.Assert(UseProfile(util.DefaultProfile), jc.ErrorIsNil)
+	c.Assert(DeleteProfile("copy"), jc.ErrorIsNil)
+
+	profiles, err = Profiles()
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(profiles, jc.DeepEquals, []string{"default", "onprem"})
+}
+
+func (s *profileSuite) TestSecretNames(c *gc.C) {
+	c.Assert(CreateProfile("onprem", "onprem", ""), jc.ErrorIsNil)
+	def, err := AuthForProfile(util.DefaultProfile)
+	c.Assert(err, jc.ErrorIsNil)
+	onprem, err := AuthForProfile("onprem")
+	c.Assert(err, jc.ErrorIsNil)
+
+	// Profiles keep their secrets apart, even in a shared keyring.
+	c.Assert(def.secretName("paas", "token"), gc.Equals, "paas/token")
+	c.Assert(onprem.secretName("paas", "token"), gc.Equals, "onprem:paas/token")
+}
+
+func (s *profileSuite) TestDeleteProfileGitCredentialHelper(c *gc.C) {
+	// Nothing is stored with the env backend, so no keyring is needed.
+	os.Setenv("LINGO_TOKEN", "token")
+	const paasAddr, onpremAddr = "https://git.codelingo.io:443", "https://git.example.com"
+	c.Assert(CreateProfile("work", "paas", ""), jc.ErrorIsNil)
+	c.Assert(CreateProfile("onprem", "onprem", ""), jc.ErrorIsNil)
+	write(c, filepath.Join(s.home, "profiles", "onprem", PlatformCfgFile), "onprem:\n  gitserver:\n    addr: "+onpremAddr+"\n")
+	c.Assert(SetGitCredentialHelper(paasAddr), jc.ErrorIsNil)
+	c.Assert(SetGitCredentialHelper(onpremAddr), jc.ErrorIsNil)
+
+	// The default profile still uses the same git server as work.
+	c.Assert(DeleteProfile("work"), jc.ErrorIsNil)
+	_, err := git("config", "--global", "credential."+paasAddr+".helper")
+	c.Assert(err, jc.ErrorIsNil)
+
+	c.Assert(DeleteProfile("onprem"), jc.ErrorIsNil)
+	_, err = git("config", "--global", "credential."+onpremAddr+".helper")
+	c.Assert(err, gc.NotNil)
+	_, err = git("config", "--global", "credential."+paasAddr+".helper")
+	c.Assert(err, jc.ErrorIsNil)
+}
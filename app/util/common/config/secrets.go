@@ -0,0 +1,263 @@
+package config
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/codelingo/lingo/app/credentials"
+	"github.com/codelingo/lingo/app/login"
+	"github.com/codelingo/lingo/app/util"
+	"github.com/juju/errors"
+)
+
+// The secrets kept beside the token of a browser sign in.
+const (
+	refreshTokenName = "refresh_token"
+	tokenExpiryName  = "token_expiry"
+)
+
+// refreshTimeout limits how long refreshing a token may take.
+const refreshTimeout = 30 * time.Second
+
+var errSignInExpired = errors.New("your CodeLingo sign in has expired. Please run `lingo config setup`")
+
+// Store returns the credentials store the user's token is kept in, as
+// chosen by credentials.backend.
+func (a *authConfig) Store() (credentials.Store, error) {
+	if a.store == nil {
+		env, err := a.GetEnv()
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		// Read the backend from the profile of this config, which may not
+		// be the one in use.
+		backend, err := a.platformValue(env, credentialsBackend)
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		if a.store, err = credentials.Open(backend, filepath.Dir(a.path)); err != nil {
+			return nil, errors.Trace(err)
+		}
+	}
+	return a.store, nil
+}
+
+// secretName returns the name of a secret of env in the credentials store.
+// The environments of profiles other than the default are prefixed with
+// the profile, so each profile keeps its own credentials.
+func (a *authConfig) secretName(env, secret string) string {
+	if a.profile != "" && a.profile != util.DefaultProfile {
+		env = a.profile + ":" + env
+	}
+	return credentials.Name(env, secret)
+}
+
+// SaveLogin keeps the token of a sign in in the credentials store, along
+// with when it expires and the token to refresh it with, if it does.
+func (a *authConfig) SaveLogin(token *login.Token) error {
+	env, err := a.GetEnv()
+	if err != nil {
+		return errors.Trace(err)
+	}
+	store, err := a.Store()
+	if err != nil {
+		return errors.Trace(err)
+	}
+	return errors.Trace(a.saveToken(store, env, token))
+}
+
+func (a *authConfig) saveToken(store credentials.Store, env string, token *login.Token) error {
+	if err := store.Set(a.secretName(env, credentials.TokenName), token.AccessToken); err != nil {
+		return errors.Trace(err)
+	}
+	for name, value := range map[string]string{
+		refreshTokenName: token.RefreshToken,
+		tokenExpiryName:  formatExpiry(token.Expiry),
+	} {
+		var err error
+		if value == "" {
+			err = store.Delete(a.secretName(env, name))
+		} else {
+			err = store.Set(a.secretName(env, name), value)
+		}
+		if err != nil {
+			return errors.Trace(err)
+		}
+	}
+	return nil
+}
+
+func formatExpiry(expiry time.Time) string {
+	if expiry.IsZero() {
+		return ""
+	}
+	return expiry.UTC().Format(time.RFC3339)
+}
+
+// refreshedToken returns token, or a new one if it has expired and was
+// given with a refresh token.
+func (a *authConfig) refreshedToken(store credentials.Store, env, token string) (string, error) {
+	value, err := store.Get(a.secretName(env, tokenExpiryName))
+	if errors.IsNotFound(err) {
+		// The token doesn't expire.
+		return token, nil
+	}
+	if err != nil {
+		return "", errors.Trace(err)
+	}
+	expiry, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return "", errors.Annotate(err, "invalid token expiry")
+	}
+	// Refresh a little early, so the token doesn't expire mid request.
+	if time.Until(expiry) > time.Minute {
+		return token, nil
+	}
+
+	refreshToken, err := store.Get(a.secretName(env, refreshTokenName))
+	if errors.IsNotFound(err) {
+		return "", errSignInExpired
+	}
+	if err != nil {
+		return "", errors.Trace(err)
+	}
+	website, err := a.WebSiteAddressForEnv(env)
+	if err != nil {
+		return "", errors.Trace(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), refreshTimeout)
+	defer cancel()
+	refreshed, err := (&login.Client{BaseURL: website}).Refresh(ctx, refreshToken)
+	if errors.Cause(err) == login.ErrExpired {
+		return "", errSignInExpired
+	}
+	if err != nil {
+		return "", errors.Annotate(err, "failed to refresh your CodeLingo sign in")
+	}
+	if err := a.saveToken(store, env, refreshed); err != nil {
+		return "", errors.Trace(err)
+	}
+	return refreshed.AccessToken, nil
+}
+
+// MigrateSecrets moves the tokens earlier versions of lingo kept in
+// plaintext in auth.yaml into the credentials store. It removes the
+// plaintext git credentials files they wrote, pointing git at lingo's
+// credential helper instead. The env backend reads tokens from the
+// environment, so with it the plaintext tokens are only removed. It returns
+// the environments whose tokens were moved or removed.
+func (a *authConfig) MigrateSecrets() ([]string, error) {
+	data, err := readNested(a.path)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	var envs []string
+	for env := range data {
+		envs = append(envs, env)
+	}
+	sort.Strings(envs)
+
+	var migrated []string
+	for _, env := range envs {
+		section, ok := data[env].(map[interface{}]interface{})
+		if !ok {
+			continue
+		}
+		values := flatten(convertMap(section))
+		token, credFile := values[gitPassword], values[gitCredentialFilename]
+		if token == "" && credFile == "" && values[p4Password] == "" {
+			continue
+		}
+
+		if token != "" {
+			store, err := a.Store()
+			if err != nil {
+				return migrated, errors.Trace(err)
+			}
+			// Nothing can be written to the environment, which the env
+			// backend reads the token from instead.
+			if store.Backend() != credentials.BackendEnv {
+				if err := store.Set(a.secretName(env, credentials.TokenName), token); err != nil {
+					return migrated, errors.Annotatef(err, "failed to move the %s token", env)
+				}
+			}
+			migrated = append(migrated, env)
+		}
+		if credFile != "" {
+			if err := removeGitCredentialsFile(filepath.Join(filepath.Dir(a.path), credFile)); err != nil {
+				return migrated, errors.Trace(err)
+			}
+		}
+		for _, key := range []string{gitPassword, p4Password, gitCredentialFilename} {
+			if _, err := a.UnsetForEnv(env, key); err != nil {
+				return migrated, errors.Trace(err)
+			}
+		}
+	}
+	return migrated, errors.Trace(os.Chmod(a.path, 0600))
+}
+
+// GitCredentialHelper returns the git credential helper which reads the
+// user's token from the credentials store.
+func GitCredentialHelper() (string, error) {
+	exe, err := os.Executable()
+	if err != nil {
+		return "", errors.Trace(err)
+	}
+	return fmt.Sprintf("!'%s' config git-credential", filepath.ToSlash(exe)), nil
+}
+
+// SetGitCredentialHelper configures git to ask lingo for the credentials
+// of the git server at addr.
+func SetGitCredentialHelper(addr string) error {
+	helper, err := GitCredentialHelper()
+	if err != nil {
+		return errors.Trace(err)
+	}
+	_, err = git("config", "--global", fmt.Sprintf("credential.%s.helper", addr), helper)
+	return errors.Trace(err)
+}
+
+// removeGitCredentialsFile removes a plaintext git credentials file,
+// pointing the git credential helpers which read it at lingo's instead.
+func removeGitCredentialsFile(path string) error {
+	// git exits with 1 if no helpers are configured.
+	out, _ := git("config", "--global", "--get-regexp", `^credential\..*\.helper$`)
+	for _, line := range strings.Split(out, "\n") {
+		parts := strings.SplitN(line, " ", 2)
+		if len(parts) != 2 || !strings.HasPrefix(parts[1], "store --file ") {
+			continue
+		}
+		// The path may have been rewritten for git on Windows.
+		if filepath.Base(strings.TrimPrefix(parts[1], "store --file ")) != filepath.Base(path) {
+			continue
+		}
+		helper, err := GitCredentialHelper()
+		if err != nil {
+			return errors.Trace(err)
+		}
+		if _, err := git("config", "--global", parts[0], helper); err != nil {
+			return errors.Trace(err)
+		}
+	}
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return errors.Trace(err)
+	}
+	return nil
+}
+
+func git(args ...string) (string, error) {
+	b, err := exec.Command("git", args...).CombinedOutput()
+	out := strings.TrimSpace(string(b))
+	if err != nil {
+		return out, errors.Annotate(err, out)
+	}
+	return out, nil
+}
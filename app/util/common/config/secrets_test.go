@@ -0,0 +1,35 @@
+package config
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	jc "github.com/juju/testing/checkers"
+	gc "gopkg.in/check.v1"
+)
+
+type secretsSuite struct {
+	profileSuite
+}
+
+var _ = gc.Suite(&secretsSuite{})
+
+func (s *secretsSuite) TestMigrateSecretsEnvBackend(c *gc.C) {
+	path := filepath.Join(s.home, AuthCfgFile)
+	write(c, path, "paas:\n  gitserver:\n    user:\n      username: me\n      password: s3cret\n")
+	// The env backend is used when the token is in the environment.
+	os.Setenv("LINGO_TOKEN", "from-env")
+
+	a, err := Auth()
+	c.Assert(err, jc.ErrorIsNil)
+	envs, err := a.MigrateSecrets()
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(envs, jc.DeepEquals, []string{"paas"})
+
+	// The plaintext token is removed, as the env backend doesn't need it.
+	data, err := ioutil.ReadFile(path)
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(string(data), gc.Not(jc.Contains), "s3cret")
+	c.Assert(string(data), jc.Contains, "username: me")
+}
@@ -50,6 +50,14 @@ var (
 		"diff",
 		"d",
 	}
+	ConfigFlg = flagName{
+		"config",
+		"C",
+	}
+	ProfileFlg = flagName{
+		"profile",
+		"P",
+	}
 
 	//local flags
 	AllFlg = flagName{
@@ -112,6 +120,10 @@ var (
 		Long:  "insecure",
 		Short: "in",
 	}
+	RefreshFlg = flagName{
+		Long:  "refresh",
+		Short: "r",
+	}
 )
 
 func (f *flagName) String() string {
@@ -133,6 +145,23 @@ var GlobalOptions = []cli.Flag{
 		EnvVar: "LINGO_HOME",
 	},
 
+	cli.StringFlag{
+		Name:   TenetCfgFlg.String(),
+		Usage:  "the codelingo.yaml file for commands that edit one to use, instead of the nearest",
+		EnvVar: "LINGO_TENET_CONFIG",
+	},
+
+	cli.StringFlag{
+		Name:   ProfileFlg.String(),
+		Usage:  "the profile to use, instead of the one chosen with lingo config profile use",
+		EnvVar: ProfileEnvVar,
+	},
+
+	cli.StringSliceFlag{
+		Name:  ConfigFlg.String(),
+		Usage: "set a config value as key=value for this command only, overriding the config files. May be repeated",
+	},
+
 	// cli.StringFlag{
 	// 	Name:   outputTemplateFlg.String(),
 	// 	Value:  "",
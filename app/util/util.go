@@ -6,9 +6,11 @@ import (
 	"bytes"
 	"fmt"
 	"io"
+	"io/ioutil"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"regexp"
 	"strings"
 	"text/tabwriter"
 	"text/template"
@@ -39,7 +41,7 @@ const EnvCfgFile = "lingo-current-env"
 
 func GetEnv() (string, error) {
 
-	configsHome, err := ConfigHome()
+	configsHome, err := ProfileHome()
 	if err != nil {
 		return "", errors.Trace(err)
 	}
@@ -143,6 +145,73 @@ func ConfigHome() (string, error) {
 	return filepath.Join(home, defaultHome, "configs"), nil
 }
 
+// ProfileEnvVar selects the profile to use, overriding the current one.
+const ProfileEnvVar = "LINGO_PROFILE"
+
+// DefaultProfile is the profile kept directly in the config directory, as
+// lingo's config was before there were profiles.
+const DefaultProfile = "default"
+
+// ProfileCfgFile names the current profile, in the config directory.
+const ProfileCfgFile = "lingo-current-profile"
+
+var validProfile = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9_.-]*$`)
+
+// Profile returns the name of the profile in use: $LINGO_PROFILE if set,
+// else the one chosen with `lingo config profile use`, else the default.
+func Profile() (string, error) {
+	if profile := os.Getenv(ProfileEnvVar); profile != "" {
+		return profile, nil
+	}
+	configHome, err := ConfigHome()
+	if err != nil {
+		return "", errors.Trace(err)
+	}
+	data, err := ioutil.ReadFile(filepath.Join(configHome, ProfileCfgFile))
+	if os.IsNotExist(err) {
+		return DefaultProfile, nil
+	}
+	if err != nil {
+		return "", errors.Trace(err)
+	}
+	if profile := strings.TrimSpace(string(data)); profile != "" {
+		return profile, nil
+	}
+	return DefaultProfile, nil
+}
+
+// ProfileDir returns the directory holding the platform.yaml, auth.yaml and
+// current environment of the named profile.
+func ProfileDir(profile string) (string, error) {
+	if !validProfile.MatchString(profile) {
+		return "", errors.Errorf("invalid profile name %q", profile)
+	}
+	configHome, err := ConfigHome()
+	if err != nil {
+		return "", errors.Trace(err)
+	}
+	if profile == DefaultProfile {
+		return configHome, nil
+	}
+	return filepath.Join(configHome, "profiles", profile), nil
+}
+
+// ProfileHome returns the directory of the profile in use.
+func ProfileHome() (string, error) {
+	profile, err := Profile()
+	if err != nil {
+		return "", errors.Trace(err)
+	}
+	dir, err := ProfileDir(profile)
+	if err != nil {
+		return "", errors.Trace(err)
+	}
+	if _, err := os.Stat(dir); os.IsNotExist(err) && profile != DefaultProfile {
+		return "", errors.Errorf("profile %q does not exist. Create it with `lingo config profile create %s`", profile, profile)
+	}
+	return dir, nil
+}
+
 func ConfigDefaults() (string, error) {
 	configHome, err := ConfigHome()
 	if err != nil {
@@ -9,9 +9,9 @@ require (
 	github.com/frankban/quicktest v1.10.2 // indirect
 	github.com/fsouza/go-dockerclient v1.6.5
 	github.com/gogits/go-gogs-client v0.0.0-20200821174505-4ab716bb71a3
+	github.com/golang/protobuf v1.3.3
 	github.com/golang/snappy v0.0.1 // indirect
 	github.com/hishboy/gocommons v0.0.0-20160108023425-89887b2ade6d
-	github.com/howeyc/gopass v0.0.0-20190910152052-7cb4b85ec19c
 	github.com/juju/errors v0.0.0-20200330140219-3fe23663418f
 	github.com/juju/testing v0.0.0-20200706033705-4c23f9c453cd
 	github.com/kardianos/osext v0.0.0-20190222173326-2bc1f35cddc0
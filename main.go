@@ -8,9 +8,12 @@ import (
 	"github.com/juju/errors"
 
 	"github.com/codelingo/lingo/app"
+	"github.com/codelingo/lingo/app/sandbox"
 )
 
 func main() {
+	// Sandboxed Actions are started by re-executing lingo.
+	sandbox.Init()
 
 	err := app.New().Run(os.Args)
 	if err != nil {
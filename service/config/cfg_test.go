@@ -51,3 +51,27 @@ func (s *suite) TestSetCfg(c *C) {
 
 	// TODO(waigani) assert config
 }
+
+func (s *suite) TestUnsetForEnv(c *C) {
+	file := filepath.Join(c.MkDir(), "cfg.yaml")
+	cfg := config.New(filepath.Join(c.MkDir(), commonConfig.EnvCfgFile))
+	testCfg, err := cfg.Create(file, map[string]interface{}{}, 0644)
+	c.Assert(err, jc.ErrorIsNil)
+
+	c.Assert(testCfg.SetForEnv("dev", "actions.timeout", "5m"), jc.ErrorIsNil)
+	c.Assert(testCfg.SetForEnv("dev", "website", "localhost"), jc.ErrorIsNil)
+
+	unset, err := testCfg.UnsetForEnv("dev", "actions.timeout")
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(unset, jc.IsTrue)
+	all, err := testCfg.GetAll("actions")
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(all, HasLen, 0)
+	website, err := testCfg.GetForEnv("dev", "website")
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(website, Equals, "localhost")
+
+	unset, err = testCfg.UnsetForEnv("dev", "actions.timeout")
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(unset, jc.IsFalse)
+}
@@ -195,6 +195,25 @@ func (c cfgInfo) walkSet(keyPath []string, value interface{}) error {
 	return nil
 }
 
+func (c cfgInfo) walkUnset(keyPath []string) bool {
+	if len(keyPath) == 1 {
+		if _, ok := c.info[keyPath[0]]; !ok {
+			return false
+		}
+		delete(c.info, keyPath[0])
+		return true
+	}
+
+	child, ok := c.info[keyPath[0]].(map[interface{}]interface{})
+	if !ok || !(cfgInfo{info: child}).walkUnset(keyPath[1:]) {
+		return false
+	}
+	if len(child) == 0 {
+		delete(c.info, keyPath[0])
+	}
+	return true
+}
+
 func newCfgInfo(infoMap interface{}) (*cfgInfo, error) {
 	if infoMap == nil {
 		return nil, errors.New("infoMap is nil")
@@ -322,7 +341,7 @@ func (fc *FileConfig) SetForEnv(env string, key string, value interface{}) error
 
 	fc.data = convertMapType(infoM.info)
 
-	err = ioutil.WriteFile(fc.filename, data, 0755)
+	err = ioutil.WriteFile(fc.filename, data, 0600)
 	if err != nil {
 		return errors.Trace(err)
 	}
@@ -335,6 +354,33 @@ func (fc *FileConfig) SetForEnv(env string, key string, value interface{}) error
 	return nil
 }
 
+// UnsetForEnv removes a key from the given environment, and any sections
+// left empty by doing so. It returns false if the key wasn't set.
+func (fc *FileConfig) UnsetForEnv(env string, key string) (bool, error) {
+	mapData, err := readYaml(fc.filename)
+	if err != nil {
+		return false, errors.Trace(err)
+	}
+	infoM, err := newCfgInfo(mapData)
+	if err != nil {
+		return false, errors.Trace(err)
+	}
+
+	if !infoM.walkUnset(strings.Split(env+"."+key, ".")) {
+		return false, nil
+	}
+
+	data, err := yaml.Marshal(infoM.info)
+	if err != nil {
+		return false, errors.Trace(err)
+	}
+	err = ioutil.WriteFile(fc.filename, data, 0600)
+	if err != nil {
+		return false, errors.Trace(err)
+	}
+	return true, errors.Trace(fc.Reload())
+}
+
 func (fc *FileConfig) Set(key string, value interface{}) error {
 	env, err := fc.config.GetEnv()
 	if err != nil {
@@ -0,0 +1,62 @@
+package service
+
+import (
+	"context"
+
+	"github.com/codelingo/rpc/flow"
+	"github.com/codelingo/rpc/flow/client"
+	"github.com/golang/protobuf/ptypes"
+	"github.com/juju/errors"
+)
+
+// Review runs the review flow on the flow server and returns the issues it
+// found.
+func Review(ctx context.Context, req *flow.ReviewRequest, insecureAllowed bool) ([]*flow.Issue, error) {
+	conn, err := GrpcConnection(LocalClient, FlowServer, insecureAllowed)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	defer conn.Close()
+
+	payload, err := ptypes.MarshalAny(req)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	requestc := make(chan *flow.Request, 1)
+	requestc <- &flow.Request{Flow: "review", Payload: payload}
+	close(requestc)
+
+	replyc, errc, err := client.NewFlowClient(conn).Run(ctx, requestc)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+
+	var issues []*flow.Issue
+	for replyc != nil || errc != nil {
+		select {
+		case reply, ok := <-replyc:
+			if !ok {
+				replyc = nil
+				continue
+			}
+			if reply.IsHeartbeat {
+				continue
+			}
+			if reply.Error != "" {
+				return nil, errors.New(reply.Error)
+			}
+			issue := &flow.Issue{}
+			if err := ptypes.UnmarshalAny(reply.Payload, issue); err != nil {
+				return nil, errors.Trace(err)
+			}
+			issues = append(issues, issue)
+		case err, ok := <-errc:
+			if !ok {
+				errc = nil
+				continue
+			}
+			return nil, errors.Trace(err)
+		}
+	}
+	return issues, nil
+}
@@ -43,20 +43,28 @@ func GrpcConnection(client, server string, insecureAllowed bool) (*grpc.ClientCo
 		if err != nil {
 			return nil, errors.Trace(err)
 		}
+		var serverTLS bool
 		switch server {
 		case FlowServer:
 			grpcAddr, err = pCfg.FlowAddress()
 			if err != nil {
 				return nil, errors.Trace(err)
 			}
+			serverTLS, err = pCfg.FlowTLS()
 		case PlatformServer:
 			grpcAddr, err = pCfg.PlatformAddress()
 			if err != nil {
 				return nil, errors.Trace(err)
 			}
+			serverTLS, err = pCfg.PlatformTLS()
 		default:
 			return nil, errors.Errorf("Unknown Server %s:", server)
 		}
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		// On-prem servers may be configured without TLS.
+		isTLS = isTLS && serverTLS
 	case FlowClient:
 		isTLS = false
 		// TODO: this is hardcoded to platform address:port